@@ -0,0 +1,75 @@
+// Package auth authenticates API clients and carries the result, a
+// Principal, through the request context.
+//
+// Authentication is pluggable: an Authenticator looks for its own kind of
+// credentials in the request headers, and Chain tries several in turn.
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+var (
+	// ErrNoCredentials is returned by an Authenticator that found none of
+	// the credentials it handles.
+	ErrNoCredentials = errors.New("no credentials")
+	// ErrInvalidCredentials is returned for credentials that were present
+	// but could not be verified. Its wrapped message tells why.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is the authenticated client a request is made by.
+type Principal struct {
+	// Subject identifies the client, the sub claim of a token.
+	Subject string
+	// Roles and Scopes are what the credentials grant.
+	Roles  []string
+	Scopes []string
+	// Permissions are policy permissions granted to the principal itself
+	// rather than through a role, as API keys are.
+	Permissions []string
+}
+
+// HasRole reports whether p holds role.
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// Authenticator verifies the credentials of a request. header returns the
+// value of a request header, empty if it is missing.
+type Authenticator interface {
+	Authenticate(ctx context.Context, header func(name string) string) (*Principal, error)
+}
+
+// Chain returns an Authenticator trying each of authenticators in order
+// until one finds its credentials.
+func Chain(authenticators ...Authenticator) Authenticator {
+	return chain(authenticators)
+}
+
+type chain []Authenticator
+
+func (c chain) Authenticate(ctx context.Context, header func(name string) string) (*Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(ctx, header)
+		if !errors.Is(err, ErrNoCredentials) {
+			return p, err
+		}
+	}
+	return nil, ErrNoCredentials
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying p.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal of ctx, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// minRSABits is the smallest RSA modulus accepted for RS256.
+const minRSABits = 2048
+
+// KeySet holds the keys tokens are verified with, by key id. Keys without
+// an id are stored under the empty id and verify tokens without a kid.
+type KeySet struct {
+	rsa  map[string]*rsa.PublicKey
+	hmac map[string][]byte
+}
+
+// jwk is a JSON Web Key (RFC 7517) of the kinds KeySet supports: RSA public
+// keys for RS256 and symmetric ("oct") keys for HS256.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// LoadJWKS reads a JSON Web Key Set from the file at path.
+func LoadJWKS(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ParseJWKS parses a JSON Web Key Set. Keys meant for encryption are
+// skipped, as are key types that do not sign HS256 or RS256.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := &KeySet{rsa: map[string]*rsa.PublicKey{}, hmac: map[string][]byte{}}
+	for i, k := range doc.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		if _, ok := keys.rsa[k.Kid]; ok {
+			return nil, fmt.Errorf("key %d: duplicate kid %q", i, k.Kid)
+		}
+		if _, ok := keys.hmac[k.Kid]; ok {
+			return nil, fmt.Errorf("key %d: duplicate kid %q", i, k.Kid)
+		}
+
+		switch k.Kty {
+		case "RSA":
+			if k.Alg != "" && k.Alg != AlgRS256 {
+				continue
+			}
+			key, err := k.rsaPublicKey()
+			if err != nil {
+				return nil, fmt.Errorf("key %d: %w", i, err)
+			}
+			keys.rsa[k.Kid] = key
+		case "oct":
+			if k.Alg != "" && k.Alg != AlgHS256 {
+				continue
+			}
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("key %d: invalid k", i)
+			}
+			keys.hmac[k.Kid] = secret
+		}
+	}
+	if keys.Len() == 0 {
+		return nil, fmt.Errorf("no RS256 or HS256 signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, fmt.Errorf("invalid n")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, fmt.Errorf("invalid e")
+	}
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if key.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("RSA key shorter than %d bits", minRSABits)
+	}
+	return key, nil
+}
+
+// Len returns the number of keys in the set.
+func (s *KeySet) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rsa) + len(s.hmac)
+}
@@ -0,0 +1,221 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Signature algorithms accepted for JWTs (RFC 7518). Every other one,
+// including "none", is rejected.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+)
+
+// JWTConfig configures a JWTAuthenticator.
+type JWTConfig struct {
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Secret verifies HS256 tokens without a kid.
+	Secret []byte
+	// Keys verifies tokens by their kid, HS256 with symmetric keys and
+	// RS256 with RSA keys.
+	Keys *KeySet
+	// Leeway absorbs clock skew when checking exp and nbf.
+	Leeway time.Duration
+	// Now returns the current time; time.Now if nil.
+	Now func() time.Time
+}
+
+// JWTAuthenticator authenticates requests carrying a JSON Web Token
+// (RFC 7519) as an Authorization bearer token.
+type JWTAuthenticator struct {
+	cfg JWTConfig
+}
+
+// NewJWTAuthenticator returns an authenticator verifying tokens with the
+// keys of cfg, of which there must be at least one.
+func NewJWTAuthenticator(cfg JWTConfig) (*JWTAuthenticator, error) {
+	if len(cfg.Secret) == 0 && cfg.Keys.Len() == 0 {
+		return nil, errors.New("jwt: no verification keys")
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &JWTAuthenticator{cfg: cfg}, nil
+}
+
+func (a *JWTAuthenticator) Authenticate(_ context.Context, header func(name string) string) (*Principal, error) {
+	scheme, token, ok := strings.Cut(header("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, ErrNoCredentials
+	}
+	return a.Verify(strings.TrimSpace(token))
+}
+
+// claims are the registered claims checked by Verify and the private ones
+// making up the principal.
+type claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	Roles     []string `json:"roles"`
+	// Scope holds space separated scopes (RFC 8693).
+	Scope string `json:"scope"`
+}
+
+// audience is the aud claim, a single string or an array of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("aud must be a string or an array of strings")
+	}
+	*a = many
+	return nil
+}
+
+// Verify checks the signature and claims of a compact serialized token and
+// returns the principal it identifies. Tokens must expire and name their
+// subject.
+func (a *JWTAuthenticator) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalid("malformed header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("malformed signature")
+	}
+	if err := a.verifySignature(header.Alg, header.Kid, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var c claims
+	if err := decodeSegment(parts[1], &c); err != nil {
+		return nil, invalid("malformed claims")
+	}
+	if err := a.checkClaims(&c); err != nil {
+		return nil, err
+	}
+	return &Principal{Subject: c.Subject, Roles: c.Roles, Scopes: strings.Fields(c.Scope)}, nil
+}
+
+// verifySignature checks signature with the key kid names. The key type
+// must fit the algorithm, so that an RSA public key can never be used as an
+// HMAC secret.
+func (a *JWTAuthenticator) verifySignature(alg, kid, signed string, signature []byte) error {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case AlgHS256:
+		secret, ok := a.cfg.Keys.hmacKey(kid)
+		if kid == "" && len(a.cfg.Secret) > 0 {
+			secret, ok = a.cfg.Secret, true
+		}
+		if !ok {
+			return invalid("unknown key")
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return invalid("signature mismatch")
+		}
+	case AlgRS256:
+		key, ok := a.cfg.Keys.rsaKey(kid)
+		if !ok {
+			return invalid("unknown key")
+		}
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+			return invalid("signature mismatch")
+		}
+	default:
+		return invalid(fmt.Sprintf("unsupported algorithm %q", alg))
+	}
+	return nil
+}
+
+func (a *JWTAuthenticator) checkClaims(c *claims) error {
+	now := a.cfg.Now()
+	switch {
+	case c.ExpiresAt == nil:
+		return invalid("token has no expiry")
+	case !now.Before(unixTime(*c.ExpiresAt).Add(a.cfg.Leeway)):
+		return invalid("token expired")
+	case c.NotBefore != nil && now.Add(a.cfg.Leeway).Before(unixTime(*c.NotBefore)):
+		return invalid("token not valid yet")
+	case c.Subject == "":
+		return invalid("token has no subject")
+	case a.cfg.Issuer != "" && c.Issuer != a.cfg.Issuer:
+		return invalid("unexpected issuer")
+	}
+	if a.cfg.Audience != "" {
+		for _, aud := range c.Audience {
+			if aud == a.cfg.Audience {
+				return nil
+			}
+		}
+		return invalid("unexpected audience")
+	}
+	return nil
+}
+
+func (s *KeySet) hmacKey(kid string) ([]byte, bool) {
+	if s == nil {
+		return nil, false
+	}
+	key, ok := s.hmac[kid]
+	return key, ok
+}
+
+func (s *KeySet) rsaKey(kid string) (*rsa.PublicKey, bool) {
+	if s == nil {
+		return nil, false
+	}
+	key, ok := s.rsa[kid]
+	return key, ok
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// unixTime converts a NumericDate, seconds that may have a fraction.
+func unixTime(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9))
+}
+
+func invalid(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCredentials, reason)
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+func segment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func signHS256(t *testing.T, secret []byte, header, claims map[string]any) string {
+	t.Helper()
+	header["alg"] = AlgHS256
+	signed := segment(t, header) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, header, claims map[string]any) string {
+	t.Helper()
+	header["alg"] = AlgRS256
+	signed := segment(t, header) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"iss":   "https://issuer.example",
+		"aud":   "experiences",
+		"sub":   "user-1",
+		"exp":   now.Add(time.Hour).Unix(),
+		"roles": []string{"admin"},
+		"scope": "experiences:read experiences:write",
+	}
+}
+
+func with(claims map[string]any, key string, value any) map[string]any {
+	out := map[string]any{}
+	for k, v := range claims {
+		out[k] = v
+	}
+	if value == nil {
+		delete(out, key)
+	} else {
+		out[key] = value
+	}
+	return out
+}
+
+func writeJWKS(t *testing.T, rsaKey *rsa.PublicKey, secret []byte) string {
+	t.Helper()
+	encode := base64.RawURLEncoding.EncodeToString
+	doc := map[string]any{"keys": []map[string]any{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "alg": "RS256", "n": encode(rsaKey.N.Bytes()), "e": encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "oct", "kid": "hmac-1", "k": encode(secret)},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "", "e": ""},
+	}}
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	keyed := []byte("fedcba9876543210fedcba9876543210")
+
+	keys, err := LoadJWKS(writeJWKS(t, &rsaKey.PublicKey, keyed))
+	require.NoError(t, err)
+	require.Equal(t, 2, keys.Len())
+
+	a, err := NewJWTAuthenticator(JWTConfig{
+		Issuer:   "https://issuer.example",
+		Audience: "experiences",
+		Secret:   secret,
+		Keys:     keys,
+		Leeway:   time.Minute,
+		Now:      func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	p, err := a.Verify(signHS256(t, secret, map[string]any{}, validClaims()))
+	require.NoError(t, err)
+	require.Equal(t, &Principal{Subject: "user-1", Roles: []string{"admin"}, Scopes: []string{"experiences:read", "experiences:write"}}, p)
+	require.True(t, p.HasRole("admin"))
+
+	_, err = a.Verify(signHS256(t, keyed, map[string]any{"kid": "hmac-1"}, validClaims()))
+	require.NoError(t, err)
+	_, err = a.Verify(signRS256(t, rsaKey, map[string]any{"kid": "rsa-1"}, validClaims()))
+	require.NoError(t, err)
+	_, err = a.Verify(signHS256(t, secret, map[string]any{}, with(validClaims(), "aud", []string{"other", "experiences"})))
+	require.NoError(t, err)
+	_, err = a.Verify(signHS256(t, secret, map[string]any{}, with(validClaims(), "exp", now.Add(-30*time.Second).Unix())))
+	require.NoError(t, err, "within the leeway")
+
+	rsaPublicDER := rsaKey.PublicKey.N.Bytes()
+	tampered := strings.Split(signHS256(t, secret, map[string]any{}, validClaims()), ".")
+	tampered[1] = segment(t, with(validClaims(), "sub", "admin"))
+	none := segment(t, map[string]any{"alg": "none"}) + "." + segment(t, validClaims()) + "."
+
+	for name, token := range map[string]string{
+		"wrong secret":      signHS256(t, []byte("not the secret at all, not at all"), map[string]any{}, validClaims()),
+		"tampered claims":   strings.Join(tampered, "."),
+		"alg none":          none,
+		"unknown kid":       signRS256(t, rsaKey, map[string]any{"kid": "rsa-2"}, validClaims()),
+		"HS256 with RSA id": signHS256(t, rsaPublicDER, map[string]any{"kid": "rsa-1"}, validClaims()),
+		"RS256 without kid": signRS256(t, rsaKey, map[string]any{}, validClaims()),
+		"expired":           signHS256(t, secret, map[string]any{}, with(validClaims(), "exp", now.Add(-2*time.Minute).Unix())),
+		"no expiry":         signHS256(t, secret, map[string]any{}, with(validClaims(), "exp", nil)),
+		"not yet valid":     signHS256(t, secret, map[string]any{}, with(validClaims(), "nbf", now.Add(2*time.Minute).Unix())),
+		"no subject":        signHS256(t, secret, map[string]any{}, with(validClaims(), "sub", nil)),
+		"wrong issuer":      signHS256(t, secret, map[string]any{}, with(validClaims(), "iss", "https://evil.example")),
+		"wrong audience":    signHS256(t, secret, map[string]any{}, with(validClaims(), "aud", []string{"other"})),
+		"malformed":         "a.b",
+	} {
+		_, err := a.Verify(token)
+		require.ErrorIs(t, err, ErrInvalidCredentials, name)
+	}
+}
+
+func TestJWTAuthenticatorHeader(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	a, err := NewJWTAuthenticator(JWTConfig{Secret: secret, Now: func() time.Time { return now }})
+	require.NoError(t, err)
+	token := signHS256(t, secret, map[string]any{}, validClaims())
+
+	headers := func(value string) func(string) string {
+		return func(name string) string {
+			if name == "Authorization" {
+				return value
+			}
+			return ""
+		}
+	}
+	p, err := a.Authenticate(context.Background(), headers("bearer "+token))
+	require.NoError(t, err)
+	require.Equal(t, "user-1", p.Subject)
+
+	_, err = a.Authenticate(context.Background(), headers(""))
+	require.ErrorIs(t, err, ErrNoCredentials)
+	_, err = a.Authenticate(context.Background(), headers("Basic dXNlcjpwYXNz"))
+	require.ErrorIs(t, err, ErrNoCredentials)
+	_, err = a.Authenticate(context.Background(), headers("Bearer nope"))
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// A chain stops at the first authenticator that finds credentials.
+	p, err = Chain(none{}, a).Authenticate(context.Background(), headers("Bearer "+token))
+	require.NoError(t, err)
+	require.Equal(t, "user-1", p.Subject)
+	_, err = Chain(none{}).Authenticate(context.Background(), headers("Bearer "+token))
+	require.ErrorIs(t, err, ErrNoCredentials)
+
+	_, err = NewJWTAuthenticator(JWTConfig{})
+	require.Error(t, err)
+}
+
+type none struct{}
+
+func (none) Authenticate(context.Context, func(string) string) (*Principal, error) {
+	return nil, ErrNoCredentials
+}
+
+func TestParseJWKS(t *testing.T) {
+	for name, doc := range map[string]string{
+		"not JSON":      `{`,
+		"no keys":       `{"keys": []}`,
+		"short RSA key": `{"keys": [{"kty": "RSA", "n": "AQAB", "e": "AQAB"}]}`,
+		"bad oct key":   `{"keys": [{"kty": "oct", "k": "!"}]}`,
+		"duplicate kid": `{"keys": [{"kty": "oct", "kid": "a", "k": "AQAB"}, {"kty": "oct", "kid": "a", "k": "AQAB"}]}`,
+	} {
+		_, err := ParseJWKS([]byte(doc))
+		require.Error(t, err, name)
+	}
+
+	keys, err := ParseJWKS([]byte(`{"keys": [{"kty": "EC", "kid": "ec"}, {"kty": "oct", "kid": "a", "k": "AQAB"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, 1, keys.Len())
+}
@@ -0,0 +1,499 @@
+package configs
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the full runtime configuration of the service.
+//
+// Values are resolved in the following order, later sources overriding
+// earlier ones: built-in defaults, the optional config file (YAML or JSON),
+// environment variables and finally command-line flags.
+type Config struct {
+	Server  ServerConfig
+	Storage StorageConfig
+	Mongo   MongoConfig
+	Log     LogConfig
+	Health  HealthConfig
+	API     APIConfig
+	Auth    AuthConfig
+}
+
+type ServerConfig struct {
+	Addr            string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+	RequestTimeout  time.Duration
+	ErrorFormat     string
+}
+
+// Storage backends selectable with storage.backend.
+const (
+	BackendMongo  = "mongo"
+	BackendMemory = "memory"
+	BackendFile   = "file"
+)
+
+type StorageConfig struct {
+	Backend  string
+	FilePath string
+	// TrashRetention is how long deleted experiences stay in the trash
+	// before being purged; zero keeps them until purged by hand.
+	TrashRetention time.Duration
+	PurgeInterval  time.Duration
+	// IdempotencyTTL is how long the response to a request sent with an
+	// Idempotency-Key is kept for replay.
+	IdempotencyTTL time.Duration
+}
+
+type MongoConfig struct {
+	URI             string
+	Database        string
+	Collection      string
+	ConnectTimeout  time.Duration
+	ConnectRetries  int
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+}
+
+type LogConfig struct {
+	Level string
+}
+
+type HealthConfig struct {
+	Timeout time.Duration
+}
+
+// APIConfig controls the unversioned routes that predate /api/v1.
+type APIConfig struct {
+	LegacyRoutes bool
+	LegacySunset time.Time
+}
+
+// AuthConfig controls authentication of the API with JWT bearer tokens.
+// Tokens are verified with the HS256 secret, the keys of the JWKS file, or
+// both.
+type AuthConfig struct {
+	Enabled     bool
+	Issuer      string
+	Audience    string
+	HS256Secret string
+	JWKSFile    string
+	// Leeway absorbs clock skew when checking token expiry.
+	Leeway time.Duration
+	// PolicyFile holds the access policy (YAML or JSON); the built-in
+	// default policy applies without one.
+	PolicyFile string
+	// DefaultOwner, if set, is given at startup to the experiences without
+	// an owner, so that their owner keeps seeing them once auth is enabled.
+	DefaultOwner string
+}
+
+// minHS256Secret is the shortest HS256 secret accepted, the output size of
+// SHA-256 (RFC 7518, section 3.2).
+const minHS256Secret = 32
+
+// Default returns the configuration used when no other source sets a value.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:            ":6000",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 15 * time.Second,
+			RequestTimeout:  5 * time.Second,
+			ErrorFormat:     "envelope",
+		},
+		Storage: StorageConfig{
+			Backend:        BackendMongo,
+			FilePath:       "experiences.json",
+			TrashRetention: 30 * 24 * time.Hour,
+			PurgeInterval:  time.Hour,
+			IdempotencyTTL: 24 * time.Hour,
+		},
+		Mongo: MongoConfig{
+			URI:             "mongodb://localhost:27017",
+			Database:        "TODOLIST",
+			Collection:      "experience",
+			ConnectTimeout:  10 * time.Second,
+			ConnectRetries:  5,
+			RetryBackoff:    500 * time.Millisecond,
+			MaxRetryBackoff: 10 * time.Second,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+		Health: HealthConfig{
+			Timeout: 2 * time.Second,
+		},
+		API: APIConfig{
+			LegacyRoutes: true,
+			LegacySunset: time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC),
+		},
+		Auth: AuthConfig{
+			Leeway: 30 * time.Second,
+		},
+	}
+}
+
+// field describes a single configuration key. The environment variable and
+// flag names are derived from the key: "mongo.uri" becomes MONGO_URI and
+// -mongo-uri.
+type field struct {
+	key   string
+	usage string
+	ptr   func(c *Config) any
+}
+
+func (f field) env() string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(f.key))
+}
+
+func (f field) flag() string {
+	return strings.ReplaceAll(f.key, ".", "-")
+}
+
+var fields = []field{
+	{"server.addr", "HTTP listen address", func(c *Config) any { return &c.Server.Addr }},
+	{"server.read-timeout", "maximum duration for reading a request", func(c *Config) any { return &c.Server.ReadTimeout }},
+	{"server.write-timeout", "maximum duration for writing a response", func(c *Config) any { return &c.Server.WriteTimeout }},
+	{"server.idle-timeout", "maximum keep-alive idle time", func(c *Config) any { return &c.Server.IdleTimeout }},
+	{"server.shutdown-timeout", "how long to drain in-flight requests on shutdown", func(c *Config) any { return &c.Server.ShutdownTimeout }},
+	{"server.request-timeout", "deadline for storage operations made by a single request", func(c *Config) any { return &c.Server.RequestTimeout }},
+	{"server.error-format", "default error body format (envelope, problem)", func(c *Config) any { return &c.Server.ErrorFormat }},
+	{"storage.backend", "storage backend (mongo, memory, file)", func(c *Config) any { return &c.Storage.Backend }},
+	{"storage.file-path", "data file used by the file backend", func(c *Config) any { return &c.Storage.FilePath }},
+	{"storage.trash-retention", "how long deleted experiences stay in the trash (0 keeps them)", func(c *Config) any { return &c.Storage.TrashRetention }},
+	{"storage.purge-interval", "how often expired trash is purged", func(c *Config) any { return &c.Storage.PurgeInterval }},
+	{"storage.idempotency-ttl", "how long responses to requests with an Idempotency-Key are replayed", func(c *Config) any { return &c.Storage.IdempotencyTTL }},
+	{"mongo.uri", "MongoDB connection string", func(c *Config) any { return &c.Mongo.URI }},
+	{"mongo.database", "MongoDB database name", func(c *Config) any { return &c.Mongo.Database }},
+	{"mongo.collection", "MongoDB collection holding experiences", func(c *Config) any { return &c.Mongo.Collection }},
+	{"mongo.connect-timeout", "timeout for establishing the MongoDB connection", func(c *Config) any { return &c.Mongo.ConnectTimeout }},
+	{"mongo.connect-retries", "number of times to retry the initial MongoDB connection", func(c *Config) any { return &c.Mongo.ConnectRetries }},
+	{"mongo.retry-backoff", "delay before the first connection retry, doubled on each attempt", func(c *Config) any { return &c.Mongo.RetryBackoff }},
+	{"mongo.max-retry-backoff", "upper bound for the connection retry delay", func(c *Config) any { return &c.Mongo.MaxRetryBackoff }},
+	{"log.level", "log level (debug, info, warn, error)", func(c *Config) any { return &c.Log.Level }},
+	{"health.timeout", "timeout for each readiness check", func(c *Config) any { return &c.Health.Timeout }},
+	{"api.legacy-routes", "serve the deprecated unversioned /api routes", func(c *Config) any { return &c.API.LegacyRoutes }},
+	{"api.legacy-sunset", "date announced for removing the legacy routes (YYYY-MM-DD)", func(c *Config) any { return &c.API.LegacySunset }},
+	{"auth.enabled", "require a JWT bearer token on the API", func(c *Config) any { return &c.Auth.Enabled }},
+	{"auth.issuer", "expected iss claim of tokens (any if empty)", func(c *Config) any { return &c.Auth.Issuer }},
+	{"auth.audience", "expected aud claim of tokens (any if empty)", func(c *Config) any { return &c.Auth.Audience }},
+	{"auth.hs256-secret", "shared secret verifying HS256 tokens", func(c *Config) any { return &c.Auth.HS256Secret }},
+	{"auth.jwks-file", "JSON Web Key Set file verifying RS256 and HS256 tokens", func(c *Config) any { return &c.Auth.JWKSFile }},
+	{"auth.leeway", "allowed clock skew when checking token expiry", func(c *Config) any { return &c.Auth.Leeway }},
+	{"auth.policy-file", "access policy file (YAML or JSON) granting permissions to roles", func(c *Config) any { return &c.Auth.PolicyFile }},
+	{"auth.default-owner", "subject given the experiences without an owner at startup (none if empty)", func(c *Config) any { return &c.Auth.DefaultOwner }},
+}
+
+// ConfigFileEnv names the environment variable holding the config file path.
+// The -config flag takes precedence over it.
+const ConfigFileEnv = "CONFIG_FILE"
+
+// Load resolves the configuration from defaults, the config file, the
+// environment and the given command-line arguments (without the program
+// name), then validates the result.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv(ConfigFileEnv), "path to a YAML or JSON config file")
+	values := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		values[f.key] = fs.String(f.flag(), "", f.usage+" (env "+f.env()+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+
+	if *configFile != "" {
+		problems = append(problems, cfg.loadFile(*configFile)...)
+	}
+
+	for _, f := range fields {
+		if v, ok := os.LookupEnv(f.env()); ok {
+			if err := set(f.ptr(cfg), v); err != nil {
+				problems = append(problems, fmt.Sprintf("%s (env %s): %v", f.key, f.env(), err))
+			}
+		}
+	}
+
+	byFlag := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byFlag[f.flag()] = f
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		f, ok := byFlag[fl.Name]
+		if !ok {
+			return
+		}
+		if err := set(f.ptr(cfg), *values[f.key]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (flag -%s): %v", f.key, f.flag(), err))
+		}
+	})
+
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []string{fmt.Sprintf("config file: %v", err)}
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("config file %s: %v", path, err)}
+	}
+
+	flat := map[string]string{}
+	flatten("", raw, flat)
+
+	byKey := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+
+	var problems []string
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		f, ok := byKey[k]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s (file %s): unknown key", k, path))
+			continue
+		}
+		if err := set(f.ptr(c), flat[k]); err != nil {
+			problems = append(problems, fmt.Sprintf("%s (file %s): %v", k, path, err))
+		}
+	}
+	return problems
+}
+
+func flatten(prefix string, in map[string]any, out map[string]string) {
+	for k, v := range in {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(key, v, out)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(items, ",")
+		case nil:
+			out[key] = ""
+		case time.Time:
+			out[key] = v.Format(time.RFC3339)
+		default:
+			out[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+func set(ptr any, value string) error {
+	value = strings.TrimSpace(value)
+	switch p := ptr.(type) {
+	case *string:
+		*p = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		*p = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+		*p = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration (e.g. 5s, 1m)", value)
+		}
+		*p = d
+	case *time.Time:
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, value); err != nil {
+				return fmt.Errorf("%q is not a date (YYYY-MM-DD)", value)
+			}
+		}
+		*p = t.UTC()
+	case *[]string:
+		*p = nil
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*p = append(*p, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", ptr)
+	}
+	return nil
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+
+	if c.Server.Addr == "" {
+		problems = append(problems, "server.addr: must not be empty")
+	}
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"server.read-timeout", c.Server.ReadTimeout},
+		{"server.write-timeout", c.Server.WriteTimeout},
+		{"server.idle-timeout", c.Server.IdleTimeout},
+		{"server.shutdown-timeout", c.Server.ShutdownTimeout},
+		{"server.request-timeout", c.Server.RequestTimeout},
+		{"health.timeout", c.Health.Timeout},
+		{"storage.purge-interval", c.Storage.PurgeInterval},
+		{"storage.idempotency-ttl", c.Storage.IdempotencyTTL},
+	} {
+		if d.value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s: must be greater than zero", d.key))
+		}
+	}
+	if c.Storage.TrashRetention < 0 {
+		problems = append(problems, "storage.trash-retention: must not be negative")
+	}
+
+	if c.Server.ErrorFormat != "envelope" && c.Server.ErrorFormat != "problem" {
+		problems = append(problems, fmt.Sprintf("server.error-format: %q is not one of envelope, problem", c.Server.ErrorFormat))
+	}
+
+	switch c.Storage.Backend {
+	case BackendMongo:
+		problems = append(problems, c.Mongo.validate()...)
+	case BackendMemory:
+	case BackendFile:
+		if c.Storage.FilePath == "" {
+			problems = append(problems, "storage.file-path: must not be empty for the file backend")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("storage.backend: %q is not one of mongo, memory, file", c.Storage.Backend))
+	}
+
+	if _, err := ParseLogLevel(c.Log.Level); err != nil {
+		problems = append(problems, "log.level: "+err.Error())
+	}
+
+	if c.Auth.Enabled {
+		problems = append(problems, c.Auth.validate()...)
+	}
+
+	return problems
+}
+
+func (c AuthConfig) validate() []string {
+	var problems []string
+
+	if c.HS256Secret == "" && c.JWKSFile == "" {
+		problems = append(problems, "auth: one of auth.hs256-secret, auth.jwks-file is required when auth is enabled")
+	}
+	if c.HS256Secret != "" && len(c.HS256Secret) < minHS256Secret {
+		problems = append(problems, fmt.Sprintf("auth.hs256-secret: must be at least %d bytes", minHS256Secret))
+	}
+	if c.Leeway < 0 {
+		problems = append(problems, "auth.leeway: must not be negative")
+	}
+
+	return problems
+}
+
+func (c MongoConfig) validate() []string {
+	var problems []string
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"mongo.connect-timeout", c.ConnectTimeout},
+		{"mongo.retry-backoff", c.RetryBackoff},
+		{"mongo.max-retry-backoff", c.MaxRetryBackoff},
+	} {
+		if d.value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s: must be greater than zero", d.key))
+		}
+	}
+
+	if c.URI == "" {
+		problems = append(problems, "mongo.uri: must not be empty")
+	} else if u, err := url.Parse(c.URI); err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") {
+		problems = append(problems, "mongo.uri: must start with mongodb:// or mongodb+srv://")
+	}
+	if c.ConnectRetries < 0 {
+		problems = append(problems, "mongo.connect-retries: must not be negative")
+	}
+	if c.Database == "" {
+		problems = append(problems, "mongo.database: must not be empty")
+	}
+	if c.Collection == "" {
+		problems = append(problems, "mongo.collection: must not be empty")
+	}
+
+	return problems
+}
+
+// ParseLogLevel converts a configured level name into a slog.Level.
+func ParseLogLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("%q is not one of debug, info, warn, error", level)
+	}
+	return l, nil
+}
+
+// ValidationError lists every problem found while loading the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString("invalid configuration:")
+	for _, p := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(p)
+	}
+	return b.String()
+}
@@ -0,0 +1,130 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	require.Equal(t, Default(), cfg)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+server:
+  addr: ":7000"
+  read-timeout: 3s
+mongo:
+  database: staging
+  collection: jobs
+`), 0o600))
+
+	t.Setenv(ConfigFileEnv, file)
+	t.Setenv("MONGO_DATABASE", "from-env")
+	t.Setenv("SERVER_ADDR", ":8000")
+
+	cfg, err := Load([]string{"-server-addr", ":9000"})
+	require.NoError(t, err)
+
+	require.Equal(t, ":9000", cfg.Server.Addr)
+	require.Equal(t, 3*time.Second, cfg.Server.ReadTimeout)
+	require.Equal(t, "from-env", cfg.Mongo.Database)
+	require.Equal(t, "jobs", cfg.Mongo.Collection)
+}
+
+func TestLoadJSONFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"log": {"level": "debug"}}`), 0o600))
+
+	cfg, err := Load([]string{"-config", file})
+	require.NoError(t, err)
+	require.Equal(t, "debug", cfg.Log.Level)
+}
+
+func TestLoadValidation(t *testing.T) {
+	t.Setenv("MONGO_URI", "http://example.com")
+	t.Setenv("SERVER_READ_TIMEOUT", "soon")
+
+	_, err := Load([]string{"-log-level", "loud", "-mongo-database", ""})
+	require.Error(t, err)
+
+	verr, ok := err.(*ValidationError)
+	require.True(t, ok)
+	require.Len(t, verr.Problems, 4)
+	require.Contains(t, err.Error(), "mongo.uri: must start with mongodb://")
+	require.Contains(t, err.Error(), "server.read-timeout (env SERVER_READ_TIMEOUT)")
+}
+
+func TestLoadLegacySunset(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("api:\n  legacy-sunset: 2027-01-31\n"), 0o600))
+
+	cfg, err := Load([]string{"-config", file})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC), cfg.API.LegacySunset)
+	require.True(t, cfg.API.LegacyRoutes)
+
+	cfg, err = Load([]string{"-config", file, "-api-legacy-sunset", "2028-02-29", "-api-legacy-routes=false"})
+	require.NoError(t, err)
+	require.Equal(t, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC), cfg.API.LegacySunset)
+	require.False(t, cfg.API.LegacyRoutes)
+
+	_, err = Load([]string{"-api-legacy-sunset", "someday"})
+	require.ErrorContains(t, err, "api.legacy-sunset (flag -api-legacy-sunset)")
+}
+
+func TestLoadTrashRetention(t *testing.T) {
+	cfg, err := Load([]string{"-storage-trash-retention", "0"})
+	require.NoError(t, err)
+	require.Zero(t, cfg.Storage.TrashRetention)
+	require.Equal(t, time.Hour, cfg.Storage.PurgeInterval)
+
+	_, err = Load([]string{"-storage-trash-retention", "-1h", "-storage-purge-interval", "0s"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "storage.trash-retention: must not be negative")
+	require.Contains(t, err.Error(), "storage.purge-interval: must be greater than zero")
+}
+
+func TestLoadIdempotencyTTL(t *testing.T) {
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	require.Equal(t, 24*time.Hour, cfg.Storage.IdempotencyTTL)
+
+	t.Setenv("STORAGE_IDEMPOTENCY_TTL", "10m")
+	cfg, err = Load(nil)
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Minute, cfg.Storage.IdempotencyTTL)
+
+	_, err = Load([]string{"-storage-idempotency-ttl", "0s"})
+	require.ErrorContains(t, err, "storage.idempotency-ttl: must be greater than zero")
+}
+
+func TestLoadAuth(t *testing.T) {
+	cfg, err := Load(nil)
+	require.NoError(t, err)
+	require.False(t, cfg.Auth.Enabled)
+	require.Equal(t, 30*time.Second, cfg.Auth.Leeway)
+
+	_, err = Load([]string{"-auth-enabled", "true"})
+	require.ErrorContains(t, err, "one of auth.hs256-secret, auth.jwks-file is required")
+
+	_, err = Load([]string{"-auth-enabled", "true", "-auth-hs256-secret", "short"})
+	require.ErrorContains(t, err, "auth.hs256-secret: must be at least 32 bytes")
+
+	t.Setenv("AUTH_ENABLED", "true")
+	t.Setenv("AUTH_JWKS_FILE", "/etc/experiences/jwks.json")
+	cfg, err = Load([]string{"-auth-audience", "experiences", "-auth-policy-file", "policy.yaml", "-auth-default-owner", "alice"})
+	require.NoError(t, err)
+	require.Equal(t, "policy.yaml", cfg.Auth.PolicyFile)
+	require.Equal(t, "alice", cfg.Auth.DefaultOwner)
+	require.Equal(t, "/etc/experiences/jwks.json", cfg.Auth.JWKSFile)
+	require.Equal(t, "experiences", cfg.Auth.Audience)
+}
@@ -0,0 +1,39 @@
+package configs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectDBRetriesThenFails(t *testing.T) {
+	cfg := MongoConfig{
+		URI:             "mongodb://127.0.0.1:1/?connect=direct",
+		ConnectTimeout:  50 * time.Millisecond,
+		ConnectRetries:  2,
+		RetryBackoff:    10 * time.Millisecond,
+		MaxRetryBackoff: 15 * time.Millisecond,
+	}
+
+	db, err := ConnectDB(context.Background(), cfg)
+	require.Nil(t, db)
+	require.ErrorContains(t, err, "after 3 attempts")
+}
+
+func TestConnectDBHonoursContext(t *testing.T) {
+	cfg := MongoConfig{
+		URI:             "mongodb://127.0.0.1:1/?connect=direct",
+		ConnectTimeout:  20 * time.Millisecond,
+		ConnectRetries:  100,
+		RetryBackoff:    time.Second,
+		MaxRetryBackoff: time.Second,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := ConnectDB(ctx, cfg)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"GO-Project/models"
+	"GO-Project/policy"
+	"GO-Project/responses"
+	"GO-Project/services"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type apiKeyHandle struct {
+	keys services.APIKeyService
+}
+
+// NewAPIKeyHandle registers the routes managing API keys on router,
+// relative to the prefix of its API version group. They all require the
+// apikeys:manage action.
+func NewAPIKeyHandle(router fiber.Router, keys services.APIKeyService) {
+	c := apiKeyHandle{
+		keys: keys,
+	}
+	manage := Require(policy.ActionManageKeys)
+	router.Post("/apikeys", manage, c.Create)
+	router.Get("/apikeys", manage, c.List)
+	router.Get("/apikeys/:apiKeyId", manage, c.Get)
+	router.Post("/apikeys/:apiKeyId/rotate", manage, c.Rotate)
+	router.Delete("/apikeys/:apiKeyId", manage, c.Revoke)
+}
+
+// Create makes a key and returns it with the full key, which is not shown
+// again.
+func (h apiKeyHandle) Create(c *fiber.Ctx) error {
+	var dto *models.APIKeyDto
+	if err := c.BodyParser(&dto); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "invalid request body")
+	}
+	if dto == nil {
+		dto = &models.APIKeyDto{}
+	}
+
+	k, key, err := h.keys.Create(c.UserContext(), dto)
+	if err != nil {
+		return err
+	}
+
+	view := models.NewAPIKeyView(k)
+	view.Key = key
+	return c.Status(http.StatusCreated).JSON(responses.MessageResponse{
+		Status:  http.StatusCreated,
+		Message: "success",
+		Data:    &fiber.Map{"data": view},
+	})
+}
+
+// List returns every key, revoked ones included, without their secrets.
+func (h apiKeyHandle) List(c *fiber.Ctx) error {
+	keys, err := h.keys.List(c.UserContext())
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    &fiber.Map{"data": models.NewAPIKeyViews(keys)},
+	})
+}
+
+func (h apiKeyHandle) Get(c *fiber.Ctx) error {
+	id, err := apiKeyId(c)
+	if err != nil {
+		return err
+	}
+
+	k, err := h.keys.Get(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    &fiber.Map{"data": models.NewAPIKeyView(k)},
+	})
+}
+
+// Rotate gives a key a new secret, returned like on Create. The previous
+// one stops working at once.
+func (h apiKeyHandle) Rotate(c *fiber.Ctx) error {
+	id, err := apiKeyId(c)
+	if err != nil {
+		return err
+	}
+
+	k, key, err := h.keys.Rotate(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	view := models.NewAPIKeyView(k)
+	view.Key = key
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    &fiber.Map{"data": view},
+	})
+}
+
+// Revoke disables a key. It is kept, and listed, with its revocation time.
+func (h apiKeyHandle) Revoke(c *fiber.Ctx) error {
+	id, err := apiKeyId(c)
+	if err != nil {
+		return err
+	}
+
+	k, err := h.keys.Revoke(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    &fiber.Map{"data": models.NewAPIKeyView(k)},
+	})
+}
+
+func apiKeyId(c *fiber.Ctx) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Params("apiKeyId"))
+	if err != nil || objID.IsZero() {
+		return primitive.NilObjectID, fiber.NewError(http.StatusBadRequest, "invalid api key id")
+	}
+	return objID, nil
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"GO-Project/auth"
+	"GO-Project/models"
+	"GO-Project/policy"
+	"GO-Project/services"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	keys := services.NewAPIKeyService(services.NewMemoryAPIKeyStore(), policy.Default())
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	f.Use(Authenticate(auth.Chain(staticAuthenticator{
+		"Bearer admin":  {Subject: "root", Roles: []string{policy.RoleAdmin}},
+		"Bearer member": {Subject: "alice"},
+	}, keys)))
+	f.Use(Enforce(policy.Default()))
+	v1 := NewV1(f, services.NewMemoryExperienceService())
+	NewAPIKeyHandle(v1, keys)
+
+	send := func(name, value, method, url, body string) *http.Response {
+		request := httptest.NewRequest(method, url, strings.NewReader(body))
+		if name != "" {
+			request.Header.Set(name, value)
+		}
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		response, err := f.Test(request)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = response.Body.Close() })
+		return response
+	}
+	admin := func(method, url, body string) *http.Response {
+		return send(fiber.HeaderAuthorization, "Bearer admin", method, url, body)
+	}
+	withKey := func(key, method, url, body string) *http.Response {
+		return send(services.HeaderAPIKey, key, method, url, body)
+	}
+	decode := func(response *http.Response) models.APIKeyView {
+		var body struct {
+			Data struct{ Data models.APIKeyView }
+		}
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+		return body.Data.Data
+	}
+	payload := `{"company": "Acme", "title": "Dev", "startDate": "2020-01-01T00:00:00Z"}`
+
+	require.Equal(t, 403, send(fiber.HeaderAuthorization, "Bearer member", fiber.MethodGet, "/api/v1/apikeys", "").StatusCode)
+	// Keys grant no more than their creator holds.
+	_, keyManager, err := keys.Create(auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "root", Roles: []string{policy.RoleAdmin}}),
+		&models.APIKeyDto{Name: "keys", Permissions: []string{"apikeys:manage"}})
+	require.NoError(t, err)
+	require.Equal(t, 403, withKey(keyManager, fiber.MethodPost, "/api/v1/apikeys", `{"name": "ci", "subject": "alice", "permissions": ["experiences:*:any"]}`).StatusCode)
+	require.Equal(t, 422, admin(fiber.MethodPost, "/api/v1/apikeys", `{"name": "ci", "permissions": ["experiences:publish"]}`).StatusCode)
+
+	response := admin(fiber.MethodPost, "/api/v1/apikeys", `{"name": "ci", "subject": "ci-bot", "permissions": ["experiences:create"]}`)
+	require.Equal(t, 201, response.StatusCode)
+	created := decode(response)
+	require.Equal(t, "ci-bot", created.Subject)
+	require.Equal(t, "root", created.CreatedBy)
+	require.NotEmpty(t, created.Key)
+	require.Equal(t, 403, withKey(keyManager, fiber.MethodPost, "/api/v1/apikeys/"+created.ID+"/rotate", "").StatusCode)
+
+	// The key is limited to its permissions.
+	require.Equal(t, 201, withKey(created.Key, fiber.MethodPost, "/api/v1/experiences", payload).StatusCode)
+	require.Equal(t, 403, withKey(created.Key, fiber.MethodGet, "/api/v1/experiences", "").StatusCode)
+	require.Equal(t, 403, withKey(created.Key, fiber.MethodGet, "/api/v1/apikeys", "").StatusCode)
+	require.Equal(t, 401, withKey("exk_nope", fiber.MethodGet, "/api/v1/experiences", "").StatusCode)
+
+	response = admin(fiber.MethodGet, "/api/v1/apikeys", "")
+	require.Equal(t, 200, response.StatusCode)
+	var listed struct {
+		Data struct{ Data []models.APIKeyView }
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&listed))
+	require.Len(t, listed.Data.Data, 2)
+	require.Equal(t, created.ID, listed.Data.Data[1].ID)
+	require.Empty(t, listed.Data.Data[1].Key, "secrets are only shown once")
+	require.NotNil(t, listed.Data.Data[1].LastUsedAt)
+
+	response = admin(fiber.MethodPost, "/api/v1/apikeys/"+created.ID+"/rotate", "")
+	require.Equal(t, 200, response.StatusCode)
+	rotated := decode(response)
+	require.NotEqual(t, created.Key, rotated.Key)
+	require.Equal(t, 401, withKey(created.Key, fiber.MethodPost, "/api/v1/experiences", payload).StatusCode)
+	require.Equal(t, 201, withKey(rotated.Key, fiber.MethodPost, "/api/v1/experiences", payload).StatusCode)
+
+	response = admin(fiber.MethodDelete, "/api/v1/apikeys/"+created.ID, "")
+	require.Equal(t, 200, response.StatusCode)
+	require.NotNil(t, decode(response).RevokedAt)
+	require.Equal(t, 401, withKey(rotated.Key, fiber.MethodPost, "/api/v1/experiences", payload).StatusCode)
+	require.Equal(t, 409, admin(fiber.MethodPost, "/api/v1/apikeys/"+created.ID+"/rotate", "").StatusCode)
+	require.Equal(t, 404, admin(fiber.MethodGet, "/api/v1/apikeys/000000000000000000000001", "").StatusCode)
+	require.Equal(t, 400, admin(fiber.MethodGet, "/api/v1/apikeys/nope", "").StatusCode)
+}
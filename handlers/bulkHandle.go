@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"GO-Project/models"
+	"GO-Project/policy"
+	"GO-Project/responses"
+	"GO-Project/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// bulkRequest is the body of POST /experiences/bulk.
+type bulkRequest struct {
+	// Atomic applies either every operation or none.
+	Atomic     bool                   `json:"atomic"`
+	Operations []bulkOperationRequest `json:"operations"`
+}
+
+// bulkOperationRequest is a single write of a batch. ifMatch and
+// ifNoneMatch take the values of the matching headers of a single write.
+type bulkOperationRequest struct {
+	Op          string          `json:"op"`
+	ID          string          `json:"id"`
+	IfMatch     string          `json:"ifMatch"`
+	IfNoneMatch string          `json:"ifNoneMatch"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// bulkResultView reports the outcome of the operation at Index with the
+// status code and error message it would have got as a single request.
+type bulkResultView struct {
+	Index  int                      `json:"index"`
+	Op     string                   `json:"op"`
+	ID     string                   `json:"id,omitempty"`
+	Status int                      `json:"status"`
+	Data   *models.ExperienceView   `json:"data,omitempty"`
+	Error  string                   `json:"error,omitempty"`
+	Errors []responses.ProblemField `json:"errors,omitempty"`
+}
+
+// Bulk runs a batch of creates, full updates and deletes. The response is
+// 200 when every operation succeeded and 207 Multi-Status otherwise, with a
+// result per operation in request order. A malformed batch is rejected as a
+// whole, as is one with an operation the policy does not allow.
+func (e expHandle) Bulk(c *fiber.Ctx) error {
+	if mediaType(c) != fiber.MIMEApplicationJSON {
+		return fiber.NewError(http.StatusUnsupportedMediaType, "bulk requires application/json")
+	}
+	var req bulkRequest
+	if err := json.Unmarshal(c.Body(), &req); err != nil {
+		return fiber.NewError(http.StatusBadRequest, "invalid request body")
+	}
+
+	ops, err := bulkOperations(req.Operations)
+	if err != nil {
+		return err
+	}
+	if err := authorize(c, bulkActions(ops)...); err != nil {
+		return err
+	}
+	results, err := e.expService.Bulk(c.UserContext(), ops, req.Atomic)
+	if err != nil {
+		return err
+	}
+
+	views := make([]bulkResultView, len(results))
+	failed := 0
+	for i, r := range results {
+		views[i] = bulkResultView{Index: i, Op: ops[i].Op, Status: http.StatusOK}
+		if !r.ID.IsZero() {
+			views[i].ID = r.ID.Hex()
+		}
+		switch {
+		case r.Err != nil:
+			failed++
+			status, message, fields := classify(r.Err)
+			if status >= http.StatusInternalServerError {
+				slog.Error("bulk operation failed", slog.Int("index", i), slog.Any("err", r.Err))
+			}
+			views[i].Status, views[i].Error, views[i].Errors = status, message, problemFields(fields)
+		case r.Experience != nil:
+			view := models.NewExperienceView(r.Experience)
+			views[i].Data = &view
+		}
+		if r.Err == nil && ops[i].Op == services.BulkCreate {
+			views[i].Status = http.StatusCreated
+		}
+	}
+
+	status := http.StatusOK
+	if failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	return c.Status(status).JSON(responses.MessageResponse{
+		Status:  status,
+		Message: "success",
+		Data: &fiber.Map{
+			"data": views,
+			"meta": fiber.Map{
+				"total":     len(views),
+				"succeeded": len(views) - failed,
+				"failed":    failed,
+				"atomic":    req.Atomic,
+			},
+		},
+	})
+}
+
+// bulkActions returns the policy actions of the operations of a batch.
+func bulkActions(ops []services.BulkOperation) []policy.Action {
+	actions := []policy.Action{}
+	for _, op := range ops {
+		action := map[string]policy.Action{
+			services.BulkCreate: policy.ActionCreate,
+			services.BulkUpdate: policy.ActionUpdate,
+			services.BulkDelete: policy.ActionDelete,
+		}[op.Op]
+		if !slices.Contains(actions, action) {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// bulkOperations decodes the operations of a batch, reporting the fields of
+// every invalid one at once.
+func bulkOperations(reqs []bulkOperationRequest) ([]services.BulkOperation, error) {
+	ops := make([]services.BulkOperation, len(reqs))
+	var errs []services.FieldError
+	for i, req := range reqs {
+		field := fmt.Sprintf("operations[%d].", i)
+		ops[i] = services.BulkOperation{
+			Op:   req.Op,
+			Cond: parsePrecondition(req.IfMatch, req.IfNoneMatch),
+		}
+		if req.ID != "" {
+			id, err := primitive.ObjectIDFromHex(req.ID)
+			if err != nil || id.IsZero() {
+				errs = append(errs, services.FieldError{Field: field + "id", Rule: "objectid", Message: "must be an experience id"})
+			}
+			ops[i].ID = id
+		}
+		if len(req.Data) == 0 || string(req.Data) == "null" {
+			continue
+		}
+		dto, err := services.DecodeExperience(req.Data)
+		var serviceErr *services.Error
+		switch {
+		case errors.As(err, &serviceErr) && len(serviceErr.Fields) > 0:
+			for _, fe := range serviceErr.Fields {
+				if fe.Field == "body" {
+					fe.Field = field + "data"
+				} else {
+					fe.Field = field + "data." + fe.Field
+				}
+				errs = append(errs, fe)
+			}
+		case err != nil:
+			return nil, err
+		}
+		ops[i].Experience = dto
+	}
+	if err := services.NewValidationError(errs); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
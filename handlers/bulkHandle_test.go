@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"GO-Project/models"
+	"GO-Project/services"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulk(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	svc := services.NewMemoryExperienceService()
+	NewV1(f, svc)
+
+	existing, err := svc.Create(context.Background(), &models.ExperienceDto{Company: "Acme", Title: "Dev", StartDate: time.Now()})
+	require.NoError(t, err)
+	id := existing.ID.Hex()
+
+	type result struct {
+		Index  int
+		Op     string
+		ID     string
+		Status int
+		Data   map[string]any
+		Error  string
+	}
+	type responseBody struct {
+		Status int
+		Data   struct {
+			Data   []result
+			Meta   map[string]any
+			Errors []map[string]string
+		}
+	}
+	send := func(contentType, payload string) (*http.Response, responseBody) {
+		request := httptest.NewRequest(fiber.MethodPost, "/api/v1/experiences/bulk", strings.NewReader(payload))
+		request.Header.Set(fiber.HeaderContentType, contentType)
+		response, err := f.Test(request)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		// Error responses carry a message in data.data, which leaves
+		// body.Data.Data empty but still decodes the rest.
+		var body responseBody
+		_ = json.NewDecoder(response.Body).Decode(&body)
+		return response, body
+	}
+	valid := func(title string) string {
+		return `{"company": "Acme", "title": "` + title + `", "startDate": "2020-01-01T00:00:00Z"}`
+	}
+
+	response, body := send(fiber.MIMEApplicationJSON, `{"operations": [
+		{"op": "create", "data": `+valid("Imported")+`},
+		{"op": "update", "id": "`+id+`", "ifMatch": "\"7\"", "data": `+valid("Lead")+`},
+		{"op": "delete", "id": "000000000000000000000001"}
+	]}`)
+	require.Equal(t, http.StatusMultiStatus, response.StatusCode)
+	require.Len(t, body.Data.Data, 3)
+	require.Equal(t, http.StatusCreated, body.Data.Data[0].Status)
+	require.Equal(t, "Imported", body.Data.Data[0].Data["title"])
+	require.Equal(t, body.Data.Data[0].ID, body.Data.Data[0].Data["id"])
+	require.Equal(t, http.StatusPreconditionFailed, body.Data.Data[1].Status)
+	require.Equal(t, http.StatusNotFound, body.Data.Data[2].Status)
+	require.NotEmpty(t, body.Data.Data[2].Error)
+	require.Equal(t, map[string]any{"total": float64(3), "succeeded": float64(1), "failed": float64(2), "atomic": false}, body.Data.Meta)
+
+	response, body = send(fiber.MIMEApplicationJSON, `{"atomic": true, "operations": [
+		{"op": "create", "data": `+valid("Never")+`},
+		{"op": "update", "id": "`+id+`", "ifMatch": "\"7\"", "data": `+valid("Lead")+`}
+	]}`)
+	require.Equal(t, http.StatusMultiStatus, response.StatusCode)
+	require.Equal(t, http.StatusFailedDependency, body.Data.Data[0].Status)
+	require.Equal(t, http.StatusPreconditionFailed, body.Data.Data[1].Status)
+
+	response, body = send(fiber.MIMEApplicationJSON, `{"atomic": true, "operations": [
+		{"op": "update", "id": "`+id+`", "ifMatch": "\"1\"", "data": `+valid("Lead")+`},
+		{"op": "create", "data": `+valid("Second import")+`}
+	]}`)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, http.StatusOK, body.Data.Data[0].Status)
+	require.Equal(t, "Lead", body.Data.Data[0].Data["title"])
+	require.Equal(t, float64(2), body.Data.Data[0].Data["version"])
+
+	all, err := svc.FindAll(context.Background(), services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), all.Total)
+
+	// Malformed operations reject the whole batch.
+	response, body = send(fiber.MIMEApplicationJSON, `{"operations": [
+		{"op": "create", "data": {"title": "", "salary": 1}},
+		{"op": "delete", "id": "nope"},
+		{"op": "upsert"}
+	]}`)
+	require.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	var fields []string
+	for _, fe := range body.Data.Errors {
+		fields = append(fields, fe["field"])
+	}
+	require.Equal(t, []string{"operations[0].data.salary", "operations[1].id"}, fields)
+
+	response, body = send(fiber.MIMEApplicationJSON, `{"operations": [{"op": "upsert"}]}`)
+	require.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	require.Equal(t, "operations[0].op", body.Data.Errors[0]["field"])
+
+	response, _ = send(fiber.MIMEApplicationJSON, `{"operations": []}`)
+	require.Equal(t, http.StatusUnprocessableEntity, response.StatusCode)
+	response, _ = send(fiber.MIMEApplicationJSON, `[`)
+	require.Equal(t, http.StatusBadRequest, response.StatusCode)
+	response, _ = send(fiber.MIMEApplicationForm, `operations=1`)
+	require.Equal(t, http.StatusUnsupportedMediaType, response.StatusCode)
+
+	all, err = svc.FindAll(context.Background(), services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), all.Total)
+}
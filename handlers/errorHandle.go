@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"GO-Project/responses"
+	"GO-Project/services"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ErrorHandler renders errors in the legacy envelope unless the client asks
+// for application/problem+json.
+var ErrorHandler = NewErrorHandler(responses.FormatEnvelope)
+
+// NewErrorHandler returns a fiber.Config.ErrorHandler that turns errors
+// returned by handlers into responses, mapping the services error
+// categories to status codes. defaultFormat is used unless the Accept header
+// prefers the other format. Messages of uncategorised errors are logged and
+// replaced so driver details never reach clients.
+func NewErrorHandler(defaultFormat string) fiber.ErrorHandler {
+	offers := []string{fiber.MIMEApplicationJSON, responses.ProblemContentType}
+	if defaultFormat == responses.FormatProblem {
+		offers[0], offers[1] = offers[1], offers[0]
+	}
+
+	return func(c *fiber.Ctx, err error) error {
+		status, message, fields := classify(err)
+		if status >= http.StatusInternalServerError {
+			slog.Error("request failed",
+				slog.String("method", c.Method()),
+				slog.String("path", c.Path()),
+				slog.Int("status", status),
+				slog.Any("err", err))
+		}
+
+		if c.Accepts(offers...) == responses.ProblemContentType {
+			return writeProblem(c, status, message, fields)
+		}
+
+		data := fiber.Map{"data": message}
+		if len(fields) > 0 {
+			data["errors"] = problemFields(fields)
+		}
+		return c.Status(status).JSON(responses.MessageResponse{
+			Status:  status,
+			Message: "error",
+			Data:    &data,
+		})
+	}
+}
+
+func writeProblem(c *fiber.Ctx, status int, detail string, fields []services.FieldError) error {
+	problem := responses.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: c.OriginalURL(),
+		Errors:   problemFields(fields),
+	}
+	if detail != problem.Title {
+		problem.Detail = detail
+	}
+	return c.Status(status).JSON(problem, responses.ProblemContentType)
+}
+
+func problemFields(fields []services.FieldError) []responses.ProblemField {
+	if len(fields) == 0 {
+		return nil
+	}
+	out := make([]responses.ProblemField, len(fields))
+	for i, f := range fields {
+		out[i] = responses.ProblemField{Field: f.Field, Message: f.Message}
+	}
+	return out
+}
+
+func classify(err error) (int, string, []services.FieldError) {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code, fiberErr.Message, nil
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, services.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, services.ErrValidation):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		// The request timeout expired; backends return it unwrapped.
+		return http.StatusServiceUnavailable, "request timed out", nil
+	case errors.Is(err, services.ErrPreconditionFailed):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, services.ErrAborted):
+		status = http.StatusFailedDependency
+	case errors.Is(err, services.ErrForbidden):
+		status = http.StatusForbidden
+	}
+
+	var serviceErr *services.Error
+	if status != http.StatusInternalServerError && errors.As(err, &serviceErr) {
+		return status, serviceErr.Message, serviceErr.Fields
+	}
+	return status, http.StatusText(status), nil
+}
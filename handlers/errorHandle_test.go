@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"GO-Project/responses"
+	"GO-Project/services"
+	mock_services "GO-Project/services/mocks"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/mock/gomock"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"not found", &services.Error{Kind: services.ErrNotFound, Message: "experience x not found", Err: errors.New("mongo: no documents in result")}, 404, "experience x not found"},
+		{"conflict", &services.Error{Kind: services.ErrConflict, Message: "experience already exists"}, 409, "experience already exists"},
+		{"validation", &services.Error{Kind: services.ErrValidation, Message: "experience is required"}, 422, "experience is required"},
+		{"unavailable", &services.Error{Kind: services.ErrUnavailable, Message: "storage is temporarily unavailable", Err: errors.New("connection refused 10.0.0.1")}, 503, "storage is temporarily unavailable"},
+		{"precondition failed", &services.Error{Kind: services.ErrPreconditionFailed, Message: "experience x is at version 3"}, 412, "experience x is at version 3"},
+		{"aborted", &services.Error{Kind: services.ErrAborted, Message: "not applied"}, 424, "not applied"},
+		{"forbidden", &services.Error{Kind: services.ErrForbidden, Message: "forbidden: cannot grant apikeys:manage"}, 403, "forbidden: cannot grant apikeys:manage"},
+		{"deadline", fmt.Errorf("list experiences: %w", context.DeadlineExceeded), 503, "request timed out"},
+		{"bare sentinel", services.ErrNotFound, 404, "Not Found"},
+		{"internal", errors.New("(Unauthorized) command find requires authentication"), 500, "Internal Server Error"},
+		{"fiber error", fiber.NewError(400, "invalid experience id"), 400, "invalid experience id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+			ctrl := gomock.NewController(t)
+			mockStore := mock_services.NewMockExperienceService(ctrl)
+			NewV1(f, mockStore)
+
+			id := primitive.NewObjectID()
+			mockStore.EXPECT().Delete(gomock.Any(), id, gomock.Any()).Times(1).Return(tt.err)
+
+			response, err := f.Test(httptest.NewRequest(fiber.MethodDelete, "/api/v1/experiences/"+id.Hex(), nil))
+			require.NoError(t, err)
+			defer func() { _ = response.Body.Close() }()
+
+			require.Equal(t, tt.status, response.StatusCode)
+
+			var body responses.MessageResponse
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+			require.Equal(t, tt.status, body.Status)
+			require.Equal(t, "error", body.Message)
+			require.Equal(t, tt.message, (*body.Data)["data"])
+		})
+	}
+}
+
+func TestInvalidExperienceId(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	NewV1(f, mock_services.NewMockExperienceService(ctrl))
+
+	for _, id := range []string{"not-an-id", primitive.NilObjectID.Hex()} {
+		response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/experiences/"+id, nil))
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		require.Equal(t, 400, response.StatusCode)
+	}
+}
+
+func TestErrorHandlerProblemJSON(t *testing.T) {
+	validationErr := &services.Error{
+		Kind:    services.ErrValidation,
+		Message: "request validation failed",
+		Fields:  []services.FieldError{{Field: "experience", Message: "is required"}},
+	}
+
+	tests := []struct {
+		name          string
+		defaultFormat string
+		accept        string
+		problem       bool
+	}{
+		{"envelope by default", responses.FormatEnvelope, "", false},
+		{"problem via Accept", responses.FormatEnvelope, "application/problem+json", true},
+		{"problem by config", responses.FormatProblem, "*/*", true},
+		{"envelope via Accept with problem config", responses.FormatProblem, "application/json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := fiber.New(fiber.Config{ErrorHandler: NewErrorHandler(tt.defaultFormat)})
+			f.Post("/api/experiences", func(c *fiber.Ctx) error { return validationErr })
+
+			req := httptest.NewRequest(fiber.MethodPost, "/api/experiences?x=1", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			response, err := f.Test(req)
+			require.NoError(t, err)
+			defer func() { _ = response.Body.Close() }()
+
+			require.Equal(t, 422, response.StatusCode)
+
+			if !tt.problem {
+				require.Equal(t, fiber.MIMEApplicationJSON, response.Header.Get("Content-Type"))
+				var body responses.MessageResponse
+				require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+				require.Equal(t, "request validation failed", (*body.Data)["data"])
+				require.NotEmpty(t, (*body.Data)["errors"])
+				return
+			}
+
+			require.Equal(t, responses.ProblemContentType, response.Header.Get("Content-Type"))
+			var problem responses.Problem
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&problem))
+			require.Equal(t, responses.Problem{
+				Type:     "about:blank",
+				Title:    "Unprocessable Entity",
+				Status:   422,
+				Detail:   "request validation failed",
+				Instance: "/api/experiences?x=1",
+				Errors:   []responses.ProblemField{{Field: "experience", Message: "is required"}},
+			}, problem)
+		})
+	}
+}
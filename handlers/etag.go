@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"GO-Project/services"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// etag is the strong entity tag of an experience at the given version.
+// Every write bumps the version, so it changes whenever the representation
+// does.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// entityTags parses an If-Match or If-None-Match header into the versions it
+// names. With weak set, W/ tags count too, as If-None-Match's weak comparison
+// requires; If-Match compares strongly and ignores them. Tags this API never
+// issued match no version and are dropped.
+func entityTags(header string, weak bool) (versions []int64, wildcard bool) {
+	versions = []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return nil, true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions, false
+}
+
+// precondition reads the conditional request headers (RFC 9110 section 13)
+// of a write. The service evaluates them atomically with the write.
+func precondition(c *fiber.Ctx) services.Precondition {
+	return parsePrecondition(c.Get(fiber.HeaderIfMatch), c.Get(fiber.HeaderIfNoneMatch))
+}
+
+// parsePrecondition builds a Precondition from the values of If-Match and
+// If-None-Match, either of which may be empty.
+func parsePrecondition(ifMatch, ifNoneMatch string) services.Precondition {
+	var cond services.Precondition
+	if ifMatch != "" {
+		// If-Match: * holds for any existing experience, which the write
+		// requires anyway.
+		if versions, wildcard := entityTags(ifMatch, false); !wildcard {
+			cond.IfMatch = versions
+		}
+	}
+	if ifNoneMatch != "" {
+		cond.IfNoneMatch, cond.IfNoneMatchAny = entityTags(ifNoneMatch, true)
+	}
+	return cond
+}
+
+// notModified reports whether a GET's If-None-Match names the current
+// version, so the client's copy is fresh.
+func notModified(c *fiber.Ctx, version int64) bool {
+	header := c.Get(fiber.HeaderIfNoneMatch)
+	if header == "" {
+		return false
+	}
+	versions, wildcard := entityTags(header, true)
+	if wildcard {
+		return true
+	}
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
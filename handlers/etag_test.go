@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"GO-Project/models"
+	"GO-Project/services"
+	mock_services "GO-Project/services/mocks"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEntityTags(t *testing.T) {
+	tests := []struct {
+		header   string
+		weak     bool
+		versions []int64
+		wildcard bool
+	}{
+		{`"3"`, false, []int64{3}, false},
+		{`"3", W/"4" ,"5"`, false, []int64{3, 5}, false},
+		{`"3", W/"4"`, true, []int64{3, 4}, false},
+		{`*`, false, nil, true},
+		{`"abc", 7, ""`, true, []int64{}, false},
+	}
+	for _, tt := range tests {
+		versions, wildcard := entityTags(tt.header, tt.weak)
+		require.Equal(t, tt.versions, versions, tt.header)
+		require.Equal(t, tt.wildcard, wildcard, tt.header)
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+
+	id := primitive.NewObjectID()
+	url := "/api/v1/experiences/" + id.Hex()
+	stored := &models.Experience{ID: id, Company: "Acme", Title: "Dev", Version: 3}
+	mockStore.EXPECT().FindById(gomock.Any(), id).Times(2).Return(stored, nil)
+
+	response, err := f.Test(httptest.NewRequest(fiber.MethodGet, url, nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, `"3"`, response.Header.Get("ETag"))
+
+	req := httptest.NewRequest(fiber.MethodGet, url, nil)
+	req.Header.Set("If-None-Match", `W/"3"`)
+	response, err = f.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 304, response.StatusCode)
+
+	body := `{"company": "Acme", "title": "Lead", "startDate": "2020-01-01T00:00:00Z"}`
+	mockStore.EXPECT().Update(gomock.Any(), id, gomock.Any(), services.Precondition{IfMatch: []int64{3}}).
+		Return(&models.Experience{ID: id, Version: 4}, nil)
+	req = httptest.NewRequest(fiber.MethodPut, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", `"3"`)
+	response, err = f.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, `"4"`, response.Header.Get("ETag"))
+
+	stale := &services.Error{Kind: services.ErrPreconditionFailed, Message: "experience is at version 4"}
+	mockStore.EXPECT().Delete(gomock.Any(), id, services.Precondition{IfMatch: []int64{3}}).Return(stale)
+	req = httptest.NewRequest(fiber.MethodDelete, url, nil)
+	req.Header.Set("If-Match", `"3"`)
+	response, err = f.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 412, response.StatusCode)
+
+	mockStore.EXPECT().Patch(gomock.Any(), id, gomock.Any(), services.Precondition{IfNoneMatchAny: true}).Return(nil, stale)
+	req = httptest.NewRequest(fiber.MethodPatch, url, strings.NewReader(`{"title": "x"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-None-Match", `*`)
+	req.Header.Set("If-Match", `*`)
+	response, err = f.Test(req)
+	require.NoError(t, err)
+	require.Equal(t, 412, response.StatusCode)
+}
@@ -2,179 +2,267 @@ package handlers
 
 import (
 	"GO-Project/models"
+	"GO-Project/patch"
+	"GO-Project/policy"
 	"GO-Project/responses"
 	"GO-Project/services"
-	"fmt"
-	"log/slog"
+	"GO-Project/validation"
+	"context"
+	"encoding/json"
+	"mime"
 	"net/http"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// routeExperience names the route of a single experience, used to build
+// Location headers.
+const routeExperience = "experiences.get"
+
 type expHandle struct {
 	expService services.ExperienceService
 }
 
-func NewExperienceHandle(app fiber.Router, expService services.ExperienceService) {
+// NewExperienceHandle registers the experience routes on router, relative to
+// the prefix of its API version group. Each route requires the policy action
+// it performs; bulk checks the actions of its operations. Create and bulk
+// are Idempotent.
+func NewExperienceHandle(router fiber.Router, expService services.ExperienceService) {
 	c := expHandle{
 		expService: expService,
 	}
-	app.Post("/api/experiences", c.Create)
-	app.Put("/api/:experienceId", c.Update)
-	app.Get("/api/:experienceId", c.FindById)
-	app.Get("/api/", c.FindAll)
-	app.Delete("/api/:experienceId", c.Delete)
+	read, create := Require(policy.ActionRead), Require(policy.ActionCreate)
+	update, remove := Require(policy.ActionUpdate), Require(policy.ActionDelete)
+
+	router.Post("/experiences", create, Idempotent, c.Create)
+	router.Post("/experiences/bulk", Idempotent, c.Bulk)
+	router.Get("/experiences", read, c.FindAll)
+	router.Get("/experiences/search", read, c.Search)
+	router.Get("/experiences/trash", read, c.Trash)
+	router.Post("/experiences/trash/:experienceId/restore", remove, c.Undelete)
+	router.Delete("/experiences/trash/:experienceId", remove, c.Purge)
+	router.Get("/experiences/:experienceId", read, c.FindById).Name(routeExperience)
+	router.Put("/experiences/:experienceId", update, c.Update)
+	router.Patch("/experiences/:experienceId", update, c.Patch)
+	router.Delete("/experiences/:experienceId", remove, c.Delete)
 }
 
 func (e expHandle) Create(c *fiber.Ctx) error {
 	var exp *models.ExperienceDto
 
 	if err := c.BodyParser(&exp); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(responses.MessageResponse{
-			Status:  http.StatusBadRequest,
-			Message: "error",
-			Data:    &fiber.Map{"data": err.Error()},
-		})
+		return fiber.NewError(http.StatusBadRequest, "invalid request body")
+	}
+
+	if err := services.NewValidationError(validation.Struct(exp)); err != nil {
+		return err
+	}
+
+	created, err := e.expService.Create(c.UserContext(), exp)
+	if err != nil {
+		return err
 	}
 
-	if err := e.expService.Create(c.Context(), exp); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(responses.MessageResponse{
-			Status:  http.StatusInternalServerError,
-			Message: "error",
-			Data:    &fiber.Map{"data": err.Error()},
-		})
+	location, err := c.GetRouteURL(routeExperience, fiber.Map{"experienceId": created.ID.Hex()})
+	if err != nil {
+		return err
 	}
+	c.Location(location)
+	c.Set(fiber.HeaderETag, etag(created.Version))
 
 	return c.Status(http.StatusCreated).JSON(responses.MessageResponse{
 		Status:  http.StatusCreated,
 		Message: "success",
-		Data:    &fiber.Map{"data": exp},
+		Data:    &fiber.Map{"data": models.NewExperienceView(created)},
 	})
 }
 
+// Update replaces the experience with the request body, which must be a
+// complete, valid representation. Read-only members such as id and version
+// may be sent back as received from GET and are ignored; send the ETag in
+// If-Match to guard against overwriting someone else's change.
 func (e expHandle) Update(c *fiber.Ctx) error {
-	experienceId := c.Params("experienceId")
+	objId, err := experienceId(c)
+	if err != nil {
+		return err
+	}
 
-	var exp *models.ExperienceDto
+	if mediaType(c) != fiber.MIMEApplicationJSON {
+		return fiber.NewError(http.StatusUnsupportedMediaType, "PUT requires application/json")
+	}
+	if !json.Valid(c.Body()) {
+		return fiber.NewError(http.StatusBadRequest, "invalid request body")
+	}
+	exp, err := services.DecodeExperience(c.Body())
+	if err != nil {
+		return err
+	}
 
-	objId, err := primitive.ObjectIDFromHex(experienceId)
+	updated, err := e.expService.Update(c.UserContext(), objId, exp, precondition(c))
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(responses.MessageResponse{
-			Status:  http.StatusBadRequest,
-			Message: "error",
-			Data:    &fiber.Map{"data": err.Error()},
-		})
+		return err
 	}
+	c.Set(fiber.HeaderETag, etag(updated.Version))
 
-	if err := c.BodyParser(&exp); err != nil {
-		return c.Status(http.StatusBadRequest).JSON(responses.MessageResponse{
-			Status:  http.StatusBadRequest,
-			Message: "error",
-			Data:    &fiber.Map{"data": err.Error()},
-		})
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    &fiber.Map{"data": models.NewExperienceView(updated)},
+	})
+}
+
+// acceptPatch lists the PATCH formats, advertised with the Accept-Patch
+// header (RFC 5789).
+var acceptPatch = patch.MergePatchType + ", " + patch.JSONPatchType
+
+// Patch applies a JSON Merge Patch or JSON Patch, chosen by the request
+// Content-Type, to the experience.
+func (e expHandle) Patch(c *fiber.Ctx) error {
+	objId, err := experienceId(c)
+	if err != nil {
+		return err
+	}
+
+	var p patch.Patch
+	switch mediaType(c) {
+	case patch.MergePatchType:
+		p, err = patch.ParseMerge(c.Body())
+	case patch.JSONPatchType:
+		p, err = patch.ParseJSONPatch(c.Body())
+	default:
+		c.Set("Accept-Patch", acceptPatch)
+		return fiber.NewError(http.StatusUnsupportedMediaType, "PATCH requires "+patch.MergePatchType+" or "+patch.JSONPatchType)
+	}
+	if err != nil {
+		return fiber.NewError(http.StatusBadRequest, err.Error())
 	}
 
-	if err := e.expService.Update(objId, exp); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(responses.MessageResponse{
-			Status:  http.StatusInternalServerError,
-			Message: "error",
-			Data:    &fiber.Map{"data": err.Error()},
-		})
+	updated, err := e.expService.Patch(c.UserContext(), objId, p, precondition(c))
+	if err != nil {
+		return err
 	}
+	c.Set(fiber.HeaderETag, etag(updated.Version))
 
 	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
 		Status:  http.StatusOK,
 		Message: "success",
-		Data:    &fiber.Map{"data": exp},
+		Data:    &fiber.Map{"data": models.NewExperienceView(updated)},
 	})
 }
 
+// FindById returns the experience with its version as ETag. A GET whose
+// If-None-Match names the current version gets 304 Not Modified.
 func (e expHandle) FindById(c *fiber.Ctx) error {
-	experienceId := c.Params("experienceId")
-
-	var exp *models.ExperienceDto
+	objID, err := experienceId(c)
+	if err != nil {
+		return err
+	}
 
-	objID, err := primitive.ObjectIDFromHex(experienceId)
-	if err != nil {
-		fmt.Println("0")
-		slog.Error("error log id", slog.String("id",experienceId), slog.Any("err", err))
-		return c.Status(http.StatusBadRequest).JSON(responses.MessageResponse{
-			Status:  http.StatusBadRequest,
-			Message: "error",
-			Data:    &fiber.Map{"data": "Not found"},
-		})
-	}
-	
-	if objID.IsZero(){
-		slog.Error("error log id", slog.String("id",experienceId), slog.Any("err", err))
-		return c.Status(http.StatusBadRequest).JSON(responses.MessageResponse{
-			Status:  http.StatusBadRequest,
-			Message: "error",
-			Data:    &fiber.Map{"data": "Not found"},
-		})
-	}
-
-	exp, err = e.expService.FindById(objID)
-	if err != nil {
-		fmt.Println("1")
-		if err == mongo.ErrNoDocuments {
-			return c.Status(fiber.StatusNotFound).JSON(responses.MessageResponse{
-				Status:  http.StatusInternalServerError,
-				Message: "error",
-				Data:    &fiber.Map{"data": "Experience not found"},
-			})
-		}
-		return c.Status(http.StatusInternalServerError).JSON(responses.MessageResponse{
-			Status:  http.StatusInternalServerError,
-			Message: "error",
-			Data:    &fiber.Map{"data": err.Error()},
-		})
+	exp, err := e.expService.FindById(c.UserContext(), objID)
+	if err != nil {
+		return err
+	}
+	c.Set("Accept-Patch", acceptPatch)
+	c.Set(fiber.HeaderETag, etag(exp.Version))
+	if notModified(c, exp.Version) {
+		return c.SendStatus(http.StatusNotModified)
 	}
 
 	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
 		Status:  http.StatusOK,
 		Message: "success",
-		Data:    &fiber.Map{"data": exp},
+		Data:    &fiber.Map{"data": models.NewExperienceView(exp)},
 	})
 }
 
 func (e expHandle) FindAll(c *fiber.Ctx) error {
-	exp, err := e.expService.FindAll()
+	return e.list(c, e.expService.FindAll)
+}
+
+// Trash lists the deleted experiences with the query parameters of FindAll.
+func (e expHandle) Trash(c *fiber.Ctx) error {
+	return e.list(c, e.expService.Trash)
+}
+
+func (e expHandle) list(c *fiber.Ctx, find func(ctx context.Context, query services.ListQuery) (*services.ListResult, error)) error {
+	query, err := parseListQuery(c)
 	if err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(responses.MessageResponse{
-			Status:  http.StatusInternalServerError,
-			Message: "error",
-			Data:    &fiber.Map{"data": err.Error()},
-		})
+		return err
+	}
+
+	result, err := find(c.UserContext(), query)
+	if err != nil {
+		return err
+	}
+
+	meta := fiber.Map{"total": result.Total, "limit": result.Limit}
+	if query.Cursor == "" {
+		meta["page"] = result.Page
+	}
+	if result.NextCursor != "" {
+		meta["nextCursor"] = result.NextCursor
+	}
+	if result.PrevCursor != "" {
+		meta["prevCursor"] = result.PrevCursor
 	}
 
 	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
 		Status:  http.StatusOK,
 		Message: "success",
-		Data:    &fiber.Map{"data": exp},
+		Data: &fiber.Map{
+			"data":  models.NewExperienceViews(result.Items),
+			"meta":  meta,
+			"links": listLinks(c, query, result),
+		},
 	})
 }
 
-func (e expHandle) Delete(c *fiber.Ctx) error {
-	experienceId := c.Params("experienceId")
+// searchHitView is an experience found by Search with its relevance.
+type searchHitView struct {
+	models.ExperienceView
+	Score      float64              `json:"score"`
+	Highlights []services.Highlight `json:"highlights"`
+}
+
+func (e expHandle) Search(c *fiber.Ctx) error {
+	query, err := parseSearchQuery(c)
+	if err != nil {
+		return err
+	}
 
-	objId, err := primitive.ObjectIDFromHex(experienceId)
+	result, err := e.expService.Search(c.UserContext(), query)
 	if err != nil {
-		return c.Status(http.StatusBadRequest).JSON(responses.MessageResponse{
-			Status:  http.StatusBadRequest,
-			Message: "error",
-			Data:    &fiber.Map{"data": err.Error()},
-		})
+		return err
 	}
 
-	if err := e.expService.Delete(objId); err != nil {
-		return c.Status(http.StatusInternalServerError).JSON(responses.MessageResponse{
-			Status:  http.StatusInternalServerError,
-			Message: "error",
-			Data:    &fiber.Map{"data": err.Error()},
-		})
+	hits := make([]searchHitView, len(result.Hits))
+	for i, hit := range result.Hits {
+		hits[i] = searchHitView{
+			ExperienceView: models.NewExperienceView(hit.Experience),
+			Score:          hit.Score,
+			Highlights:     hit.Highlights,
+		}
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data: &fiber.Map{
+			"data": hits,
+			"meta": fiber.Map{"total": result.Total, "limit": result.Limit},
+		},
+	})
+}
+
+func (e expHandle) Delete(c *fiber.Ctx) error {
+	objId, err := experienceId(c)
+	if err != nil {
+		return err
+	}
+
+	if err := e.expService.Delete(c.UserContext(), objId, precondition(c)); err != nil {
+		return err
 	}
 
 	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
@@ -183,3 +271,57 @@ func (e expHandle) Delete(c *fiber.Ctx) error {
 		Data:    &fiber.Map{"data": "Experience successfully deleted!"},
 	})
 }
+
+// Undelete takes an experience out of the trash.
+func (e expHandle) Undelete(c *fiber.Ctx) error {
+	objId, err := experienceId(c)
+	if err != nil {
+		return err
+	}
+
+	restored, err := e.expService.Undelete(c.UserContext(), objId, precondition(c))
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderETag, etag(restored.Version))
+
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    &fiber.Map{"data": models.NewExperienceView(restored)},
+	})
+}
+
+// Purge permanently removes an experience from the trash, with its history.
+func (e expHandle) Purge(c *fiber.Ctx) error {
+	objId, err := experienceId(c)
+	if err != nil {
+		return err
+	}
+
+	if err := e.expService.Purge(c.UserContext(), objId, precondition(c)); err != nil {
+		return err
+	}
+
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// mediaType returns the request Content-Type without parameters, in lower
+// case.
+func mediaType(c *fiber.Ctx) string {
+	mt, _, err := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// experienceId parses the :experienceId route parameter. Malformed and zero
+// ids are rejected before reaching the service.
+func experienceId(c *fiber.Ctx) (primitive.ObjectID, error) {
+	objID, err := primitive.ObjectIDFromHex(c.Params("experienceId"))
+	if err != nil || objID.IsZero() {
+		return primitive.NilObjectID, fiber.NewError(http.StatusBadRequest, "invalid experience id")
+	}
+	return objID, nil
+}
@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"GO-Project/models"
+	"GO-Project/policy"
+	"GO-Project/services"
 	mock_services "GO-Project/services/mocks"
 	"bytes"
+	"context"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/require"
@@ -39,18 +45,22 @@ func TestCreate(t *testing.T) {
 
 	mockStore := mock_services.NewMockExperienceService(ctrl)
 
-	NewExperienceHandle(f, mockStore)
-
-	mockStore.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1).Return(nil)
+	NewV1(f, mockStore)
 
 	payload := &models.ExperienceDto{
-		Experience: "Hello",
+		Company:   "Acme",
+		Title:     "Hello",
+		StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
 	}
 
+	created := &models.Experience{ID: primitive.NewObjectID(), Version: 1, CreatedAt: time.Now()}
+	payload.ApplyTo(created)
+	mockStore.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1).Return(created, nil)
+
 	data, err := json.Marshal(payload)
 	require.NoError(t, err)
 
-	req := httptest.NewRequest(fiber.MethodPost, "/api/experiences", bytes.NewReader(data))
+	req := httptest.NewRequest(fiber.MethodPost, "/api/v1/experiences", bytes.NewReader(data))
 	req.Header.Set("Content-Type", "application/json")
 
 	response, err := f.Test(req)
@@ -58,6 +68,18 @@ func TestCreate(t *testing.T) {
 	defer func() { _ = response.Body.Close() }()
 
 	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "/api/v1/experiences/"+created.ID.Hex(), response.Header.Get("Location"))
+
+	var body struct {
+		Data struct {
+			Data models.ExperienceView `json:"data"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	require.Equal(t, created.ID.Hex(), body.Data.Data.ID)
+	require.Equal(t, int64(1), body.Data.Data.Version)
+	require.Equal(t, "Hello", body.Data.Data.Title)
+	require.Nil(t, body.Data.Data.UpdatedAt)
 }
 
 func TestUpdate(t *testing.T) {
@@ -70,19 +92,23 @@ func TestUpdate(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStore := mock_services.NewMockExperienceService(ctrl)
-	NewExperienceHandle(f, mockStore)
+	NewV1(f, mockStore)
 
 	payload := &models.ExperienceDto{
-		Experience: "Helloooooo",
+		Company:   "Acme",
+		Title:     "Helloooooo",
+		StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
 	}
 
 	data, err := json.Marshal(payload)
 	require.NoError(t, err)
 
 	mockId := primitive.NewObjectID()
-	mockStore.EXPECT().Update(mockId, payload).Times(1).Return(nil)
+	updated := &models.Experience{ID: mockId, Version: 2, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	payload.ApplyTo(updated)
+	mockStore.EXPECT().Update(gomock.Any(), mockId, payload, services.Precondition{}).Times(1).Return(updated, nil)
 
-	url := "/api/" + mockId.Hex()
+	url := "/api/v1/experiences/" + mockId.Hex()
 	req := httptest.NewRequest(fiber.MethodPut, url, bytes.NewReader(data))
 	req.Header.Set("Content-Type", "application/json")
 
@@ -104,19 +130,20 @@ func TestFindById(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStore := mock_services.NewMockExperienceService(ctrl)
-	NewExperienceHandle(f, mockStore)
+	NewV1(f, mockStore)
 
-	payload := &models.ExperienceDto{
-		Experience: "Hellooo",
+	mockId := primitive.NewObjectID()
+	payload := &models.Experience{
+		ID:    mockId,
+		Title: "Hellooo",
 	}
 
 	data, err := json.Marshal(payload)
 	require.NoError(t, err)
 
-	mockId := primitive.NewObjectID()
-	mockStore.EXPECT().FindById(mockId).Times(1).Return(payload, err)
+	mockStore.EXPECT().FindById(gomock.Any(), mockId).Times(1).Return(payload, err)
 
-	url := "/api/" + mockId.Hex()
+	url := "/api/v1/experiences/" + mockId.Hex()
 	req := httptest.NewRequest(fiber.MethodGet, url, bytes.NewReader(data))
 	req.Header.Set("Content-Type", "application/json")
 
@@ -137,18 +164,19 @@ func TestFindAll(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStore := mock_services.NewMockExperienceService(ctrl)
-	NewExperienceHandle(f, mockStore)
+	NewV1(f, mockStore)
 
-	payload := []*models.ExperienceDto{
-		{Experience: "Hello"},
+	payload := []*models.Experience{
+		{ID: primitive.NewObjectID(), Title: "Hello"},
 	}
 
 	data, err := json.Marshal(payload)
 	require.NoError(t, err)
 
-	mockStore.EXPECT().FindAll().Times(1).Return(payload, err)
+	result := &services.ListResult{Items: payload, Total: 1, Page: 1, Limit: services.DefaultLimit}
+	mockStore.EXPECT().FindAll(gomock.Any(), services.ListQuery{}).Times(1).Return(result, err)
 
-	req := httptest.NewRequest(fiber.MethodGet, "/api/", bytes.NewReader(data))
+	req := httptest.NewRequest(fiber.MethodGet, "/api/v1/experiences", bytes.NewReader(data))
 	req.Header.Set("Content-Type", "application/json")
 
 	response, err := f.Test(req)
@@ -159,6 +187,191 @@ func TestFindAll(t *testing.T) {
 
 }
 
+func TestFindAllQuery(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+
+	remote := true
+	startFrom := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	startTo := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)
+	want := services.ListQuery{
+		Page:      2,
+		Limit:     5,
+		Sort:      "title",
+		Desc:      true,
+		Text:      "go dev",
+		Tags:      []string{"go", "mongodb"},
+		Remote:    &remote,
+		StartFrom: &startFrom,
+		StartTo:   &startTo,
+	}
+	result := &services.ListResult{
+		Items:      []*models.Experience{{ID: primitive.NewObjectID(), Title: "Go developer"}},
+		Total:      11,
+		Page:       2,
+		Limit:      5,
+		NextCursor: "next",
+		PrevCursor: "prev",
+	}
+	mockStore.EXPECT().FindAll(gomock.Any(), want).Times(1).Return(result, nil)
+
+	req := httptest.NewRequest(fiber.MethodGet,
+		"/api/v1/experiences?page=2&limit=5&sort=title&order=desc&q=go+dev&tags=go,mongodb&remote=true&startFrom=2020-01-01&startTo=2020-12-31", nil)
+	response, err := f.Test(req)
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	require.Equal(t, 200, response.StatusCode)
+
+	var body struct {
+		Data struct {
+			Data  []models.ExperienceView `json:"data"`
+			Meta  map[string]any          `json:"meta"`
+			Links map[string]string       `json:"links"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	require.Len(t, body.Data.Data, 1)
+	require.Equal(t, float64(11), body.Data.Meta["total"])
+	require.Equal(t, float64(2), body.Data.Meta["page"])
+	require.Equal(t, "next", body.Data.Meta["nextCursor"])
+	require.Contains(t, body.Data.Links["next"], "page=3")
+	require.Contains(t, body.Data.Links["next"], "tags=go%2Cmongodb")
+	require.Contains(t, body.Data.Links["prev"], "page=1")
+	require.Contains(t, body.Data.Links["self"], "page=2")
+}
+
+func TestFindAllCursorLinks(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+
+	result := &services.ListResult{Limit: 20, PrevCursor: "back"}
+	result.Items = []*models.Experience{{ID: primitive.NewObjectID()}}
+	mockStore.EXPECT().FindAll(gomock.Any(), services.ListQuery{Cursor: "abc"}).Times(1).Return(result, nil)
+
+	response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/experiences?cursor=abc", nil))
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	require.Equal(t, 200, response.StatusCode)
+
+	var body struct {
+		Data struct {
+			Meta  map[string]any    `json:"meta"`
+			Links map[string]string `json:"links"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	require.NotContains(t, body.Data.Meta, "page")
+	require.Equal(t, "/api/v1/experiences?cursor=back", body.Data.Links["prev"])
+	require.NotContains(t, body.Data.Links, "next")
+}
+
+func TestFindAllInvalidQuery(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+
+	for _, query := range []string{
+		"page=0",
+		"page=x",
+		"page=1000001",
+		"page=922337203685477581",
+		"limit=101",
+		"sort=summary",
+		"order=up",
+		"remote=maybe",
+		"startFrom=yesterday",
+	} {
+		response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/experiences?"+query, nil))
+		require.NoError(t, err)
+		_ = response.Body.Close()
+
+		require.Equal(t, 422, response.StatusCode, query)
+	}
+}
+
+func TestFindAllHugePage(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	svc := services.NewMemoryExperienceService()
+	NewV1(f, svc)
+	_, err := svc.Create(context.Background(), &models.ExperienceDto{Company: "Acme", Title: "Dev", StartDate: time.Now()})
+	require.NoError(t, err)
+
+	// Pages far past the end are empty, and the ones whose offset would
+	// overflow are rejected rather than crashing the memory backend.
+	for query, status := range map[string]int{
+		"page=1000000&limit=100":   200,
+		"page=922337203685477581":  422,
+		"page=9223372036854775807": 422,
+	} {
+		response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/experiences?"+query, nil))
+		require.NoError(t, err)
+		_ = response.Body.Close()
+		require.Equal(t, status, response.StatusCode, query)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+
+	result := &services.SearchResult{
+		Hits: []services.SearchHit{{
+			Experience: &models.Experience{ID: primitive.NewObjectID(), Title: "Go developer"},
+			Score:      5,
+			Highlights: []services.Highlight{{Field: "title", Snippet: "<mark>Go</mark> developer"}},
+		}},
+		Total: 1,
+		Limit: 10,
+	}
+	want := services.SearchQuery{Text: "go dev", Limit: 10, Prefix: false, Fuzzy: true}
+	mockStore.EXPECT().Search(gomock.Any(), want).Times(1).Return(result, nil)
+
+	response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/experiences/search?q=go+dev&limit=10&prefix=false&fuzzy=true", nil))
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	require.Equal(t, 200, response.StatusCode)
+
+	var body struct {
+		Data struct {
+			Data []searchHitView `json:"data"`
+			Meta map[string]any  `json:"meta"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	require.Len(t, body.Data.Data, 1)
+	require.Equal(t, "Go developer", body.Data.Data[0].Title)
+	require.Equal(t, float64(5), body.Data.Data[0].Score)
+	require.Equal(t, "<mark>Go</mark> developer", body.Data.Data[0].Highlights[0].Snippet)
+	require.Equal(t, float64(1), body.Data.Meta["total"])
+}
+
+func TestSearchInvalidQuery(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+
+	for _, query := range []string{"", "q=", "q=go&limit=0", "q=go&fuzzy=perhaps"} {
+		response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/experiences/search?"+query, nil))
+		require.NoError(t, err)
+		_ = response.Body.Close()
+
+		require.Equal(t, 422, response.StatusCode, query)
+	}
+}
+
 func TestDelete(t *testing.T) {
 	f := fiber.New()
 	defer func() {
@@ -169,19 +382,19 @@ func TestDelete(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockStore := mock_services.NewMockExperienceService(ctrl)
-	NewExperienceHandle(f, mockStore)
+	NewV1(f, mockStore)
 
 	payload := &models.ExperienceDto{
-		Experience: "Hellooo",
+		Title: "Hellooo",
 	}
 
 	data, err := json.Marshal(payload)
 	require.NoError(t, err)
 
 	mockId := primitive.NewObjectID()
-	mockStore.EXPECT().Delete(mockId).Times(1).Return(err)
+	mockStore.EXPECT().Delete(gomock.Any(), mockId, services.Precondition{}).Times(1).Return(err)
 
-	url := "/api/" + mockId.Hex()
+	url := "/api/v1/experiences/" + mockId.Hex()
 	req := httptest.NewRequest(fiber.MethodDelete, url, bytes.NewReader(data))
 	req.Header.Set("Content-Type", "application/json")
 
@@ -191,3 +404,222 @@ func TestDelete(t *testing.T) {
 
 	require.Equal(t, 200, response.StatusCode)
 }
+
+func TestCreateValidation(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+
+	for _, body := range []string{
+		`null`,
+		`{}`,
+		`{"company": "Acme", "title": "   ", "startDate": "2020-01-01T00:00:00Z"}`,
+		`{"company": "Acme", "title": "bad\u0000byte", "startDate": "2020-01-01T00:00:00Z"}`,
+		`{"company": "Acme", "title": "Dev", "startDate": "2020-01-01T00:00:00Z", "endDate": "2019-01-01T00:00:00Z"}`,
+		`{"company": "Acme", "title": "Dev", "startDate": "2020-01-01T00:00:00Z", "employmentType": "gig"}`,
+	} {
+		req := httptest.NewRequest(fiber.MethodPost, "/api/v1/experiences", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		response, err := f.Test(req)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+
+		require.Equal(t, 422, response.StatusCode, body)
+	}
+}
+
+func TestUpdateStrict(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+
+	url := "/api/v1/experiences/" + primitive.NewObjectID().Hex()
+	for _, tc := range []struct {
+		contentType, body string
+		status            int
+	}{
+		{"application/json", `{"company": "Acme", "title": "Dev", "startDate": "2020-01-01T00:00:00Z", "salary": 1}`, 422},
+		{"application/json", `{"company": "Acme", "title": 7, "startDate": "2020-01-01T00:00:00Z"}`, 422},
+		{"application/json", `{"company": "Acme"}`, 422},
+		{"application/json", `{"company": `, 400},
+		{"application/x-www-form-urlencoded", `company=Acme`, 415},
+	} {
+		req := httptest.NewRequest(fiber.MethodPut, url, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+
+		response, err := f.Test(req)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+
+		require.Equal(t, tc.status, response.StatusCode, tc.body)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+
+	mockId := primitive.NewObjectID()
+	patched := &models.Experience{ID: mockId, Company: "Acme", Title: "Lead", Version: 3}
+	mockStore.EXPECT().Patch(gomock.Any(), mockId, gomock.Any(), services.Precondition{}).Times(2).Return(patched, nil)
+
+	url := "/api/v1/experiences/" + mockId.Hex()
+	for _, tc := range []struct {
+		contentType, body string
+		status            int
+	}{
+		{"application/merge-patch+json", `{"title": "Lead"}`, 200},
+		{"application/json-patch+json; charset=utf-8", `[{"op": "replace", "path": "/title", "value": "Lead"}]`, 200},
+		{"application/json-patch+json", `[{"op": "rename", "path": "/title"}]`, 400},
+		{"application/merge-patch+json", `{"title": `, 400},
+		{"application/json", `{"title": "Lead"}`, 415},
+	} {
+		req := httptest.NewRequest(fiber.MethodPatch, url, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+
+		response, err := f.Test(req)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+
+		require.Equal(t, tc.status, response.StatusCode, tc.body)
+		if tc.status == 415 {
+			require.Equal(t, "application/merge-patch+json, application/json-patch+json", response.Header.Get("Accept-Patch"))
+		}
+	}
+}
+
+func TestTrash(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	svc := services.NewMemoryExperienceService()
+	NewV1(f, svc)
+
+	created, err := svc.Create(context.Background(), &models.ExperienceDto{Company: "Acme", Title: "Dev", StartDate: time.Now()})
+	require.NoError(t, err)
+	url := "/api/v1/experiences/" + created.ID.Hex()
+	trashURL := "/api/v1/experiences/trash/" + created.ID.Hex()
+
+	send := func(method, url string) *http.Response {
+		response, err := f.Test(httptest.NewRequest(method, url, nil))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = response.Body.Close() })
+		return response
+	}
+
+	require.Equal(t, 200, send(fiber.MethodDelete, url).StatusCode)
+	require.Equal(t, 404, send(fiber.MethodGet, url).StatusCode)
+
+	response := send(fiber.MethodGet, "/api/v1/experiences/trash")
+	require.Equal(t, 200, response.StatusCode)
+	var body struct {
+		Data struct {
+			Data []map[string]any
+			Meta map[string]any
+		}
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+	require.Len(t, body.Data.Data, 1)
+	require.Contains(t, body.Data.Data[0], "deletedAt")
+	require.Equal(t, float64(1), body.Data.Meta["total"])
+
+	response = send(fiber.MethodPost, trashURL+"/restore")
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, `"3"`, response.Header.Get("ETag"))
+	require.Equal(t, 200, send(fiber.MethodGet, url).StatusCode)
+
+	require.Equal(t, 404, send(fiber.MethodDelete, trashURL).StatusCode)
+	require.Equal(t, 200, send(fiber.MethodDelete, url).StatusCode)
+	require.Equal(t, 204, send(fiber.MethodDelete, trashURL).StatusCode)
+	require.Equal(t, 404, send(fiber.MethodPost, trashURL+"/restore").StatusCode)
+}
+
+func TestOwnership(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	f.Use(Authenticate(staticAuthenticator{
+		"Bearer alice": {Subject: "alice"},
+		"Bearer bob":   {Subject: "bob"},
+		"Bearer admin": {Subject: "root", Roles: []string{policy.RoleAdmin}},
+	}))
+	f.Use(Enforce(policy.Default()))
+	NewV1(f, services.NewMemoryExperienceService())
+
+	send := func(token, method, url, body string) *http.Response {
+		request := httptest.NewRequest(method, url, strings.NewReader(body))
+		request.Header.Set(fiber.HeaderAuthorization, token)
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		response, err := f.Test(request)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = response.Body.Close() })
+		return response
+	}
+
+	response := send("Bearer alice", fiber.MethodPost, "/api/v1/experiences", `{"company": "Acme", "title": "Dev", "startDate": "2020-01-01T00:00:00Z"}`)
+	require.Equal(t, 201, response.StatusCode)
+	var created struct {
+		Data struct{ Data models.Experience }
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&created))
+	require.Equal(t, "alice", created.Data.Data.OwnerID)
+	url := "/api/v1/experiences/" + created.Data.Data.ID.Hex()
+
+	require.Equal(t, 200, send("Bearer alice", fiber.MethodGet, url, "").StatusCode)
+	require.Equal(t, 404, send("Bearer bob", fiber.MethodGet, url, "").StatusCode)
+	require.Equal(t, 404, send("Bearer bob", fiber.MethodPut, url, `{"company": "Acme", "title": "Mine", "startDate": "2020-01-01T00:00:00Z"}`).StatusCode)
+	require.Equal(t, 404, send("Bearer bob", fiber.MethodDelete, url, "").StatusCode)
+	require.Equal(t, 200, send("Bearer admin", fiber.MethodPut, url, `{"company": "Acme", "title": "Lead", "startDate": "2020-01-01T00:00:00Z"}`).StatusCode)
+
+	var list struct {
+		Data struct{ Meta map[string]any }
+	}
+	require.NoError(t, json.NewDecoder(send("Bearer bob", fiber.MethodGet, "/api/v1/experiences", "").Body).Decode(&list))
+	require.Equal(t, float64(0), list.Data.Meta["total"])
+}
+
+func TestUpdateAsReturned(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	f.Use(Authenticate(staticAuthenticator{"Bearer alice": {Subject: "alice"}}))
+	f.Use(Enforce(policy.Default()))
+	NewV1(f, services.NewMemoryExperienceService())
+
+	send := func(method, url, body string) *http.Response {
+		request := httptest.NewRequest(method, url, strings.NewReader(body))
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer alice")
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		response, err := f.Test(request)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = response.Body.Close() })
+		return response
+	}
+	read := func(response *http.Response) json.RawMessage {
+		var body struct {
+			Data struct{ Data json.RawMessage }
+		}
+		require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+		return body.Data.Data
+	}
+
+	response := send(fiber.MethodPost, "/api/v1/experiences", `{"company": "Acme", "title": "Dev", "startDate": "2020-01-01T00:00:00Z"}`)
+	require.Equal(t, 201, response.StatusCode)
+	var created struct {
+		ID      string
+		OwnerID string
+	}
+	require.NoError(t, json.Unmarshal(read(response), &created))
+	require.Equal(t, "alice", created.OwnerID)
+	url := "/api/v1/experiences/" + created.ID
+
+	// What GET returns, ownerId included, can be sent back as is.
+	response = send(fiber.MethodGet, url, "")
+	require.Equal(t, 200, response.StatusCode)
+	got := read(response)
+	require.Contains(t, string(got), `"ownerId":"alice"`)
+	require.Equal(t, 200, send(fiber.MethodPut, url, string(got)).StatusCode)
+	require.Equal(t, 200, send(fiber.MethodPost, "/api/v1/experiences/bulk", `{"operations": [{"op": "update", "id": "`+created.ID+`", "data": `+string(got)+`}]}`).StatusCode)
+}
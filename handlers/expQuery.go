@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"GO-Project/services"
+	"GO-Project/validation"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseListQuery reads the list parameters from the query string. Every
+// malformed parameter is reported, not just the first.
+//
+//	page, limit                     page number (1-1000000) and size (1-100)
+//	cursor                          nextCursor or prevCursor of a previous page
+//	sort, order                     one of services.SortFields; asc or desc
+//	q                               text the experience must contain
+//	tags                            comma separated skills, all required
+//	employmentType, remote          exact matches
+//	startFrom, startTo              inclusive range on startDate
+//	createdFrom, createdTo          inclusive range on createdAt
+func parseListQuery(c *fiber.Ctx) (services.ListQuery, error) {
+	var (
+		q    services.ListQuery
+		errs validation.Errors
+	)
+	fail := func(field, rule, message string) {
+		errs = append(errs, validation.FieldError{Field: field, Rule: rule, Message: message})
+	}
+
+	q.Page = queryInt(c, "page", 1, services.MaxPage, fail)
+	q.Limit = queryInt(c, "limit", 1, services.MaxLimit, fail)
+	q.Cursor = c.Query("cursor")
+
+	q.Sort = c.Query("sort")
+	if _, ok := services.SortFields[q.Sort]; q.Sort != "" && !ok {
+		fail("sort", "oneof", "must be one of "+strings.Join(services.SortFieldNames(), ", "))
+	}
+	switch c.Query("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		fail("order", "oneof", "must be one of asc, desc")
+	}
+
+	q.Text = strings.TrimSpace(c.Query("q"))
+	if len([]rune(q.Text)) > 200 {
+		fail("q", "max", "must be at most 200 characters")
+	}
+	for _, tag := range strings.Split(c.Query("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			q.Tags = append(q.Tags, tag)
+		}
+	}
+	q.EmploymentType = c.Query("employmentType")
+	if c.Query("remote") != "" {
+		remote := queryBool(c, "remote", false, fail)
+		q.Remote = &remote
+	}
+
+	q.StartFrom = queryTime(c, "startFrom", false, fail)
+	q.StartTo = queryTime(c, "startTo", true, fail)
+	q.CreatedFrom = queryTime(c, "createdFrom", false, fail)
+	q.CreatedTo = queryTime(c, "createdTo", true, fail)
+
+	return q, services.NewValidationError(errs)
+}
+
+// parseSearchQuery reads the search parameters: q, limit, and the prefix
+// (default true) and fuzzy (default false) switches.
+func parseSearchQuery(c *fiber.Ctx) (services.SearchQuery, error) {
+	var (
+		q    = services.SearchQuery{Prefix: true}
+		errs validation.Errors
+	)
+	fail := func(field, rule, message string) {
+		errs = append(errs, validation.FieldError{Field: field, Rule: rule, Message: message})
+	}
+
+	q.Text = strings.TrimSpace(c.Query("q"))
+	switch {
+	case q.Text == "":
+		fail("q", "required", "is required")
+	case len([]rune(q.Text)) > 200:
+		fail("q", "max", "must be at most 200 characters")
+	}
+	q.Limit = queryInt(c, "limit", 1, services.MaxLimit, fail)
+	q.Prefix = queryBool(c, "prefix", q.Prefix, fail)
+	q.Fuzzy = queryBool(c, "fuzzy", q.Fuzzy, fail)
+
+	return q, services.NewValidationError(errs)
+}
+
+// queryBool parses an optional boolean parameter.
+func queryBool(c *fiber.Ctx, key string, def bool, fail func(field, rule, message string)) bool {
+	raw := c.Query(key)
+	if raw == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		fail(key, "type", "must be true or false")
+		return def
+	}
+	return b
+}
+
+// queryInt parses an optional integer parameter within [lo, hi]; hi of zero
+// means unbounded. Absent parameters are returned as zero, leaving the
+// default to the service.
+func queryInt(c *fiber.Ctx, key string, lo, hi int, fail func(field, rule, message string)) int {
+	raw := c.Query(key)
+	if raw == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(raw)
+	switch {
+	case err != nil:
+		fail(key, "type", "must be an integer")
+	case n < lo:
+		fail(key, "min", "must be at least "+strconv.Itoa(lo))
+	case hi > 0 && n > hi:
+		fail(key, "max", "must be at most "+strconv.Itoa(hi))
+	default:
+		return n
+	}
+	return 0
+}
+
+// queryTime parses an optional RFC 3339 timestamp or YYYY-MM-DD date. A date
+// given as an upper bound includes the whole day.
+func queryTime(c *fiber.Ctx, key string, upper bool, fail func(field, rule, message string)) *time.Time {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &t
+	}
+	t, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		fail(key, "type", "must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+		return nil
+	}
+	if upper {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t
+}
+
+// listLinks builds the self, next and prev links of a page. They keep the
+// filters of the current request and continue in the same mode, by page
+// number or by cursor.
+func listLinks(c *fiber.Ctx, q services.ListQuery, result *services.ListResult) fiber.Map {
+	link := func(key, value string) string {
+		args, _ := url.ParseQuery(string(c.Request().URI().QueryString()))
+		if key != "" {
+			args.Set(key, value)
+		}
+		if len(args) == 0 {
+			return c.Path()
+		}
+		return c.Path() + "?" + args.Encode()
+	}
+
+	links := fiber.Map{"self": link("", "")}
+	if q.Cursor != "" {
+		if result.NextCursor != "" {
+			links["next"] = link("cursor", result.NextCursor)
+		}
+		if result.PrevCursor != "" {
+			links["prev"] = link("cursor", result.PrevCursor)
+		}
+		return links
+	}
+
+	if result.NextCursor != "" {
+		links["next"] = link("page", strconv.Itoa(result.Page+1))
+	}
+	if result.Page > 1 {
+		links["prev"] = link("page", strconv.Itoa(result.Page-1))
+	}
+	return links
+}
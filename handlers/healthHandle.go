@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"GO-Project/health"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type healthHandle struct {
+	registry *health.Registry
+}
+
+func NewHealthHandle(app fiber.Router, registry *health.Registry) {
+	h := healthHandle{
+		registry: registry,
+	}
+	app.Get("/healthz", h.Liveness)
+	app.Get("/readyz", h.Readiness)
+}
+
+// Liveness only reports that the process is serving requests.
+func (h healthHandle) Liveness(c *fiber.Ctx) error {
+	return c.Status(http.StatusOK).JSON(fiber.Map{"status": health.StatusUp})
+}
+
+// Readiness runs every registered dependency check.
+func (h healthHandle) Readiness(c *fiber.Ctx) error {
+	report := h.registry.Run(c.UserContext())
+
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	return c.Status(status).JSON(report)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"GO-Project/health"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveness(t *testing.T) {
+	f := fiber.New()
+	NewHealthHandle(f, health.NewRegistry(time.Second))
+
+	response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/healthz", nil))
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+
+	require.Equal(t, 200, response.StatusCode)
+}
+
+func TestReadiness(t *testing.T) {
+	f := fiber.New()
+	registry := health.NewRegistry(time.Second)
+	NewHealthHandle(f, registry)
+
+	registry.Register("mongo", func(ctx context.Context) error { return nil })
+
+	response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/readyz", nil))
+	require.NoError(t, err)
+	require.Equal(t, 200, response.StatusCode)
+	_ = response.Body.Close()
+
+	registry.Register("cache", func(ctx context.Context) error { return errors.New("unreachable") })
+
+	response, err = f.Test(httptest.NewRequest(fiber.MethodGet, "/readyz", nil))
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	require.Equal(t, 503, response.StatusCode)
+
+	var report health.Report
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&report))
+	require.Equal(t, health.StatusDown, report.Status)
+	require.Equal(t, "unreachable", report.Checks["cache"].Error)
+	require.Equal(t, health.StatusUp, report.Checks["mongo"].Status)
+}
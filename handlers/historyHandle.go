@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"GO-Project/models"
+	"GO-Project/policy"
+	"GO-Project/responses"
+	"GO-Project/services"
+	"GO-Project/validation"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type historyHandle struct {
+	history services.HistoryService
+}
+
+// NewHistoryHandle registers the revision history routes of an experience on
+// router, relative to the prefix of its API version group.
+func NewHistoryHandle(router fiber.Router, history services.HistoryService) {
+	c := historyHandle{
+		history: history,
+	}
+	read := Require(policy.ActionRead)
+	router.Get("/experiences/:experienceId/revisions", read, c.List)
+	router.Get("/experiences/:experienceId/revisions/diff", read, c.Diff)
+	router.Get("/experiences/:experienceId/revisions/:revision", read, c.Get)
+	router.Post("/experiences/:experienceId/revisions/:revision/restore", Require(policy.ActionUpdate), c.Restore)
+}
+
+// List returns the revisions of an experience, oldest first, without their
+// snapshots.
+func (h historyHandle) List(c *fiber.Ctx) error {
+	id, err := experienceId(c)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := h.history.Revisions(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+
+	views := make([]models.RevisionView, len(revisions))
+	for i := range revisions {
+		views[i] = models.NewRevisionView(&revisions[i], false)
+	}
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    &fiber.Map{"data": views},
+	})
+}
+
+func (h historyHandle) Get(c *fiber.Ctx) error {
+	id, err := experienceId(c)
+	if err != nil {
+		return err
+	}
+	number, err := revisionNumber(c)
+	if err != nil {
+		return err
+	}
+
+	revision, err := h.history.Revision(c.UserContext(), id, number)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    &fiber.Map{"data": models.NewRevisionView(revision, true)},
+	})
+}
+
+// Diff compares the revisions given by the from and to query parameters.
+func (h historyHandle) Diff(c *fiber.Ctx) error {
+	id, err := experienceId(c)
+	if err != nil {
+		return err
+	}
+
+	var errs validation.Errors
+	fail := func(field, rule, message string) {
+		errs = append(errs, validation.FieldError{Field: field, Rule: rule, Message: message})
+	}
+	required := func(key string) int {
+		if c.Query(key) == "" {
+			fail(key, "required", "is required")
+			return 0
+		}
+		return queryInt(c, key, 1, 0, fail)
+	}
+	from, to := required("from"), required("to")
+	if err := services.NewValidationError(errs); err != nil {
+		return err
+	}
+
+	changes, err := h.history.Diff(c.UserContext(), id, int64(from), int64(to))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data: &fiber.Map{
+			"data": models.NewChangeViews(changes),
+			"meta": fiber.Map{"from": from, "to": to},
+		},
+	})
+}
+
+// Restore makes the snapshot of a revision the current state of the
+// experience. Like PUT, it honours If-Match and If-None-Match.
+func (h historyHandle) Restore(c *fiber.Ctx) error {
+	id, err := experienceId(c)
+	if err != nil {
+		return err
+	}
+	number, err := revisionNumber(c)
+	if err != nil {
+		return err
+	}
+
+	restored, err := h.history.Restore(c.UserContext(), id, number, precondition(c))
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderETag, etag(restored.Version))
+
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data:    &fiber.Map{"data": models.NewExperienceView(restored)},
+	})
+}
+
+// revisionNumber parses the :revision route parameter.
+func revisionNumber(c *fiber.Ctx) (int64, error) {
+	n, err := strconv.ParseInt(c.Params("revision"), 10, 64)
+	if err != nil || n < 1 {
+		return 0, fiber.NewError(http.StatusBadRequest, "invalid revision number")
+	}
+	return n, nil
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"GO-Project/services"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryRoutes(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	history := services.NewHistoryService(services.NewMemoryExperienceService(), services.NewMemoryHistoryStore())
+	NewHistoryHandle(NewV1(f, history), history)
+
+	send := func(method, url, contentType, body string) (int, map[string]any) {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		response, err := f.Test(req)
+		require.NoError(t, err)
+		defer func() { _ = response.Body.Close() }()
+		data, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(data, &decoded), string(data))
+		return response.StatusCode, decoded
+	}
+
+	status, body := send(fiber.MethodPost, "/api/v1/experiences", "application/json",
+		`{"company": "Acme", "title": "Developer", "startDate": "2020-01-01T00:00:00Z"}`)
+	require.Equal(t, 201, status)
+	url := "/api/v1/experiences/" + body["data"].(map[string]any)["data"].(map[string]any)["id"].(string)
+
+	status, _ = send(fiber.MethodPatch, url, "application/merge-patch+json", `{"title": "Lead"}`)
+	require.Equal(t, 200, status)
+
+	status, body = send(fiber.MethodGet, url+"/revisions", "", "")
+	require.Equal(t, 200, status)
+	revisions := body["data"].(map[string]any)["data"].([]any)
+	require.Len(t, revisions, 2)
+	require.Equal(t, "patch", revisions[1].(map[string]any)["action"])
+	require.NotContains(t, revisions[1], "snapshot")
+
+	status, body = send(fiber.MethodGet, url+"/revisions/2", "", "")
+	require.Equal(t, 200, status)
+	revision := body["data"].(map[string]any)["data"].(map[string]any)
+	require.Equal(t, "Lead", revision["snapshot"].(map[string]any)["title"])
+
+	status, body = send(fiber.MethodGet, url+"/revisions/diff?from=1&to=2", "", "")
+	require.Equal(t, 200, status)
+	require.Equal(t, []any{map[string]any{"field": "title", "from": "Developer", "to": "Lead"}}, body["data"].(map[string]any)["data"])
+
+	status, body = send(fiber.MethodPost, url+"/revisions/1/restore", "", "")
+	require.Equal(t, 200, status)
+	require.Equal(t, "Developer", body["data"].(map[string]any)["data"].(map[string]any)["title"])
+
+	for path, want := range map[string]int{
+		"/revisions/diff?from=1":      422,
+		"/revisions/diff?from=x&to=2": 422,
+		"/revisions/0":                400,
+		"/revisions/9":                404,
+	} {
+		status, _ = send(fiber.MethodGet, url+path, "", "")
+		require.Equal(t, want, status, path)
+	}
+}
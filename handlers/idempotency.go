@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"GO-Project/auth"
+	"GO-Project/models"
+	"GO-Project/services"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderIdempotencyKey carries the client chosen key that makes a POST safe
+// to retry.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the keys accepted, long enough for any UUID
+// or ULID based scheme.
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the response headers stored and replayed with the
+// body.
+var replayedHeaders = []string{fiber.HeaderContentType, fiber.HeaderLocation, fiber.HeaderETag}
+
+type idempotencyKey struct{}
+
+// idempotency is what Idempotency installs for Idempotent.
+type idempotency struct {
+	store services.IdempotencyStore
+	ttl   time.Duration
+}
+
+// Idempotency makes the routes after it guarded by Idempotent keep their
+// responses in store for ttl. Without it, Idempotent does nothing.
+func Idempotency(store services.IdempotencyStore, ttl time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(idempotencyKey{}, idempotency{store: store, ttl: ttl})
+		return c.Next()
+	}
+}
+
+// Idempotent makes a POST route safe to retry with an Idempotency-Key
+// header. The first request with a key runs and its response is kept;
+// repeats with the same key and request get that response again, marked
+// with Idempotent-Replayed, while a different request with the key is
+// rejected with 422. A repeat arriving while the first request is still
+// running gets 409. Responses with a 5xx status are not kept, so the
+// request can be retried. Keys of authenticated requests belong to their
+// principal, so clients cannot see each other's responses.
+//
+// Responses are stored as they are, so routes returning secrets, such as
+// API keys, must not use it.
+func Idempotent(c *fiber.Ctx) error {
+	config, ok := c.Locals(idempotencyKey{}).(idempotency)
+	// Values read from c are only valid during the request, unlike the
+	// record stored.
+	key := strings.Clone(c.Get(HeaderIdempotencyKey))
+	if !ok || c.Method() != fiber.MethodPost || key == "" {
+		return c.Next()
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return fiber.NewError(http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+	}
+	store := config.store
+
+	if p, ok := auth.FromContext(c.UserContext()); ok {
+		key = p.Subject + "\n" + key
+	}
+	record := &models.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: fingerprint(c),
+		ExpiresAt:   time.Now().Add(config.ttl),
+	}
+	existing, err := store.Reserve(c.UserContext(), record)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return replay(c, existing, record.Fingerprint)
+	}
+
+	// The outcome is settled even if the request ran out of time.
+	ctx := context.WithoutCancel(c.UserContext())
+	if err := c.Next(); err != nil {
+		if err := c.App().Config().ErrorHandler(c, err); err != nil {
+			return releaseKey(ctx, store, key, err)
+		}
+	}
+	status := c.Response().StatusCode()
+	if status >= http.StatusInternalServerError {
+		return releaseKey(ctx, store, key, nil)
+	}
+
+	record.Status = status
+	record.Body = append([]byte(nil), c.Response().Body()...)
+	record.Header = map[string]string{}
+	for _, name := range replayedHeaders {
+		if value := c.GetRespHeader(name); value != "" {
+			record.Header[name] = strings.Clone(value)
+		}
+	}
+	if err := store.Complete(ctx, record); err != nil {
+		// The response stands; a retry runs the request again once the
+		// reservation expires.
+		slog.Error("store idempotent response", slog.String("key", key), slog.Any("err", err))
+	}
+	return nil
+}
+
+// replay answers a request whose key is already held.
+func replay(c *fiber.Ctx, record *models.IdempotencyRecord, fingerprint string) error {
+	switch {
+	case record.Fingerprint != fingerprint:
+		return fiber.NewError(http.StatusUnprocessableEntity, "Idempotency-Key was already used with a different request")
+	case record.Status == 0:
+		return fiber.NewError(http.StatusConflict, "a request with this Idempotency-Key is still in progress")
+	}
+	for name, value := range record.Header {
+		c.Set(name, value)
+	}
+	c.Set("Idempotent-Replayed", "true")
+	return c.Status(record.Status).Send(record.Body)
+}
+
+// releaseKey frees the key of a request without a response worth keeping
+// and passes on err.
+func releaseKey(ctx context.Context, store services.IdempotencyStore, key string, err error) error {
+	if releaseErr := store.Release(ctx, key); releaseErr != nil {
+		slog.Error("release idempotency key", slog.String("key", key), slog.Any("err", releaseErr))
+	}
+	return err
+}
+
+// fingerprint identifies a request by its method, path and body, so that a
+// key is bound to the request it was first sent with.
+func fingerprint(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method() + " " + c.Path() + "\n"))
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"GO-Project/models"
+	"GO-Project/policy"
+	"GO-Project/services"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	store := services.NewMemoryIdempotencyStore()
+	f.Use(Idempotency(store, time.Hour))
+	svc := services.NewMemoryExperienceService()
+	NewV1(f, svc)
+
+	type response struct {
+		status int
+		header http.Header
+		body   string
+	}
+	post := func(key, body string) response {
+		request := httptest.NewRequest(fiber.MethodPost, "/api/v1/experiences", strings.NewReader(body))
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		if key != "" {
+			request.Header.Set(HeaderIdempotencyKey, key)
+		}
+		r, err := f.Test(request)
+		require.NoError(t, err)
+		defer r.Body.Close()
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		return response{r.StatusCode, r.Header, string(data)}
+	}
+	count := func() int64 {
+		all, err := svc.FindAll(context.Background(), services.ListQuery{})
+		require.NoError(t, err)
+		return all.Total
+	}
+	payload := `{"company": "Acme", "title": "Dev", "startDate": "2020-01-01T00:00:00Z"}`
+
+	first := post("key-1", payload)
+	require.Equal(t, http.StatusCreated, first.status)
+	require.Empty(t, first.header.Get("Idempotent-Replayed"))
+
+	again := post("key-1", payload)
+	require.Equal(t, http.StatusCreated, again.status)
+	require.Equal(t, first.body, again.body)
+	require.Equal(t, first.header.Get(fiber.HeaderLocation), again.header.Get(fiber.HeaderLocation))
+	require.Equal(t, first.header.Get(fiber.HeaderETag), again.header.Get(fiber.HeaderETag))
+	require.Equal(t, "true", again.header.Get("Idempotent-Replayed"))
+	require.Equal(t, int64(1), count())
+
+	require.Equal(t, http.StatusUnprocessableEntity, post("key-1", strings.Replace(payload, "Dev", "Lead", 1)).status)
+	require.Equal(t, int64(1), count())
+
+	// Error responses are replayed too.
+	invalid := post("key-2", `{"company": "Acme"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, invalid.status)
+	require.Equal(t, invalid.body, post("key-2", `{"company": "Acme"}`).body)
+
+	require.Equal(t, http.StatusCreated, post("", payload).status)
+	require.Equal(t, http.StatusCreated, post("", payload).status)
+	require.Equal(t, int64(3), count())
+
+	require.Equal(t, http.StatusBadRequest, post(strings.Repeat("k", 256), payload).status)
+
+	// A request still running holds its key.
+	sum := sha256.Sum256([]byte("POST /api/v1/experiences\n" + payload))
+	_, err := store.Reserve(context.Background(), &models.IdempotencyRecord{Key: "key-3", Fingerprint: hex.EncodeToString(sum[:]), ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusConflict, post("key-3", payload).status)
+	require.Equal(t, int64(3), count())
+}
+
+func TestIdempotencyReleasesServerErrors(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	f.Use(Idempotency(services.NewMemoryIdempotencyStore(), time.Hour))
+	calls := 0
+	f.Post("/", Idempotent, func(c *fiber.Ctx) error {
+		calls++
+		if calls == 1 {
+			return &services.Error{Kind: services.ErrUnavailable, Message: "storage is temporarily unavailable"}
+		}
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	post := func() int {
+		request := httptest.NewRequest(fiber.MethodPost, "/", nil)
+		request.Header.Set(HeaderIdempotencyKey, "key")
+		r, err := f.Test(request)
+		require.NoError(t, err)
+		return r.StatusCode
+	}
+	require.Equal(t, http.StatusServiceUnavailable, post())
+	require.Equal(t, http.StatusCreated, post())
+	require.Equal(t, http.StatusCreated, post())
+	require.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeysPerPrincipal(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	f.Use(Authenticate(staticAuthenticator{"Bearer a": {Subject: "a"}, "Bearer b": {Subject: "b"}}))
+	f.Use(Idempotency(services.NewMemoryIdempotencyStore(), time.Hour))
+	calls := 0
+	f.Post("/", Idempotent, func(c *fiber.Ctx) error {
+		calls++
+		return c.SendStatus(http.StatusCreated)
+	})
+
+	post := func(token string) *http.Response {
+		request := httptest.NewRequest(fiber.MethodPost, "/", nil)
+		request.Header.Set(fiber.HeaderAuthorization, token)
+		request.Header.Set(HeaderIdempotencyKey, "key")
+		r, err := f.Test(request)
+		require.NoError(t, err)
+		return r
+	}
+	require.Empty(t, post("Bearer a").Header.Get("Idempotent-Replayed"))
+	require.Empty(t, post("Bearer b").Header.Get("Idempotent-Replayed"))
+	require.Equal(t, "true", post("Bearer a").Header.Get("Idempotent-Replayed"))
+	require.Equal(t, 2, calls)
+}
+
+func TestIdempotencyStoresNoAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiences.idempotency.json")
+	store, err := services.NewFileIdempotencyStore(path)
+	require.NoError(t, err)
+	keys := services.NewAPIKeyService(services.NewMemoryAPIKeyStore(), policy.Default())
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	f.Use(Authenticate(staticAuthenticator{"Bearer admin": {Subject: "root", Roles: []string{policy.RoleAdmin}}}))
+	f.Use(Enforce(policy.Default()), Idempotency(store, time.Hour))
+	v1 := NewV1(f, services.NewMemoryExperienceService())
+	NewAPIKeyHandle(v1, keys)
+
+	post := func(url, body string) string {
+		request := httptest.NewRequest(fiber.MethodPost, url, strings.NewReader(body))
+		request.Header.Set(fiber.HeaderAuthorization, "Bearer admin")
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		request.Header.Set(HeaderIdempotencyKey, "key-"+url)
+		r, err := f.Test(request)
+		require.NoError(t, err)
+		defer r.Body.Close()
+		require.Empty(t, r.Header.Get("Idempotent-Replayed"))
+		var created struct {
+			Data struct{ Data models.APIKeyView }
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&created))
+		require.NotEmpty(t, created.Data.Data.Key)
+		return created.Data.Data.ID
+	}
+	id := post("/api/v1/apikeys", `{"name": "ci", "permissions": ["experiences:read"]}`)
+	post("/api/v1/apikeys/"+id+"/rotate", "")
+	post("/api/v1/apikeys/"+id+"/rotate", "")
+
+	// Neither response was kept, so the file was never written.
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"GO-Project/auth"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestTimeout bounds the user context of every request, so service calls
+// made with c.UserContext() are cancelled once the deadline passes.
+func RequestTimeout(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// Deprecated marks responses of a route scheduled for removal with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers, and links the route
+// that replaces it. The headers are set on error responses too.
+func Deprecated(deprecatedAt, sunset time.Time, successor func(c *fiber.Ctx) string) fiber.Handler {
+	deprecation := "@" + strconv.FormatInt(deprecatedAt.Unix(), 10)
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", deprecation)
+		c.Set("Sunset", sunsetDate)
+		c.Append(fiber.HeaderLink, "<"+successor(c)+`>; rel="successor-version"`)
+		return c.Next()
+	}
+}
+
+// Authenticate rejects requests that a does not authenticate with 401
+// Unauthorized and puts the principal of the others in the user context,
+// where auth.FromContext finds it. The challenge names Bearer tokens only;
+// API keys are for clients configured to send them.
+func Authenticate(a auth.Authenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p, err := a.Authenticate(c.UserContext(), func(name string) string { return c.Get(name) })
+		switch {
+		case errors.Is(err, auth.ErrNoCredentials):
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="experiences"`)
+			return fiber.NewError(fiber.StatusUnauthorized, "authentication required")
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="experiences", error="invalid_token"`)
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid credentials")
+		case err != nil:
+			return err
+		}
+
+		c.SetUserContext(auth.WithPrincipal(c.UserContext(), p))
+		return c.Next()
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"GO-Project/auth"
+	"GO-Project/services"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	f := fiber.New()
+	f.Use(RequestTimeout(time.Minute))
+
+	var deadline time.Time
+	f.Get("/", func(c *fiber.Ctx) error {
+		var ok bool
+		deadline, ok = c.UserContext().Deadline()
+		require.True(t, ok)
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+
+	require.Equal(t, fiber.StatusNoContent, response.StatusCode)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
+
+type staticAuthenticator map[string]*auth.Principal
+
+func (a staticAuthenticator) Authenticate(_ context.Context, header func(string) string) (*auth.Principal, error) {
+	token := header(fiber.HeaderAuthorization)
+	if token == "" {
+		return nil, auth.ErrNoCredentials
+	}
+	if p, ok := a[token]; ok {
+		return p, nil
+	}
+	return nil, auth.ErrInvalidCredentials
+}
+
+func TestAuthenticate(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	f.Use(Authenticate(staticAuthenticator{"Bearer good": {Subject: "user-1"}}))
+	f.Get("/", func(c *fiber.Ctx) error {
+		p, ok := auth.FromContext(c.UserContext())
+		require.True(t, ok)
+		return c.SendString(p.Subject)
+	})
+
+	get := func(authorization string) *http.Response {
+		request := httptest.NewRequest(fiber.MethodGet, "/", nil)
+		if authorization != "" {
+			request.Header.Set(fiber.HeaderAuthorization, authorization)
+		}
+		response, err := f.Test(request)
+		require.NoError(t, err)
+		return response
+	}
+
+	response := get("Bearer good")
+	defer func() { _ = response.Body.Close() }()
+	require.Equal(t, fiber.StatusOK, response.StatusCode)
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.Equal(t, "user-1", string(body))
+
+	response = get("")
+	require.Equal(t, fiber.StatusUnauthorized, response.StatusCode)
+	require.Equal(t, `Bearer realm="experiences"`, response.Header.Get(fiber.HeaderWWWAuthenticate))
+
+	response = get("Bearer bad")
+	require.Equal(t, fiber.StatusUnauthorized, response.StatusCode)
+	require.Equal(t, `Bearer realm="experiences", error="invalid_token"`, response.Header.Get(fiber.HeaderWWWAuthenticate))
+}
+
+func TestRequestTimeoutExpired(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	f.Use(RequestTimeout(time.Nanosecond))
+	NewV1(f, services.NewMemoryExperienceService())
+
+	response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/experiences", nil))
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+
+	require.Equal(t, fiber.StatusServiceUnavailable, response.StatusCode)
+}
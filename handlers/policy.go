@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"GO-Project/auth"
+	"GO-Project/policy"
+	"GO-Project/responses"
+	"GO-Project/services"
+	"net/http"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type policyKey struct{}
+
+// Enforce makes Require check the actions of the routes after it against p.
+// Without it, as with authentication disabled, every action is allowed.
+func Enforce(p *policy.Policy) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(policyKey{}, p)
+		return c.Next()
+	}
+}
+
+// Require guards a handler with the policy action it performs. Denied
+// requests get 403 Forbidden with the reason; the ones allowed on every
+// owner's experiences have their services called WithAllOwners.
+func Require(action policy.Action) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := authorize(c, action); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+// authorize decides the actions for the request, all of which must be
+// allowed. Experiences of every owner are only reachable if each action
+// allows it.
+func authorize(c *fiber.Ctx, actions ...policy.Action) error {
+	p, ok := c.Locals(policyKey{}).(*policy.Policy)
+	if !ok || len(actions) == 0 {
+		return nil
+	}
+	principal, _ := auth.FromContext(c.UserContext())
+
+	anyOwner := true
+	for _, action := range actions {
+		d := p.Decide(principal, action)
+		if !d.Allowed {
+			return fiber.NewError(http.StatusForbidden, "forbidden: "+d.Reason)
+		}
+		anyOwner = anyOwner && d.AnyOwner
+	}
+	if anyOwner {
+		c.SetUserContext(services.WithAllOwners(c.UserContext()))
+	}
+	return nil
+}
+
+type policyHandle struct {
+	policy *policy.Policy
+}
+
+// NewPolicyHandle registers the endpoint explaining the decisions of p for
+// the calling principal.
+func NewPolicyHandle(router fiber.Router, p *policy.Policy) {
+	c := policyHandle{policy: p}
+	router.Get("/policy/explain", c.Explain)
+}
+
+// decisionView is a policy.Decision as returned by the explain endpoint.
+type decisionView struct {
+	Action     policy.Action `json:"action"`
+	Allowed    bool          `json:"allowed"`
+	Owner      string        `json:"owner,omitempty"`
+	Role       string        `json:"role,omitempty"`
+	Permission string        `json:"permission,omitempty"`
+	Reason     string        `json:"reason"`
+}
+
+// Explain reports the decision for every action, or the one given by the
+// action query parameter, to debug requests that were denied.
+func (h policyHandle) Explain(c *fiber.Ctx) error {
+	actions := policy.Actions
+	if action := policy.Action(c.Query("action")); action != "" {
+		if !slices.Contains(policy.Actions, action) {
+			return fiber.NewError(http.StatusBadRequest, "unknown action "+string(action))
+		}
+		actions = []policy.Action{action}
+	}
+	principal, _ := auth.FromContext(c.UserContext())
+
+	roles := []string{}
+	decisions := make([]decisionView, len(actions))
+	for i, action := range actions {
+		d := h.policy.Decide(principal, action)
+		roles = d.Roles
+		decisions[i] = decisionView{Action: action, Allowed: d.Allowed, Role: d.Role, Permission: d.Permission, Reason: d.Reason}
+		if d.Allowed && action.Owned() {
+			decisions[i].Owner = policy.OwnerOwn
+			if d.AnyOwner {
+				decisions[i].Owner = policy.OwnerAny
+			}
+		}
+	}
+	subject := ""
+	if principal != nil {
+		subject = principal.Subject
+	}
+
+	return c.Status(http.StatusOK).JSON(responses.MessageResponse{
+		Status:  http.StatusOK,
+		Message: "success",
+		Data: &fiber.Map{"data": fiber.Map{
+			"subject":   subject,
+			"roles":     roles,
+			"decisions": decisions,
+		}},
+	})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"GO-Project/auth"
+	"GO-Project/models"
+	"GO-Project/policy"
+	"GO-Project/services"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequire(t *testing.T) {
+	rules, err := policy.New(policy.Definition{
+		Roles: map[string][]string{
+			"viewer": {"experiences:read"},
+			"editor": {"experiences:*:any"},
+		},
+		DefaultRoles: []string{"viewer"},
+	})
+	require.NoError(t, err)
+
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	f.Use(Authenticate(staticAuthenticator{
+		"Bearer viewer": {Subject: "alice"},
+		"Bearer editor": {Subject: "bob", Roles: []string{"editor"}},
+	}))
+	f.Use(Enforce(rules))
+	svc := services.NewMemoryExperienceService()
+	v1 := NewV1(f, svc)
+	NewPolicyHandle(v1, rules)
+
+	send := func(token, method, url, body string) *http.Response {
+		request := httptest.NewRequest(method, url, strings.NewReader(body))
+		request.Header.Set(fiber.HeaderAuthorization, token)
+		request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		response, err := f.Test(request)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = response.Body.Close() })
+		return response
+	}
+	payload := `{"company": "Acme", "title": "Dev", "startDate": "2020-01-01T00:00:00Z"}`
+
+	require.Equal(t, 200, send("Bearer viewer", fiber.MethodGet, "/api/v1/experiences", "").StatusCode)
+	response := send("Bearer viewer", fiber.MethodPost, "/api/v1/experiences", payload)
+	require.Equal(t, 403, response.StatusCode)
+	var denied struct{ Data struct{ Data string } }
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&denied))
+	require.Equal(t, "forbidden: no role of viewer grants experiences:create", denied.Data.Data)
+	require.Equal(t, 403, send("Bearer viewer", fiber.MethodPost, "/api/v1/experiences/bulk",
+		`{"operations": [{"op": "create", "data": `+payload+`}]}`).StatusCode)
+
+	// Editors act on experiences of every owner.
+	require.Equal(t, 201, send("Bearer editor", fiber.MethodPost, "/api/v1/experiences", payload).StatusCode)
+	created, err := svc.Create(auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "carol"}),
+		&models.ExperienceDto{Company: "Acme", Title: "Dev", StartDate: time.Now()})
+	require.NoError(t, err)
+	require.Equal(t, 200, send("Bearer editor", fiber.MethodGet, "/api/v1/experiences/"+created.ID.Hex(), "").StatusCode)
+	require.Equal(t, 404, send("Bearer viewer", fiber.MethodGet, "/api/v1/experiences/"+created.ID.Hex(), "").StatusCode)
+	require.Equal(t, 200, send("Bearer editor", fiber.MethodPost, "/api/v1/experiences/bulk",
+		`{"operations": [{"op": "delete", "id": "`+created.ID.Hex()+`"}]}`).StatusCode)
+
+	var explained struct {
+		Data struct {
+			Data struct {
+				Subject   string
+				Roles     []string
+				Decisions []decisionView
+			}
+		}
+	}
+	require.NoError(t, json.NewDecoder(send("Bearer viewer", fiber.MethodGet, "/api/v1/policy/explain", "").Body).Decode(&explained))
+	require.Equal(t, "alice", explained.Data.Data.Subject)
+	require.Equal(t, []string{"viewer"}, explained.Data.Data.Roles)
+	require.Len(t, explained.Data.Data.Decisions, len(policy.Actions))
+	require.Equal(t, decisionView{Action: policy.ActionRead, Allowed: true, Owner: policy.OwnerOwn, Role: "viewer", Permission: "experiences:read:own", Reason: "role viewer grants experiences:read on the principal's own experiences"}, explained.Data.Data.Decisions[0])
+	require.False(t, explained.Data.Data.Decisions[1].Allowed)
+
+	require.NoError(t, json.NewDecoder(send("Bearer editor", fiber.MethodGet, "/api/v1/policy/explain?action=experiences:delete", "").Body).Decode(&explained))
+	require.Equal(t, []decisionView{{Action: policy.ActionDelete, Allowed: true, Owner: policy.OwnerAny, Role: "editor", Permission: "experiences:*:any", Reason: "role editor grants experiences:delete on every experience"}}, explained.Data.Data.Decisions)
+	require.Equal(t, 400, send("Bearer editor", fiber.MethodGet, "/api/v1/policy/explain?action=publish", "").StatusCode)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"GO-Project/policy"
+	"GO-Project/services"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Each API version is a route group of its own, so a later version can be
+// mounted next to v1 and change its routes without touching it.
+const v1Prefix = "/api/v1"
+
+// legacyDeprecatedAt is when the unversioned routes were superseded by v1.
+var legacyDeprecatedAt = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+// NewV1 mounts version 1 of the API and returns its group.
+func NewV1(app fiber.Router, expService services.ExperienceService) fiber.Router {
+	v1 := app.Group(v1Prefix)
+	NewExperienceHandle(v1, expService)
+	return v1
+}
+
+// NewLegacyExperienceHandle keeps the routes that predate /api/v1 working.
+// Their responses announce the deprecation and point to the v1 equivalent.
+// Created experiences are located under v1, which must be mounted too.
+func NewLegacyExperienceHandle(app fiber.Router, expService services.ExperienceService, sunset time.Time) {
+	c := expHandle{
+		expService: expService,
+	}
+	legacy := func(successor func(c *fiber.Ctx) string) fiber.Handler {
+		return Deprecated(legacyDeprecatedAt, sunset, successor)
+	}
+	collection := legacy(func(*fiber.Ctx) string { return v1Prefix + "/experiences" })
+	search := legacy(func(*fiber.Ctx) string { return v1Prefix + "/experiences/search" })
+	item := legacy(func(c *fiber.Ctx) string {
+		return v1Prefix + "/experiences/" + url.PathEscape(c.Params("experienceId"))
+	})
+
+	app.Post("/api/experiences", collection, Require(policy.ActionCreate), Idempotent, c.Create)
+	app.Get("/api/", collection, Require(policy.ActionRead), c.FindAll)
+	app.Get("/api/search", search, Require(policy.ActionRead), c.Search)
+	app.Get("/api/:experienceId", item, Require(policy.ActionRead), c.FindById)
+	app.Put("/api/:experienceId", item, Require(policy.ActionUpdate), c.Update)
+	app.Delete("/api/:experienceId", item, Require(policy.ActionDelete), c.Delete)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"GO-Project/models"
+	"GO-Project/services"
+	mock_services "GO-Project/services/mocks"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLegacyRoutesAreDeprecatedAliases(t *testing.T) {
+	f := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+
+	ctrl := gomock.NewController(t)
+	mockStore := mock_services.NewMockExperienceService(ctrl)
+	NewV1(f, mockStore)
+	NewLegacyExperienceHandle(f, mockStore, time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC))
+
+	id := primitive.NewObjectID()
+	exp := &models.Experience{ID: id, Version: 1}
+	mockStore.EXPECT().FindById(gomock.Any(), id).Times(2).Return(exp, nil)
+	mockStore.EXPECT().FindAll(gomock.Any(), gomock.Any()).Times(1).Return(&services.ListResult{}, nil)
+	mockStore.EXPECT().Create(gomock.Any(), gomock.Any()).Times(1).Return(exp, nil)
+	mockStore.EXPECT().Delete(gomock.Any(), id, gomock.Any()).Times(1).Return(services.ErrNotFound)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		successor          string
+	}{
+		{fiber.MethodGet, "/api/" + id.Hex(), "", 200, "/api/v1/experiences/" + id.Hex()},
+		{fiber.MethodGet, "/api/", "", 200, "/api/v1/experiences"},
+		{fiber.MethodPost, "/api/experiences", `{"company": "Acme", "title": "Dev", "startDate": "2020-01-01T00:00:00Z"}`, 201, "/api/v1/experiences"},
+		{fiber.MethodDelete, "/api/" + id.Hex(), "", 404, "/api/v1/experiences/" + id.Hex()},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+
+		response, err := f.Test(req)
+		require.NoError(t, err)
+		_ = response.Body.Close()
+
+		require.Equal(t, tt.status, response.StatusCode, tt.path)
+		require.Equal(t, "@1792281600", response.Header.Get("Deprecation"), tt.path)
+		require.Equal(t, "Fri, 30 Apr 2027 00:00:00 GMT", response.Header.Get("Sunset"), tt.path)
+		require.Equal(t, "<"+tt.successor+`>; rel="successor-version"`, response.Header.Get("Link"), tt.path)
+		if tt.status == 201 {
+			require.Equal(t, "/api/v1/experiences/"+id.Hex(), response.Header.Get("Location"))
+		}
+	}
+
+	response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/experiences/"+id.Hex(), nil))
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	require.Equal(t, 200, response.StatusCode)
+	require.Empty(t, response.Header.Get("Deprecation"))
+}
+
+func TestVersionsMountIndependently(t *testing.T) {
+	f := fiber.New()
+
+	ctrl := gomock.NewController(t)
+	NewV1(f, mock_services.NewMockExperienceService(ctrl))
+	f.Group("/api/v2").Get("/experiences", func(c *fiber.Ctx) error {
+		return c.SendString("v2")
+	})
+
+	response, err := f.Test(httptest.NewRequest(fiber.MethodGet, "/api/v2/experiences", nil))
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	require.Equal(t, 200, response.StatusCode)
+
+	// Without the legacy aliases nothing answers outside the version groups.
+	response, err = f.Test(httptest.NewRequest(fiber.MethodGet, "/api/"+primitive.NewObjectID().Hex(), nil))
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	require.Equal(t, 404, response.StatusCode)
+}
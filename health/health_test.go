@@ -0,0 +1,39 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAllUp(t *testing.T) {
+	r := NewRegistry(time.Second)
+	r.Register("a", func(ctx context.Context) error { return nil })
+	r.Register("b", func(ctx context.Context) error { return nil })
+
+	report := r.Run(context.Background())
+	require.Equal(t, StatusUp, report.Status)
+	require.Len(t, report.Checks, 2)
+	require.Equal(t, []string{"a", "b"}, r.Names())
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	r := NewRegistry(20 * time.Millisecond)
+	r.Register("ok", func(ctx context.Context) error { return nil })
+	r.Register("broken", func(ctx context.Context) error { return errors.New("boom") })
+	r.Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	r.Register("panics", func(ctx context.Context) error { panic("oops") })
+
+	report := r.Run(context.Background())
+	require.Equal(t, StatusDown, report.Status)
+	require.Equal(t, StatusUp, report.Checks["ok"].Status)
+	require.Equal(t, "boom", report.Checks["broken"].Error)
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error)
+	require.Contains(t, report.Checks["panics"].Error, "oops")
+}
@@ -1,28 +1,250 @@
 package main
 
 import (
+	"GO-Project/auth"
 	"GO-Project/configs"
 	"GO-Project/handlers"
+	"GO-Project/health"
+	"GO-Project/policy"
 	"GO-Project/services"
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 func main() {
-	app := fiber.New()
+	cfg, err := configs.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	level, _ := configs.ParseLogLevel(cfg.Log.Level)
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	if err := run(cfg); err != nil {
+		slog.Error("server stopped", slog.Any("err", err))
+		os.Exit(1)
+	}
+}
+
+func run(cfg *configs.Config) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	registry := health.NewRegistry(cfg.Health.Timeout)
+
+	store, err := openStorage(ctx, cfg, registry)
+	if err != nil {
+		return err
+	}
+	expService := store.experiences
+
+	if cfg.Storage.TrashRetention > 0 {
+		go services.RunTrashRetention(ctx, expService, cfg.Storage.TrashRetention, cfg.Storage.PurgeInterval)
+	}
+
+	app := fiber.New(fiber.Config{
+		ReadTimeout:           cfg.Server.ReadTimeout,
+		WriteTimeout:          cfg.Server.WriteTimeout,
+		IdleTimeout:           cfg.Server.IdleTimeout,
+		DisableStartupMessage: true,
+		ErrorHandler:          handlers.NewErrorHandler(cfg.Server.ErrorFormat),
+	})
 
-	db := configs.ConnectDB()
-	defer func() {
-		if err := db.Disconnect(context.TODO()); err != nil {
-			panic(err)
+	handlers.NewHealthHandle(app, registry)
+
+	app.Use(handlers.RequestTimeout(cfg.Server.RequestTimeout))
+	var (
+		access  *policy.Policy
+		apiKeys services.APIKeyService
+	)
+	if cfg.Auth.Enabled {
+		authenticator, err := newAuthenticator(cfg.Auth)
+		if err != nil {
+			return err
+		}
+		if access, err = loadPolicy(cfg.Auth.PolicyFile); err != nil {
+			return err
+		}
+		if err := assignOwner(ctx, expService, cfg.Auth.DefaultOwner); err != nil {
+			return err
 		}
+		apiKeys = services.NewAPIKeyService(store.apiKeys, access)
+		app.Use(handlers.Authenticate(auth.Chain(authenticator, apiKeys)), handlers.Enforce(access))
+	}
+	app.Use(handlers.Idempotency(store.idempotency, cfg.Storage.IdempotencyTTL))
+	v1 := handlers.NewV1(app, expService)
+	handlers.NewHistoryHandle(v1, expService)
+	if access != nil {
+		handlers.NewPolicyHandle(v1, access)
+		handlers.NewAPIKeyHandle(v1, apiKeys)
+	}
+	if cfg.API.LegacyRoutes {
+		handlers.NewLegacyExperienceHandle(app, expService, cfg.API.LegacySunset)
+	}
+
+	listenErr := make(chan error, 1)
+	go func() {
+		slog.Info("listening", slog.String("addr", cfg.Server.Addr))
+		listenErr <- app.Listen(cfg.Server.Addr)
 	}()
 
-	expService := services.NewExperienceService(db)
-	handlers.NewExperienceHandle(app, expService)
+	select {
+	case err = <-listenErr:
+		slog.Error("listener failed", slog.Any("err", err))
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, draining in-flight requests",
+			slog.Duration("timeout", cfg.Server.ShutdownTimeout))
+	}
+	stop()
+
+	deadline := time.Now().Add(cfg.Server.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	if shutdownErr := app.ShutdownWithContext(shutdownCtx); shutdownErr != nil {
+		slog.Error("HTTP server did not drain in time", slog.Any("err", shutdownErr))
+		err = errors.Join(err, shutdownErr)
+	} else {
+		slog.Info("HTTP server stopped")
+	}
+
+	if closeErr := store.close(shutdownCtx); closeErr != nil {
+		slog.Error("close storage", slog.Any("err", closeErr))
+		err = errors.Join(err, closeErr)
+	} else {
+		slog.Info("storage closed", slog.String("backend", cfg.Storage.Backend))
+	}
+
+	return err
+}
+
+// assignOwner gives owner the experiences without one, if owner is set.
+func assignOwner(ctx context.Context, expService services.ExperienceService, owner string) error {
+	if owner == "" {
+		return nil
+	}
+	assigned, err := expService.AssignOwner(ctx, owner)
+	if err != nil {
+		return fmt.Errorf("assign experiences without an owner to %s: %w", owner, err)
+	}
+	if assigned > 0 {
+		slog.Info("assigned experiences without an owner", slog.String("owner", owner), slog.Int64("count", assigned))
+	}
+	return nil
+}
+
+// newAuthenticator verifies JWTs with the configured secret and key set.
+func newAuthenticator(cfg configs.AuthConfig) (auth.Authenticator, error) {
+	jwtCfg := auth.JWTConfig{
+		Issuer:   cfg.Issuer,
+		Audience: cfg.Audience,
+		Secret:   []byte(cfg.HS256Secret),
+		Leeway:   cfg.Leeway,
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := auth.LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		jwtCfg.Keys = keys
+	}
+	return auth.NewJWTAuthenticator(jwtCfg)
+}
 
-	if err := app.Listen(":6000"); err != nil {
-		panic(err)
+// loadPolicy reads the access policy from path, the default one if empty.
+func loadPolicy(path string) (*policy.Policy, error) {
+	if path == "" {
+		return policy.Default(), nil
+	}
+	return policy.Load(path)
+}
+
+// storage is what the configured backend keeps.
+type storage struct {
+	experiences services.HistoryService
+	idempotency services.IdempotencyStore
+	apiKeys     services.APIKeyStore
+	// close releases the backend's resources.
+	close func(context.Context) error
+}
+
+// openStorage builds the ExperienceService for the configured backend, with
+// its revision history, idempotency keys and API keys kept by the same
+// backend, and registers its readiness checks.
+func openStorage(ctx context.Context, cfg *configs.Config, registry *health.Registry) (*storage, error) {
+	noop := func(context.Context) error { return nil }
+
+	switch cfg.Storage.Backend {
+	case configs.BackendMemory:
+		slog.Warn("using in-memory storage, data will not survive a restart")
+		return &storage{
+			experiences: services.NewHistoryService(services.NewMemoryExperienceService(), services.NewMemoryHistoryStore()),
+			idempotency: services.NewMemoryIdempotencyStore(),
+			apiKeys:     services.NewMemoryAPIKeyStore(),
+			close:       noop,
+		}, nil
+	case configs.BackendFile:
+		expService, err := services.NewFileExperienceService(cfg.Storage.FilePath)
+		if err != nil {
+			return nil, err
+		}
+		base := strings.TrimSuffix(cfg.Storage.FilePath, filepath.Ext(cfg.Storage.FilePath))
+		history, err := services.NewFileHistoryStore(base + ".history.jsonl")
+		if err != nil {
+			return nil, err
+		}
+		idempotency, err := services.NewFileIdempotencyStore(base + ".idempotency.json")
+		if err != nil {
+			return nil, err
+		}
+		apiKeys, err := services.NewFileAPIKeyStore(base + ".apikeys.json")
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("using file storage", slog.String("path", cfg.Storage.FilePath), slog.String("history", base+".history.jsonl"))
+		return &storage{
+			experiences: services.NewHistoryService(expService, history),
+			idempotency: idempotency,
+			apiKeys:     apiKeys,
+			close:       noop,
+		}, nil
+	default:
+		db, err := configs.ConnectDB(ctx, cfg.Mongo)
+		if err != nil {
+			return nil, err
+		}
+		registry.Register("mongo", db.Ping)
+		historyCollection := cfg.Mongo.Collection + "_revisions"
+		idempotencyCollection := cfg.Mongo.Collection + "_idempotency"
+		if err := errors.Join(
+			services.EnsureIndexes(ctx, db.Client, cfg.Mongo.Database, cfg.Mongo.Collection),
+			services.EnsureHistoryIndexes(ctx, db.Client, cfg.Mongo.Database, historyCollection),
+			services.EnsureIdempotencyIndexes(ctx, db.Client, cfg.Mongo.Database, idempotencyCollection),
+		); err != nil {
+			return nil, errors.Join(err, db.Close(context.Background()))
+		}
+		expService := services.NewExperienceService(db.Client, cfg.Mongo.Database, cfg.Mongo.Collection)
+		history := services.NewMongoHistoryStore(db.Client, cfg.Mongo.Database, historyCollection)
+		return &storage{
+			experiences: services.NewHistoryService(expService, history),
+			idempotency: services.NewMongoIdempotencyStore(db.Client, cfg.Mongo.Database, idempotencyCollection),
+			apiKeys:     services.NewMongoAPIKeyStore(db.Client, cfg.Mongo.Database, cfg.Mongo.Collection+"_apikeys"),
+			close:       db.Close,
+		}, nil
 	}
 }
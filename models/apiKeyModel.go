@@ -0,0 +1,102 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey is a credential of a machine client, such as a CI bot. Only the
+// hash of its secret is stored; the secret is shown once, when the key is
+// created or rotated.
+type APIKey struct {
+	ID   primitive.ObjectID `bson:"_id"`
+	Name string             `bson:"name"`
+	// Subject is the principal the key authenticates as, and so the owner
+	// of the experiences it creates.
+	Subject string `bson:"subject"`
+	// Permissions are the policy permissions the key grants, such as
+	// "experiences:read:any".
+	Permissions []string `bson:"permissions"`
+	// Hash is the hex-encoded SHA-256 of the secret.
+	Hash      string     `bson:"hash"`
+	CreatedBy string     `bson:"createdBy,omitempty"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	RotatedAt *time.Time `bson:"rotatedAt,omitempty"`
+	// ExpiresAt is nil for keys that never expire.
+	ExpiresAt  *time.Time `bson:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `bson:"lastUsedAt,omitempty"`
+	// RevokedAt is set once the key is revoked. Revoked keys are kept so
+	// that they can still be audited.
+	RevokedAt *time.Time `bson:"revokedAt,omitempty"`
+}
+
+// Active reports whether k can be used at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Clone returns a deep copy of k.
+func (k APIKey) Clone() *APIKey {
+	k.Permissions = slices.Clone(k.Permissions)
+	for _, t := range []**time.Time{&k.RotatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt} {
+		if *t != nil {
+			v := **t
+			*t = &v
+		}
+	}
+	return &k
+}
+
+type APIKeyDto struct {
+	Name string `json:"name" validate:"trim,required,max=120,charset=line"`
+	// Subject defaults to "apikey:<id>".
+	Subject     string     `json:"subject,omitempty" validate:"trim,max=120,charset=line"`
+	Permissions []string   `json:"permissions" validate:"required,max=20,dive,trim,required,max=60,charset=line"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+// APIKeyView is the representation of an APIKey returned by the API.
+type APIKeyView struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Subject     string     `json:"subject"`
+	Permissions []string   `json:"permissions"`
+	CreatedBy   string     `json:"createdBy,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	RotatedAt   *time.Time `json:"rotatedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt  *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+	// Key is the full key, only set in the responses creating or rotating
+	// it.
+	Key string `json:"key,omitempty"`
+}
+
+func NewAPIKeyView(k *APIKey) APIKeyView {
+	view := APIKeyView{
+		ID:          k.ID.Hex(),
+		Name:        k.Name,
+		Subject:     k.Subject,
+		Permissions: k.Permissions,
+		CreatedBy:   k.CreatedBy,
+		CreatedAt:   k.CreatedAt,
+		RotatedAt:   k.RotatedAt,
+		ExpiresAt:   k.ExpiresAt,
+		LastUsedAt:  k.LastUsedAt,
+		RevokedAt:   k.RevokedAt,
+	}
+	if view.Permissions == nil {
+		view.Permissions = []string{}
+	}
+	return view
+}
+
+func NewAPIKeyViews(keys []*APIKey) []APIKeyView {
+	views := make([]APIKeyView, len(keys))
+	for i, k := range keys {
+		views[i] = NewAPIKeyView(k)
+	}
+	return views
+}
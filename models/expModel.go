@@ -6,17 +6,190 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// SchemaVersion is the document layout written by this version of the
+// service. Version 1 documents only carried the free-text Experience field.
+const SchemaVersion = 2
+
 type Experience struct {
-	ID         primitive.ObjectID `bson:"_id" json:"id"`
-	Experience string             `bson:"experience" json:"experience" `
-	CreatedAt  time.Time          `bson:"createdAt" json:"-"`
-	UpdatedAt  time.Time          `bson:"updatedAt" json:"-"`
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	Company        string             `bson:"company" json:"company"`
+	Title          string             `bson:"title" json:"title"`
+	EmploymentType string             `bson:"employmentType,omitempty" json:"employmentType,omitempty"`
+	Location       string             `bson:"location,omitempty" json:"location,omitempty"`
+	Remote         bool               `bson:"remote" json:"remote"`
+	StartDate      time.Time          `bson:"startDate" json:"startDate"`
+	EndDate        *time.Time         `bson:"endDate,omitempty" json:"endDate,omitempty"`
+	Summary        string             `bson:"summary,omitempty" json:"summary,omitempty"`
+	Achievements   []string           `bson:"achievements,omitempty" json:"achievements,omitempty"`
+	Skills         []string           `bson:"skills,omitempty" json:"skills,omitempty"`
+	SchemaVersion  int                `bson:"schemaVersion" json:"-"`
+	Version        int64              `bson:"version" json:"version"`
+	CreatedAt      time.Time          `bson:"createdAt" json:"-"`
+	UpdatedAt      time.Time          `bson:"updatedAt" json:"-"`
+	// DeletedAt is set while the experience is in the trash.
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"-"`
+	// OwnerID is the subject of the principal that created the experience,
+	// empty for experiences created without authentication.
+	OwnerID string `bson:"ownerId,omitempty" json:"ownerId,omitempty"`
+
+	// Experience is the free-text field of schema version 1. Upgrade moves
+	// it into Summary; it is never written by the current version.
+	Experience string `bson:"experience,omitempty" json:"-"`
+}
+
+// Upgrade converts a document loaded in an older layout to the current
+// one. It is applied by every backend after reading.
+func (e *Experience) Upgrade() {
+	if e.Version == 0 {
+		e.Version = 1
+	}
+	if e.SchemaVersion >= SchemaVersion {
+		return
+	}
+	if e.Summary == "" {
+		e.Summary = e.Experience
+	}
+	e.Experience = ""
+	e.SchemaVersion = SchemaVersion
+}
+
+// Clone returns a deep copy of e.
+func (e Experience) Clone() *Experience {
+	e.Achievements = append([]string(nil), e.Achievements...)
+	e.Skills = append([]string(nil), e.Skills...)
+	if e.EndDate != nil {
+		end := *e.EndDate
+		e.EndDate = &end
+	}
+	if e.DeletedAt != nil {
+		deleted := *e.DeletedAt
+		e.DeletedAt = &deleted
+	}
+	return &e
 }
 
 type ExperienceDto struct {
-	Experience string    `json:"experience"`
-	CreatedAt  time.Time `json:"-"`
-	UpdatedAt  time.Time `json:"-"`
+	Company        string     `json:"company" validate:"trim,required,max=120,charset=line"`
+	Title          string     `json:"title" validate:"trim,required,max=120,charset=line"`
+	EmploymentType string     `json:"employmentType,omitempty" validate:"trim,oneof=full-time part-time contract freelance internship temporary volunteer"`
+	Location       string     `json:"location,omitempty" validate:"trim,max=120,charset=line"`
+	Remote         bool       `json:"remote"`
+	StartDate      time.Time  `json:"startDate" validate:"required"`
+	EndDate        *time.Time `json:"endDate,omitempty" validate:"gtefield=StartDate"`
+	Summary        string     `json:"summary,omitempty" validate:"trim,max=2000,charset=text"`
+	Achievements   []string   `json:"achievements,omitempty" validate:"max=30,dive,trim,required,max=500,charset=text"`
+	Skills         []string   `json:"skills,omitempty" validate:"max=50,dive,trim,required,max=50,charset=line"`
+
+	// Experience is accepted from clients still sending the version 1
+	// payload and is used as Summary when no summary is given.
+	Experience string `json:"experience,omitempty" validate:"trim,max=2000,charset=text"`
 }
 
+// ApplyTo copies the client-editable fields onto e, replacing their previous
+// values.
+func (d *ExperienceDto) ApplyTo(e *Experience) {
+	e.Company = d.Company
+	e.Title = d.Title
+	e.EmploymentType = d.EmploymentType
+	e.Location = d.Location
+	e.Remote = d.Remote
+	e.StartDate = d.StartDate
+	e.EndDate = nil
+	if d.EndDate != nil {
+		end := *d.EndDate
+		e.EndDate = &end
+	}
+	e.Summary = d.Summary
+	if e.Summary == "" {
+		e.Summary = d.Experience
+	}
+	e.Achievements = append([]string(nil), d.Achievements...)
+	e.Skills = append([]string(nil), d.Skills...)
+	e.Experience = ""
+	e.SchemaVersion = SchemaVersion
+}
 
+// PatchDocument returns the client-editable fields of e, the document PATCH
+// requests are applied to. Every member is present, even when empty, so
+// patches can replace or test any of them.
+func (e *Experience) PatchDocument() map[string]any {
+	achievements, skills := e.Achievements, e.Skills
+	if achievements == nil {
+		achievements = []string{}
+	}
+	if skills == nil {
+		skills = []string{}
+	}
+	return map[string]any{
+		"company":        e.Company,
+		"title":          e.Title,
+		"employmentType": e.EmploymentType,
+		"location":       e.Location,
+		"remote":         e.Remote,
+		"startDate":      e.StartDate,
+		"endDate":        e.EndDate,
+		"summary":        e.Summary,
+		"achievements":   achievements,
+		"skills":         skills,
+	}
+}
+
+// ExperienceView is the representation of an Experience returned by the
+// API.
+type ExperienceView struct {
+	ID             string     `json:"id"`
+	Company        string     `json:"company"`
+	Title          string     `json:"title"`
+	EmploymentType string     `json:"employmentType,omitempty"`
+	Location       string     `json:"location,omitempty"`
+	Remote         bool       `json:"remote"`
+	StartDate      time.Time  `json:"startDate"`
+	EndDate        *time.Time `json:"endDate,omitempty"`
+	Summary        string     `json:"summary,omitempty"`
+	Achievements   []string   `json:"achievements"`
+	Skills         []string   `json:"skills"`
+	Version        int64      `json:"version"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      *time.Time `json:"updatedAt,omitempty"`
+	DeletedAt      *time.Time `json:"deletedAt,omitempty"`
+	OwnerID        string     `json:"ownerId,omitempty"`
+}
+
+func NewExperienceView(e *Experience) ExperienceView {
+	view := ExperienceView{
+		ID:             e.ID.Hex(),
+		Company:        e.Company,
+		Title:          e.Title,
+		EmploymentType: e.EmploymentType,
+		Location:       e.Location,
+		Remote:         e.Remote,
+		StartDate:      e.StartDate,
+		EndDate:        e.EndDate,
+		Summary:        e.Summary,
+		Achievements:   e.Achievements,
+		Skills:         e.Skills,
+		Version:        e.Version,
+		CreatedAt:      e.CreatedAt,
+		DeletedAt:      e.DeletedAt,
+		OwnerID:        e.OwnerID,
+	}
+	if view.Achievements == nil {
+		view.Achievements = []string{}
+	}
+	if view.Skills == nil {
+		view.Skills = []string{}
+	}
+	if !e.UpdatedAt.IsZero() {
+		updated := e.UpdatedAt
+		view.UpdatedAt = &updated
+	}
+	return view
+}
+
+func NewExperienceViews(experiences []*Experience) []ExperienceView {
+	views := make([]ExperienceView, len(experiences))
+	for i, e := range experiences {
+		views[i] = NewExperienceView(e)
+	}
+	return views
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// IdempotencyRecord is the outcome of a request sent with an Idempotency-Key,
+// kept so that a retry of the request gets the same response instead of
+// repeating its effect.
+type IdempotencyRecord struct {
+	Key string `bson:"_id"`
+	// Fingerprint identifies the request the key was first used with.
+	Fingerprint string `bson:"fingerprint"`
+	// Status is the response status, zero while the request is in progress.
+	Status int `bson:"status"`
+	// Header holds the response headers replayed with the body.
+	Header    map[string]string `bson:"header,omitempty"`
+	Body      []byte            `bson:"body,omitempty"`
+	ExpiresAt time.Time         `bson:"expiresAt"`
+}
+
+// Clone returns a deep copy of r.
+func (r *IdempotencyRecord) Clone() *IdempotencyRecord {
+	c := *r
+	if r.Header != nil {
+		c.Header = make(map[string]string, len(r.Header))
+		for k, v := range r.Header {
+			c.Header[k] = v
+		}
+	}
+	c.Body = append([]byte(nil), r.Body...)
+	return &c
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Revision actions.
+const (
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionPatch    = "patch"
+	ActionDelete   = "delete"
+	ActionUndelete = "undelete"
+	ActionRestore  = "restore"
+)
+
+// Revision is an immutable record of a single write to an experience.
+type Revision struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	ExperienceID primitive.ObjectID `bson:"experienceId"`
+	// Number is the version of the experience the write produced, so the
+	// revisions of an experience are numbered like its versions.
+	Number int64     `bson:"number"`
+	Action string    `bson:"action"`
+	Actor  string    `bson:"actor"`
+	At     time.Time `bson:"at"`
+	// RestoredFrom is the revision a restore copied.
+	RestoredFrom int64 `bson:"restoredFrom,omitempty"`
+	// Changes lists the fields the write changed.
+	Changes []Change `bson:"changes"`
+	// Snapshot is the experience after the write; nil for deletes.
+	Snapshot *Experience `bson:"snapshot,omitempty"`
+}
+
+// Change is an edited field with its JSON encoded values before and after.
+type Change struct {
+	Field string `bson:"field"`
+	From  string `bson:"from"`
+	To    string `bson:"to"`
+}
+
+// NewExperienceDto returns the client-editable fields of e, the payload that
+// would recreate it.
+func NewExperienceDto(e *Experience) *ExperienceDto {
+	dto := &ExperienceDto{
+		Company:        e.Company,
+		Title:          e.Title,
+		EmploymentType: e.EmploymentType,
+		Location:       e.Location,
+		Remote:         e.Remote,
+		StartDate:      e.StartDate,
+		Summary:        e.Summary,
+		Achievements:   append([]string(nil), e.Achievements...),
+		Skills:         append([]string(nil), e.Skills...),
+	}
+	if e.EndDate != nil {
+		end := *e.EndDate
+		dto.EndDate = &end
+	}
+	return dto
+}
+
+// RevisionView is the representation of a Revision returned by the API.
+type RevisionView struct {
+	Number       int64           `json:"number"`
+	Action       string          `json:"action"`
+	Actor        string          `json:"actor"`
+	At           time.Time       `json:"at"`
+	RestoredFrom int64           `json:"restoredFrom,omitempty"`
+	Changes      []ChangeView    `json:"changes"`
+	Snapshot     *ExperienceView `json:"snapshot,omitempty"`
+}
+
+type ChangeView struct {
+	Field string          `json:"field"`
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// NewRevisionView returns the view of r, with its snapshot when
+// withSnapshot is set.
+func NewRevisionView(r *Revision, withSnapshot bool) RevisionView {
+	view := RevisionView{
+		Number:       r.Number,
+		Action:       r.Action,
+		Actor:        r.Actor,
+		At:           r.At,
+		RestoredFrom: r.RestoredFrom,
+		Changes:      NewChangeViews(r.Changes),
+	}
+	if withSnapshot && r.Snapshot != nil {
+		snapshot := NewExperienceView(r.Snapshot)
+		view.Snapshot = &snapshot
+	}
+	return view
+}
+
+func NewChangeViews(changes []Change) []ChangeView {
+	views := make([]ChangeView, len(changes))
+	for i, c := range changes {
+		views[i] = ChangeView{Field: c.Field, From: json.RawMessage(c.From), To: json.RawMessage(c.To)}
+	}
+	return views
+}
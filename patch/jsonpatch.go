@@ -0,0 +1,327 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// operation is a single JSON Patch operation, RFC 6902 section 4.
+type operation struct {
+	Op, Path, From string
+
+	path, from []string
+	value      any
+}
+
+type jsonPatch []operation
+
+// ParseJSONPatch parses a JSON Patch: an array of operations, each checked
+// for a known op, well-formed pointers and the members it requires.
+func ParseJSONPatch(body []byte) (Patch, error) {
+	var members []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, &Error{Kind: ErrInvalid, Op: -1, Message: "JSON patch must be an array of operations"}
+	}
+
+	ops := make([]operation, len(members))
+	for i, m := range members {
+		op := &ops[i]
+		fail := func(format string, args ...any) error {
+			return &Error{Kind: ErrInvalid, Op: i, Path: op.Path, Message: fmt.Sprintf(format, args...)}
+		}
+		for _, member := range []struct {
+			name   string
+			target *string
+		}{{"op", &op.Op}, {"path", &op.Path}, {"from", &op.From}} {
+			if raw, ok := m[member.name]; ok {
+				if err := json.Unmarshal(raw, member.target); err != nil {
+					return nil, fail("%s must be a string", member.name)
+				}
+			}
+		}
+		if _, ok := m["path"]; !ok {
+			return nil, fail("path is required")
+		}
+
+		var err error
+		if op.path, err = parsePointer(op.Path); err != nil {
+			return nil, fail("path: %v", err)
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			raw, ok := m["value"]
+			if !ok {
+				return nil, fail("%s requires a value", op.Op)
+			}
+			if op.value, err = decode(raw); err != nil {
+				return nil, fail("value is not valid JSON")
+			}
+		case "move", "copy":
+			if _, ok := m["from"]; !ok {
+				return nil, fail("%s requires from", op.Op)
+			}
+			if op.from, err = parsePointer(op.From); err != nil {
+				return nil, fail("from: %v", err)
+			}
+			if op.Op == "move" && op.From != op.Path && strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fail("cannot move a value into itself")
+			}
+		case "remove":
+		case "":
+			return nil, fail("op is required")
+		default:
+			return nil, fail("unknown op %q", op.Op)
+		}
+	}
+	return jsonPatch(ops), nil
+}
+
+func (p jsonPatch) Apply(doc []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		if target, err = op.apply(target); err != nil {
+			kind := ErrPath
+			var pe *pathError
+			if errors.As(err, &pe) {
+				kind = pe.kind
+			}
+			return nil, &Error{Kind: kind, Op: i, Path: op.Path, Message: err.Error()}
+		}
+	}
+	return json.Marshal(target)
+}
+
+// pathError is an operation failure before the index of the operation is
+// known.
+type pathError struct {
+	kind    error
+	message string
+}
+
+func (e *pathError) Error() string { return e.message }
+
+func pathErr(format string, args ...any) error {
+	return &pathError{kind: ErrPath, message: fmt.Sprintf(format, args...)}
+}
+
+func (op operation) apply(doc any) (any, error) {
+	switch op.Op {
+	case "add":
+		return add(doc, op.path, deepCopy(op.value))
+	case "remove":
+		_, doc, err := remove(doc, op.path)
+		return doc, err
+	case "replace":
+		if _, err := get(doc, op.path); err != nil {
+			return nil, err
+		}
+		if len(op.path) == 0 {
+			return deepCopy(op.value), nil
+		}
+		_, doc, err := remove(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, deepCopy(op.value))
+	case "move":
+		value, doc, err := remove(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, value)
+	case "copy":
+		value, err := get(doc, op.from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.path, deepCopy(value))
+	case "test":
+		value, err := get(doc, op.path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(value, op.value) {
+			return nil, &pathError{kind: ErrTestFailed, message: "value does not match"}
+		}
+		return doc, nil
+	}
+	return nil, pathErr("unknown op %q", op.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into unescaped reference
+// tokens. The empty pointer refers to the whole document.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("%q must be empty or start with /", p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		for j := 0; j < len(t); j++ {
+			if t[j] == '~' && (j+1 == len(t) || (t[j+1] != '0' && t[j+1] != '1')) {
+				return nil, fmt.Errorf("%q has an invalid ~ escape", p)
+			}
+		}
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+// index parses an array index token. With end set, "-" and len(arr) are
+// accepted to address the position after the last element.
+func index(token string, length int, end bool) (int, error) {
+	if end && token == "-" {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.TrimLeft(token, "0123456789") != "" {
+		return 0, pathErr("%q is not an array index", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > length || (!end && i == length) {
+		return 0, pathErr("index %s is out of range", token)
+	}
+	return i, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, pathErr("member %q does not exist", token)
+			}
+			doc = value
+		case []any:
+			i, err := index(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, pathErr("cannot descend into a scalar at %q", token)
+		}
+	}
+	return doc, nil
+}
+
+// update rebuilds doc with leaf applied to the container holding the last
+// token of path.
+func update(doc any, path []string, leaf func(container any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return leaf(doc, path[0])
+	}
+	child, err := get(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	child, err = update(child, path[1:], leaf)
+	if err != nil {
+		return nil, err
+	}
+	switch node := doc.(type) {
+	case map[string]any:
+		node[path[0]] = child
+	case []any:
+		i, _ := index(path[0], len(node), false)
+		node[i] = child
+	}
+	return doc, nil
+}
+
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return update(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			node[token] = value
+			return node, nil
+		case []any:
+			i, err := index(token, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, pathErr("cannot add to a scalar at %q", token)
+	})
+}
+
+func remove(doc any, path []string) (removed, result any, err error) {
+	if len(path) == 0 {
+		return nil, nil, pathErr("cannot remove the whole document")
+	}
+	result, err = update(doc, path, func(container any, token string) (any, error) {
+		switch node := container.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, pathErr("member %q does not exist", token)
+			}
+			removed = value
+			delete(node, token)
+			return node, nil
+		case []any:
+			i, err := index(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, pathErr("cannot remove from a scalar at %q", token)
+	})
+	return removed, result, err
+}
+
+// equal compares JSON values as RFC 6902 section 4.6 requires: numbers by
+// value and objects regardless of member order.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		return errA == nil && errB == nil && x == y
+	}
+	return a == b
+}
@@ -0,0 +1,131 @@
+// Package patch applies partial updates to JSON documents in the two
+// standard formats:
+//
+//	application/merge-patch+json  JSON Merge Patch, RFC 7396
+//	application/json-patch+json   JSON Patch, RFC 6902
+//
+// Patches are parsed up front, so a malformed patch is rejected before any
+// document is loaded, and applied to a copy, so a failing patch leaves the
+// original untouched.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+// Patch is a parsed patch document.
+type Patch interface {
+	// Apply returns doc, a JSON document, with the patch applied.
+	Apply(doc []byte) ([]byte, error)
+}
+
+var (
+	// ErrInvalid reports a patch document that is not well formed.
+	ErrInvalid = errors.New("invalid patch")
+	// ErrPath reports an operation whose path does not fit the document,
+	// e.g. replacing a member that does not exist.
+	ErrPath = errors.New("patch path cannot be applied")
+	// ErrTestFailed reports a JSON Patch test operation that did not hold.
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// Error describes why a patch could not be parsed or applied. Kind is one of
+// the sentinels above.
+type Error struct {
+	Kind error
+	// Op is the index of the failing JSON Patch operation, or -1.
+	Op      int
+	Path    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Op < 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("operation %d (%s): %s", e.Op, e.Path, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Kind }
+
+// decode parses JSON keeping numbers as written.
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+type mergePatch struct {
+	value any
+}
+
+// ParseMerge parses a JSON Merge Patch. Any JSON value is a valid merge
+// patch; anything but an object replaces the whole document.
+func ParseMerge(body []byte) (Patch, error) {
+	v, err := decode(body)
+	if err != nil {
+		return nil, &Error{Kind: ErrInvalid, Op: -1, Message: "merge patch is not valid JSON"}
+	}
+	return mergePatch{value: v}, nil
+}
+
+func (p mergePatch) Apply(doc []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, deepCopy(p.value)))
+}
+
+// merge is the MergePatch function of RFC 7396, section 2.
+func merge(target, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	result, ok := target.(map[string]any)
+	if !ok {
+		result = map[string]any{}
+	}
+	for name, value := range members {
+		if value == nil {
+			delete(result, name)
+		} else {
+			result[name] = merge(result[name], value)
+		}
+	}
+	return result
+}
+
+func deepCopy(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			out[k] = deepCopy(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = deepCopy(item)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,121 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMerge runs the examples of RFC 7396, appendix A.
+func TestMerge(t *testing.T) {
+	tests := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		p, err := ParseMerge([]byte(tt.patch))
+		require.NoError(t, err)
+		got, err := p.Apply([]byte(tt.doc))
+		require.NoError(t, err)
+		require.JSONEq(t, tt.want, string(got), "%s + %s", tt.doc, tt.patch)
+	}
+
+	_, err := ParseMerge([]byte(`{"a":`))
+	require.ErrorIs(t, err, ErrInvalid)
+}
+
+// TestJSONPatch runs examples of RFC 6902, appendix A.
+func TestJSONPatch(t *testing.T) {
+	tests := []struct{ doc, patch, want string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"foo":"bar","baz":"qux"}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"foo":{"bar":1}}`, `[{"op":"copy","from":"/foo","path":"/baz"},{"op":"replace","path":"/baz/bar","value":2}]`, `{"foo":{"bar":1},"baz":{"bar":2}}`},
+		{`{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+		{`{"a":1}`, `[{"op":"add","path":"/b","value":null}]`, `{"a":1,"b":null}`},
+	}
+	for _, tt := range tests {
+		p, err := ParseJSONPatch([]byte(tt.patch))
+		require.NoError(t, err, tt.patch)
+		got, err := p.Apply([]byte(tt.doc))
+		require.NoError(t, err, tt.patch)
+		require.JSONEq(t, tt.want, string(got), tt.patch)
+	}
+}
+
+func TestJSONPatchInvalid(t *testing.T) {
+	for _, patch := range []string{
+		`{"op":"add"}`,
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"jump","path":"/a"}]`,
+		`[{"path":"/a"}]`,
+		`[{"op":"remove"}]`,
+		`[{"op":"remove","path":"a"}]`,
+		`[{"op":"remove","path":"/a~2"}]`,
+		`[{"op":"move","path":"/a"}]`,
+		`[{"op":"move","from":"/a","path":"/a/b"}]`,
+		`[{"op":1,"path":"/a"}]`,
+	} {
+		_, err := ParseJSONPatch([]byte(patch))
+		require.ErrorIs(t, err, ErrInvalid, patch)
+	}
+}
+
+func TestJSONPatchFailures(t *testing.T) {
+	tests := []struct {
+		doc, patch string
+		kind       error
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, ErrPath},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"qux"}]`, ErrPath},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, ErrPath},
+		{`{"foo":["a"]}`, `[{"op":"add","path":"/foo/2","value":"b"}]`, ErrPath},
+		{`{"foo":["a"]}`, `[{"op":"add","path":"/foo/01","value":"b"}]`, ErrPath},
+		{`{"foo":["a"]}`, `[{"op":"remove","path":"/foo/-"}]`, ErrPath},
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, ErrTestFailed},
+		{`{"baz":{"a":1}}`, `[{"op":"test","path":"/baz","value":{"a":1,"b":2}}]`, ErrTestFailed},
+	}
+	for _, tt := range tests {
+		p, err := ParseJSONPatch([]byte(tt.patch))
+		require.NoError(t, err, tt.patch)
+		_, err = p.Apply([]byte(tt.doc))
+		require.ErrorIs(t, err, tt.kind, tt.patch)
+
+		var perr *Error
+		require.ErrorAs(t, err, &perr)
+		require.Equal(t, 0, perr.Op)
+	}
+}
+
+func TestJSONPatchIsAllOrNothing(t *testing.T) {
+	doc := []byte(`{"a":[1,2]}`)
+	p, err := ParseJSONPatch([]byte(`[{"op":"remove","path":"/a/0"},{"op":"test","path":"/a/0","value":1}]`))
+	require.NoError(t, err)
+
+	_, err = p.Apply(doc)
+	require.ErrorContains(t, err, "operation 1 (/a/0)")
+	require.JSONEq(t, `{"a":[1,2]}`, string(doc))
+}
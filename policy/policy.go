@@ -0,0 +1,312 @@
+// Package policy decides what an authenticated principal may do, from roles
+// granting permissions and the permissions granted to the principal itself.
+//
+// A permission is written "<resource>:<action>[:<owner>]". Experiences can be
+// read, created, updated and deleted, and API keys managed; * stands for
+// every action of the resource. The owner applies to experiences only: own,
+// the default, for the principal's own experiences, or any for those of
+// every owner. For example:
+//
+//	roles:
+//	  admin: ["experiences:*:any", "apikeys:manage"]
+//	  editor: ["experiences:read:any", "experiences:create", "experiences:update"]
+//	  viewer: ["experiences:read"]
+//	defaultRoles: [viewer]
+//
+// Decisions are made by Policy.Decide and need nothing but a principal.
+package policy
+
+import (
+	"GO-Project/auth"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action is something done with experiences.
+type Action string
+
+const (
+	ActionRead   Action = "experiences:read"
+	ActionCreate Action = "experiences:create"
+	ActionUpdate Action = "experiences:update"
+	ActionDelete Action = "experiences:delete"
+	// ActionManageKeys creates, lists, rotates and revokes API keys.
+	ActionManageKeys Action = "apikeys:manage"
+)
+
+// Actions lists every action, in the order decisions are explained.
+var Actions = []Action{ActionRead, ActionCreate, ActionUpdate, ActionDelete, ActionManageKeys}
+
+// Resource returns the resource acted on, the part before the colon.
+func (a Action) Resource() string {
+	resource, _, _ := strings.Cut(string(a), ":")
+	return resource
+}
+
+// Owned reports whether the action applies to experiences, which have an
+// owner.
+func (a Action) Owned() bool {
+	return a.Resource() == resourceExperiences
+}
+
+// Owner rules of a permission.
+const (
+	OwnerOwn = "own"
+	OwnerAny = "any"
+)
+
+// RoleAdmin is the role of the default policy acting on every experience.
+const RoleAdmin = "admin"
+
+const (
+	resourceExperiences = "experiences"
+	resourceAPIKeys     = "apikeys"
+)
+
+// Permission is a parsed permission.
+type Permission struct {
+	Resource string
+	// Action is the action granted, empty for every action of Resource.
+	Action Action
+	// Owner is empty for resources without an owner.
+	Owner string
+}
+
+func (p Permission) String() string {
+	action := string(p.Action)
+	if action == "" {
+		action = p.Resource + ":*"
+	}
+	if p.Owner == "" {
+		return action
+	}
+	return action + ":" + p.Owner
+}
+
+func (p Permission) grants(action Action) bool {
+	return p.Action == action || p.Action == "" && p.Resource == action.Resource()
+}
+
+// ParsePermission parses a permission such as "experiences:update:own".
+func ParsePermission(s string) (Permission, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Permission{}, fmt.Errorf("%q is not of the form <resource>:<action>[:<owner>]", s)
+	}
+	p := Permission{Resource: parts[0]}
+	if p.Resource != resourceExperiences && p.Resource != resourceAPIKeys {
+		return Permission{}, fmt.Errorf("%q: unknown resource %q", s, parts[0])
+	}
+	if parts[1] != "*" {
+		p.Action = Action(p.Resource + ":" + parts[1])
+		if !slices.Contains(Actions, p.Action) {
+			return Permission{}, fmt.Errorf("%q: unknown action %q", s, parts[1])
+		}
+	}
+	if p.Resource != resourceExperiences {
+		if len(parts) == 3 {
+			return Permission{}, fmt.Errorf("%q: %s have no owner", s, p.Resource)
+		}
+		return p, nil
+	}
+	p.Owner = OwnerOwn
+	if len(parts) == 3 {
+		if parts[2] != OwnerOwn && parts[2] != OwnerAny {
+			return Permission{}, fmt.Errorf("%q: owner must be own or any", s)
+		}
+		p.Owner = parts[2]
+	}
+	return p, nil
+}
+
+// Policy maps roles to the permissions they grant.
+type Policy struct {
+	roles map[string][]Permission
+	// defaults are held by every principal on top of its own roles.
+	defaults []string
+}
+
+// Definition is the configuration a Policy is built from.
+type Definition struct {
+	Roles        map[string][]string `yaml:"roles" json:"roles"`
+	DefaultRoles []string            `yaml:"defaultRoles" json:"defaultRoles"`
+}
+
+// Default is the policy used when none is configured: admins may do
+// everything with every experience and manage API keys, everyone else may
+// do everything with their own experiences.
+func Default() *Policy {
+	p, err := New(Definition{
+		Roles: map[string][]string{
+			RoleAdmin: {"experiences:*:any", "apikeys:*"},
+			"member":  {"experiences:*:own"},
+		},
+		DefaultRoles: []string{"member"},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// New builds a policy, checking every permission and that default roles are
+// defined.
+func New(def Definition) (*Policy, error) {
+	var problems []string
+	p := &Policy{roles: map[string][]Permission{}}
+	for role, permissions := range def.Roles {
+		p.roles[role] = []Permission{}
+		for _, s := range permissions {
+			permission, err := ParsePermission(s)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("roles.%s: %v", role, err))
+				continue
+			}
+			p.roles[role] = append(p.roles[role], permission)
+		}
+	}
+	for _, role := range def.DefaultRoles {
+		if _, ok := p.roles[role]; !ok {
+			problems = append(problems, fmt.Sprintf("defaultRoles: role %q is not defined", role))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, errors.New("invalid policy: " + strings.Join(problems, "; "))
+	}
+	p.defaults = slices.Clone(def.DefaultRoles)
+	return p, nil
+}
+
+// Load reads a policy Definition from a YAML or JSON file.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var def Definition
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	p, err := New(def)
+	if err != nil {
+		return nil, fmt.Errorf("policy %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Decision is the outcome of Decide, with what led to it.
+type Decision struct {
+	Action  Action
+	Allowed bool
+	// AnyOwner allows the action on the experiences of every owner rather
+	// than the principal's own only.
+	AnyOwner bool
+	// Roles are the defined roles the principal holds.
+	Roles []string
+	// Role and Permission are the grant that allowed the action, the
+	// broadest one if several do. Role is empty for a permission of the
+	// principal itself.
+	Role       string
+	Permission string
+	// Reason explains the decision.
+	Reason string
+}
+
+// Decide returns whether principal may perform action.
+func (p *Policy) Decide(principal *auth.Principal, action Action) Decision {
+	d := Decision{Action: action, Roles: p.rolesOf(principal)}
+	if principal == nil {
+		d.Reason = "request is not authenticated"
+		return d
+	}
+	consider := func(role string, permission Permission) {
+		if !permission.grants(action) || d.Allowed && (d.AnyOwner || permission.Owner != OwnerAny) {
+			return
+		}
+		d.Allowed, d.AnyOwner = true, permission.Owner == OwnerAny
+		d.Role, d.Permission = role, permission.String()
+	}
+	for _, role := range d.Roles {
+		for _, permission := range p.roles[role] {
+			consider(role, permission)
+		}
+	}
+	// Permissions of the principal were checked when they were granted;
+	// the ones no longer understood grant nothing.
+	for _, s := range principal.Permissions {
+		if permission, err := ParsePermission(s); err == nil {
+			consider("", permission)
+		}
+	}
+
+	grantor := "role " + d.Role
+	if d.Role == "" {
+		grantor = "permission " + d.Permission + " of the principal"
+	}
+	switch {
+	case !d.Allowed && len(d.Roles) == 0 && len(principal.Permissions) == 0:
+		d.Reason = "principal holds no role of the policy"
+	case !d.Allowed && len(d.Roles) == 0:
+		d.Reason = fmt.Sprintf("no permission of the principal grants %s", action)
+	case !d.Allowed:
+		d.Reason = fmt.Sprintf("no role of %s grants %s", strings.Join(d.Roles, ", "), action)
+	case !action.Owned():
+		d.Reason = fmt.Sprintf("%s grants %s", grantor, action)
+	case d.AnyOwner:
+		d.Reason = fmt.Sprintf("%s grants %s on every experience", grantor, action)
+	default:
+		d.Reason = fmt.Sprintf("%s grants %s on the principal's own experiences", grantor, action)
+	}
+	return d
+}
+
+// Delegable reports whether principal may hand permission on, as to an API
+// key: it must be allowed every action the permission grants, on the
+// experiences of every owner if the permission is. Otherwise the reason is
+// returned.
+func (p *Policy) Delegable(principal *auth.Principal, permission Permission) (bool, string) {
+	for _, action := range Actions {
+		if !permission.grants(action) {
+			continue
+		}
+		d := p.Decide(principal, action)
+		switch {
+		case !d.Allowed:
+			return false, d.Reason
+		case permission.Owner == OwnerAny && !d.AnyOwner:
+			return false, fmt.Sprintf("%s is only granted on the principal's own experiences", action)
+		}
+	}
+	return true, ""
+}
+
+// rolesOf returns the defined roles of principal, its own and the default
+// ones, sorted. Principals with permissions of their own, such as API keys,
+// are limited to those and get no default role.
+func (p *Policy) rolesOf(principal *auth.Principal) []string {
+	roles := []string{}
+	if principal == nil {
+		return roles
+	}
+	held := slices.Clone(principal.Roles)
+	if len(principal.Permissions) == 0 {
+		held = append(held, p.defaults...)
+	}
+	for _, role := range held {
+		if _, ok := p.roles[role]; ok && !slices.Contains(roles, role) {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
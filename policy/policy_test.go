@@ -0,0 +1,181 @@
+package policy
+
+import (
+	"GO-Project/auth"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePermission(t *testing.T) {
+	p, err := ParsePermission("experiences:update")
+	require.NoError(t, err)
+	require.Equal(t, Permission{Resource: "experiences", Action: ActionUpdate, Owner: OwnerOwn}, p)
+	require.Equal(t, "experiences:update:own", p.String())
+
+	p, err = ParsePermission("experiences:*:any")
+	require.NoError(t, err)
+	require.Equal(t, Permission{Resource: "experiences", Owner: OwnerAny}, p)
+	require.Equal(t, "experiences:*:any", p.String())
+
+	p, err = ParsePermission("apikeys:manage")
+	require.NoError(t, err)
+	require.Equal(t, Permission{Resource: "apikeys", Action: ActionManageKeys}, p)
+	require.Equal(t, "apikeys:manage", p.String())
+	require.True(t, p.grants(ActionManageKeys))
+	require.False(t, p.grants(ActionRead))
+
+	for _, s := range []string{"", "experiences", "users:read", "experiences:publish", "experiences:read:some", "experiences:read:own:x", "apikeys:manage:any", "apikeys:read"} {
+		_, err := ParsePermission(s)
+		require.Error(t, err, s)
+	}
+}
+
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+	p, err := New(Definition{
+		Roles: map[string][]string{
+			"editor": {"experiences:read:any", "experiences:create", "experiences:update"},
+			"viewer": {"experiences:read"},
+		},
+		DefaultRoles: []string{"viewer"},
+	})
+	require.NoError(t, err)
+	return p
+}
+
+func TestDecide(t *testing.T) {
+	p := testPolicy(t)
+	viewer := &auth.Principal{Subject: "alice"}
+	editor := &auth.Principal{Subject: "bob", Roles: []string{"editor", "unknown"}}
+
+	d := p.Decide(viewer, ActionRead)
+	require.True(t, d.Allowed)
+	require.False(t, d.AnyOwner)
+	require.Equal(t, []string{"viewer"}, d.Roles)
+	require.Equal(t, "viewer", d.Role)
+	require.Equal(t, "experiences:read:own", d.Permission)
+
+	d = p.Decide(viewer, ActionUpdate)
+	require.False(t, d.Allowed)
+	require.Equal(t, "no role of viewer grants experiences:update", d.Reason)
+
+	// The broadest grant wins, whatever the order of the roles.
+	d = p.Decide(editor, ActionRead)
+	require.True(t, d.Allowed)
+	require.True(t, d.AnyOwner)
+	require.Equal(t, []string{"editor", "viewer"}, d.Roles)
+	require.Equal(t, "editor", d.Role)
+
+	d = p.Decide(editor, ActionUpdate)
+	require.True(t, d.Allowed)
+	require.False(t, d.AnyOwner)
+	require.False(t, p.Decide(editor, ActionDelete).Allowed)
+
+	d = p.Decide(nil, ActionRead)
+	require.False(t, d.Allowed)
+	require.Equal(t, "request is not authenticated", d.Reason)
+
+	noDefaults, err := New(Definition{Roles: map[string][]string{"editor": {"experiences:*"}}})
+	require.NoError(t, err)
+	d = noDefaults.Decide(viewer, ActionRead)
+	require.False(t, d.Allowed)
+	require.Equal(t, "principal holds no role of the policy", d.Reason)
+
+	// Permissions of the principal are granted on top of its roles.
+	key := &auth.Principal{Subject: "ci", Permissions: []string{"experiences:create", "experiences:read:any", "bogus"}}
+	d = noDefaults.Decide(key, ActionRead)
+	require.True(t, d.Allowed)
+	require.True(t, d.AnyOwner)
+	require.Empty(t, d.Role)
+	require.Equal(t, "experiences:read:any", d.Permission)
+	require.Equal(t, "permission experiences:read:any of the principal grants experiences:read on every experience", d.Reason)
+	d = noDefaults.Decide(key, ActionDelete)
+	require.False(t, d.Allowed)
+	require.Equal(t, "no permission of the principal grants experiences:delete", d.Reason)
+	// They get no default role, which would grant more than they are
+	// scoped to.
+	d = p.Decide(key, ActionUpdate)
+	require.False(t, d.Allowed)
+	require.Empty(t, d.Roles)
+	require.True(t, p.Decide(&auth.Principal{Subject: "ci", Roles: []string{"editor"}, Permissions: []string{"experiences:delete"}}, ActionUpdate).Allowed)
+}
+
+func TestDefault(t *testing.T) {
+	p := Default()
+	for _, action := range Actions {
+		d := p.Decide(&auth.Principal{Subject: "alice"}, action)
+		require.Equal(t, action.Owned(), d.Allowed, action)
+		require.False(t, d.AnyOwner, action)
+
+		d = p.Decide(&auth.Principal{Subject: "root", Roles: []string{RoleAdmin}}, action)
+		require.True(t, d.Allowed, action)
+		require.Equal(t, action.Owned(), d.AnyOwner, action)
+	}
+	d := p.Decide(&auth.Principal{Subject: "root", Roles: []string{RoleAdmin}}, ActionManageKeys)
+	require.Equal(t, "role admin grants apikeys:manage", d.Reason)
+}
+
+func TestDelegable(t *testing.T) {
+	p := testPolicy(t)
+	editor := &auth.Principal{Subject: "bob", Roles: []string{"editor"}}
+	delegable := func(principal *auth.Principal, s string) (bool, string) {
+		permission, err := ParsePermission(s)
+		require.NoError(t, err)
+		return p.Delegable(principal, permission)
+	}
+
+	ok, _ := delegable(editor, "experiences:read:any")
+	require.True(t, ok)
+	ok, _ = delegable(editor, "experiences:update")
+	require.True(t, ok)
+	ok, reason := delegable(editor, "experiences:update:any")
+	require.False(t, ok)
+	require.Equal(t, "experiences:update is only granted on the principal's own experiences", reason)
+	ok, reason = delegable(editor, "experiences:*")
+	require.False(t, ok)
+	require.Equal(t, "no role of editor, viewer grants experiences:delete", reason)
+	ok, _ = delegable(editor, "apikeys:manage")
+	require.False(t, ok)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Definition{
+		Roles:        map[string][]string{"editor": {"experiences:publish", "experiences:read:all"}},
+		DefaultRoles: []string{"viewer"},
+	})
+	require.ErrorContains(t, err, `defaultRoles: role "viewer" is not defined`)
+	require.ErrorContains(t, err, `roles.editor: "experiences:publish": unknown action "publish"`)
+	require.ErrorContains(t, err, `roles.editor: "experiences:read:all": owner must be own or any`)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	p, err := Load(write("policy.yaml", `
+roles:
+  admin: ["experiences:*:any"]
+  viewer:
+    - experiences:read
+defaultRoles: [viewer]
+`))
+	require.NoError(t, err)
+	require.True(t, p.Decide(&auth.Principal{Subject: "a"}, ActionRead).Allowed)
+	require.False(t, p.Decide(&auth.Principal{Subject: "a"}, ActionCreate).Allowed)
+
+	p, err = Load(write("policy.json", `{"roles": {"writer": ["experiences:create"]}}`))
+	require.NoError(t, err)
+	require.True(t, p.Decide(&auth.Principal{Subject: "a", Roles: []string{"writer"}}, ActionCreate).Allowed)
+
+	_, err = Load(write("typo.yaml", "role:\n  admin: []\n"))
+	require.ErrorContains(t, err, "field role not found")
+	_, err = Load(filepath.Join(dir, "missing.yaml"))
+	require.Error(t, err)
+}
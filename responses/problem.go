@@ -0,0 +1,27 @@
+package responses
+
+// ProblemContentType is the media type of RFC 7807 problem details.
+const ProblemContentType = "application/problem+json"
+
+// Error response formats selectable per request (Accept header) or as the
+// server default.
+const (
+	FormatEnvelope = "envelope"
+	FormatProblem  = "problem"
+)
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Errors   []ProblemField `json:"errors,omitempty"`
+}
+
+// ProblemField describes why a single request field was rejected.
+type ProblemField struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"GO-Project/auth"
+	"GO-Project/models"
+	"GO-Project/policy"
+	"GO-Project/validation"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HeaderAPIKey is the request header API keys are sent in.
+const HeaderAPIKey = "X-API-Key"
+
+// apiKeyPrefix starts every key, which reads "exk_<id>_<secret>". The ID
+// finds the key without searching by hash; only the secret is secret.
+const apiKeyPrefix = "exk_"
+
+// touchInterval is how stale the last-used time of a key may get before a
+// request using it records a new one, so that busy keys do not write on
+// every request.
+const touchInterval = time.Minute
+
+// APIKeyService manages the API keys of machine clients and authenticates
+// the requests made with them.
+type APIKeyService interface {
+	// Authenticate reads the key from the X-API-Key header. The principal
+	// of a valid key has the key's subject and permissions and no role.
+	auth.Authenticator
+	// Create returns the new key along with the full key, which is not
+	// stored and cannot be retrieved again. The principal creating it must
+	// hold every permission it grants, and act on every owner's
+	// experiences to choose its subject.
+	Create(ctx context.Context, dto *models.APIKeyDto) (*models.APIKey, string, error)
+	List(ctx context.Context) ([]*models.APIKey, error)
+	Get(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error)
+	// Rotate replaces the secret of a key, returning the new full key. The
+	// previous one stops working at once. Like Revoke, it is refused to
+	// principals that could not have created the key.
+	Rotate(ctx context.Context, id primitive.ObjectID) (*models.APIKey, string, error)
+	// Revoke disables a key for good. Revoking a revoked key changes
+	// nothing.
+	Revoke(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error)
+}
+
+type apiKeyService struct {
+	store APIKeyStore
+	// access limits the keys a principal may create to what it may do
+	// itself. Without it, as with authentication disabled, any key can be
+	// created.
+	access *policy.Policy
+	now    func() time.Time
+}
+
+// NewAPIKeyService returns an APIKeyService keeping keys in store and
+// checking the keys created against access, which may be nil.
+func NewAPIKeyService(store APIKeyStore, access *policy.Policy) APIKeyService {
+	return &apiKeyService{store: store, access: access, now: time.Now}
+}
+
+func (s *apiKeyService) Create(ctx context.Context, dto *models.APIKeyDto) (*models.APIKey, string, error) {
+	now := s.now()
+	if err := NewValidationError(validateAPIKey(dto, now)); err != nil {
+		return nil, "", err
+	}
+	if err := s.checkGrant(ctx, dto); err != nil {
+		return nil, "", err
+	}
+
+	k := &models.APIKey{
+		ID:          primitive.NewObjectIDFromTimestamp(now),
+		Name:        dto.Name,
+		Subject:     dto.Subject,
+		Permissions: append([]string(nil), dto.Permissions...),
+		CreatedBy:   ownerOf(ctx),
+		CreatedAt:   now,
+	}
+	if k.Subject == "" {
+		k.Subject = defaultSubject(k.ID)
+	}
+	if dto.ExpiresAt != nil {
+		expires := *dto.ExpiresAt
+		k.ExpiresAt = &expires
+	}
+	key, err := newSecret(k)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.store.Insert(ctx, k); err != nil {
+		return nil, "", err
+	}
+	return k, key, nil
+}
+
+// validateAPIKey checks dto, including that its permissions are understood
+// by the policy and that it does not expire in the past.
+func validateAPIKey(dto *models.APIKeyDto, now time.Time) validation.Errors {
+	errs := validation.Struct(dto)
+	if len(errs) > 0 {
+		return errs
+	}
+	for i, s := range dto.Permissions {
+		if _, err := policy.ParsePermission(s); err != nil {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("permissions[%d]", i), Rule: "permission", Message: err.Error()})
+		}
+	}
+	if dto.ExpiresAt != nil && !dto.ExpiresAt.After(now) {
+		errs = append(errs, FieldError{Field: "expiresAt", Rule: "future", Message: "must be in the future"})
+	}
+	return errs
+}
+
+// checkGrant refuses keys granting more than the principal of ctx may do,
+// so that managing keys does not lead to any other permission. Choosing the
+// subject acts as that principal, which only those acting on every owner's
+// experiences may do.
+func (s *apiKeyService) checkGrant(ctx context.Context, dto *models.APIKeyDto) error {
+	if what, reason, ok := s.delegable(ctx, dto.Permissions, dto.Subject != ""); !ok {
+		return newError(ErrForbidden, nil, "forbidden: cannot %s: %s", what, reason)
+	}
+	return nil
+}
+
+// checkManage refuses rotating or revoking k, as verb says, to principals
+// that could not have created it: the new secret would hand them its
+// permissions, and revoking would cut off a client stronger than them.
+func (s *apiKeyService) checkManage(ctx context.Context, verb string, k *models.APIKey) error {
+	if what, reason, ok := s.delegable(ctx, k.Permissions, k.Subject != defaultSubject(k.ID)); !ok {
+		return newError(ErrForbidden, nil, "forbidden: cannot %s api key %s: cannot %s: %s", verb, k.ID.Hex(), what, reason)
+	}
+	return nil
+}
+
+// delegable reports whether the principal of ctx may hand out permissions,
+// and a subject of its choosing if subject is set. If not, it returns what
+// it may not do and why.
+func (s *apiKeyService) delegable(ctx context.Context, permissions []string, subject bool) (string, string, bool) {
+	principal, ok := auth.FromContext(ctx)
+	if s.access == nil || !ok {
+		return "", "", true
+	}
+	for _, raw := range permissions {
+		permission, err := policy.ParsePermission(raw)
+		if err != nil {
+			return "grant " + raw, err.Error(), false
+		}
+		if ok, reason := s.access.Delegable(principal, permission); !ok {
+			return "grant " + raw, reason, false
+		}
+	}
+	if !subject {
+		return "", "", true
+	}
+	all, _ := policy.ParsePermission("experiences:*:any")
+	if ok, reason := s.access.Delegable(principal, all); !ok {
+		return "set the subject", reason, false
+	}
+	return "", "", true
+}
+
+// defaultSubject is the subject of keys created without one.
+func defaultSubject(id primitive.ObjectID) string {
+	return "apikey:" + id.Hex()
+}
+
+// newSecret gives k a new random secret, storing its hash, and returns the
+// full key.
+func newSecret(k *models.APIKey) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	k.Hash = hashSecret(encoded)
+	return apiKeyPrefix + k.ID.Hex() + "_" + encoded, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *apiKeyService) List(ctx context.Context) ([]*models.APIKey, error) {
+	return s.store.List(ctx)
+}
+
+func (s *apiKeyService) Get(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error) {
+	return s.store.Get(ctx, id)
+}
+
+func (s *apiKeyService) Rotate(ctx context.Context, id primitive.ObjectID) (*models.APIKey, string, error) {
+	k, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.checkManage(ctx, "rotate", k); err != nil {
+		return nil, "", err
+	}
+	if k.RevokedAt != nil {
+		return nil, "", newError(ErrConflict, nil, "api key %s is revoked", id.Hex())
+	}
+
+	// The store only rotates the secret read here, so a key revoked or
+	// rotated meanwhile is not brought back.
+	hash := k.Hash
+	key, err := newSecret(k)
+	if err != nil {
+		return nil, "", err
+	}
+	rotated, err := s.store.Rotate(ctx, id, hash, k.Hash, s.now())
+	if err != nil {
+		return nil, "", err
+	}
+	return rotated, key, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error) {
+	k, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkManage(ctx, "revoke", k); err != nil {
+		return nil, err
+	}
+	return s.store.Revoke(ctx, id, s.now())
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, header func(name string) string) (*auth.Principal, error) {
+	key := header(HeaderAPIKey)
+	if key == "" {
+		return nil, auth.ErrNoCredentials
+	}
+
+	id, secret, ok := parseAPIKey(key)
+	if !ok {
+		return nil, invalidAPIKey("malformed key")
+	}
+	k, err := s.store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, invalidAPIKey("unknown key")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(k.Hash)) != 1 {
+		return nil, invalidAPIKey("unknown key")
+	}
+
+	now := s.now()
+	switch {
+	case k.RevokedAt != nil:
+		return nil, invalidAPIKey("key is revoked")
+	case !k.Active(now):
+		return nil, invalidAPIKey("key is expired")
+	}
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= touchInterval {
+		// A failure to record the use must not fail the request.
+		if err := s.store.Touch(ctx, k.ID, now); err != nil {
+			slog.Warn("record api key use", slog.String("key", k.ID.Hex()), slog.Any("err", err))
+		}
+	}
+
+	return &auth.Principal{Subject: k.Subject, Permissions: k.Permissions}, nil
+}
+
+// parseAPIKey splits a key into the ID and the secret.
+func parseAPIKey(key string) (primitive.ObjectID, string, bool) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	if !ok {
+		return primitive.NilObjectID, "", false
+	}
+	hexID, secret, ok := strings.Cut(rest, "_")
+	id, err := primitive.ObjectIDFromHex(hexID)
+	if !ok || err != nil || secret == "" {
+		return primitive.NilObjectID, "", false
+	}
+	return id, secret, true
+}
+
+func invalidAPIKey(reason string) error {
+	return fmt.Errorf("%w: %s", auth.ErrInvalidCredentials, reason)
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"GO-Project/auth"
+	"GO-Project/models"
+	"GO-Project/policy"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "root"})
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryAPIKeyStore()
+	s := &apiKeyService{store: store, now: func() time.Time { return now }}
+	header := func(key string) func(string) string {
+		return func(name string) string {
+			if name == HeaderAPIKey {
+				return key
+			}
+			return ""
+		}
+	}
+
+	expires := now.Add(24 * time.Hour)
+	k, key, err := s.Create(ctx, &models.APIKeyDto{Name: " ci ", Permissions: []string{"experiences:read:any", "experiences:create"}, ExpiresAt: &expires})
+	require.NoError(t, err)
+	require.Equal(t, "ci", k.Name)
+	require.Equal(t, "apikey:"+k.ID.Hex(), k.Subject)
+	require.Equal(t, "root", k.CreatedBy)
+	require.True(t, strings.HasPrefix(key, "exk_"+k.ID.Hex()+"_"))
+	require.NotContains(t, k.Hash, key[len("exk_")+25:], "the secret is not stored")
+
+	p, err := s.Authenticate(context.Background(), header(key))
+	require.NoError(t, err)
+	require.Equal(t, &auth.Principal{Subject: k.Subject, Permissions: []string{"experiences:read:any", "experiences:create"}}, p)
+	stored, err := s.Get(ctx, k.ID)
+	require.NoError(t, err)
+	require.Equal(t, now, *stored.LastUsedAt)
+
+	// The last-used time is only refreshed once it is a minute old.
+	now = now.Add(30 * time.Second)
+	_, err = s.Authenticate(context.Background(), header(key))
+	require.NoError(t, err)
+	stored, _ = s.Get(ctx, k.ID)
+	require.Equal(t, now.Add(-30*time.Second), *stored.LastUsedAt)
+	now = now.Add(time.Minute)
+	_, err = s.Authenticate(context.Background(), header(key))
+	require.NoError(t, err)
+	stored, _ = s.Get(ctx, k.ID)
+	require.Equal(t, now, *stored.LastUsedAt)
+
+	_, err = s.Authenticate(context.Background(), header(""))
+	require.ErrorIs(t, err, auth.ErrNoCredentials)
+	for _, bad := range []string{"garbage", "exk_" + k.ID.Hex(), "exk_nothex_secret", key + "x"} {
+		_, err = s.Authenticate(context.Background(), header(bad))
+		require.ErrorIs(t, err, auth.ErrInvalidCredentials, bad)
+	}
+
+	rotated, newKey, err := s.Rotate(ctx, k.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, key, newKey)
+	require.Equal(t, now, *rotated.RotatedAt)
+	_, err = s.Authenticate(context.Background(), header(key))
+	require.ErrorContains(t, err, "unknown key")
+	_, err = s.Authenticate(context.Background(), header(newKey))
+	require.NoError(t, err)
+
+	now = expires
+	_, err = s.Authenticate(context.Background(), header(newKey))
+	require.ErrorContains(t, err, "key is expired")
+
+	revoked, err := s.Revoke(ctx, k.ID)
+	require.NoError(t, err)
+	require.Equal(t, now, *revoked.RevokedAt)
+	again, err := s.Revoke(ctx, k.ID)
+	require.NoError(t, err)
+	require.Equal(t, revoked.RevokedAt, again.RevokedAt)
+	_, err = s.Authenticate(context.Background(), header(newKey))
+	require.ErrorContains(t, err, "key is revoked")
+	_, _, err = s.Rotate(ctx, k.ID)
+	require.ErrorIs(t, err, ErrConflict)
+
+	keys, err := s.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 1, "revoked keys are kept")
+}
+
+func TestAPIKeyServiceValidation(t *testing.T) {
+	s := NewAPIKeyService(NewMemoryAPIKeyStore(), nil)
+	past := time.Now().Add(-time.Hour)
+
+	_, _, err := s.Create(context.Background(), &models.APIKeyDto{Name: "ci", Permissions: []string{"experiences:read", "experiences:publish"}, ExpiresAt: &past})
+	require.ErrorIs(t, err, ErrValidation)
+	var verr *Error
+	require.ErrorAs(t, err, &verr)
+	fields := []string{}
+	for _, f := range verr.Fields {
+		fields = append(fields, f.Field)
+	}
+	require.Equal(t, []string{"permissions[1]", "expiresAt"}, fields)
+
+	_, _, err = s.Create(context.Background(), &models.APIKeyDto{Name: "ci"})
+	require.ErrorIs(t, err, ErrValidation)
+}
+
+func TestAPIKeyServiceGrant(t *testing.T) {
+	access, err := policy.New(policy.Definition{Roles: map[string][]string{
+		"keymgr": {"apikeys:manage"},
+		"editor": {"apikeys:manage", "experiences:read:any", "experiences:update"},
+		"admin":  {"apikeys:*", "experiences:*:any"},
+	}})
+	require.NoError(t, err)
+	s := NewAPIKeyService(NewMemoryAPIKeyStore(), access)
+	as := func(roles ...string) context.Context {
+		return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "p", Roles: roles})
+	}
+
+	// Managing keys grants nothing else.
+	_, _, err = s.Create(as("keymgr"), &models.APIKeyDto{Name: "ci", Subject: "alice", Permissions: []string{"experiences:*:any"}})
+	require.ErrorIs(t, err, ErrForbidden)
+	_, _, err = s.Create(as("keymgr"), &models.APIKeyDto{Name: "ci", Permissions: []string{"experiences:read"}})
+	require.ErrorIs(t, err, ErrForbidden)
+	require.ErrorContains(t, err, "cannot grant experiences:read")
+	_, _, err = s.Create(as("keymgr"), &models.APIKeyDto{Name: "ci", Permissions: []string{"apikeys:manage"}})
+	require.NoError(t, err)
+
+	// Keys are limited to the actions and owner scope of their creator.
+	_, _, err = s.Create(as("editor"), &models.APIKeyDto{Name: "ci", Permissions: []string{"experiences:read:any", "experiences:update"}})
+	require.NoError(t, err)
+	_, _, err = s.Create(as("editor"), &models.APIKeyDto{Name: "ci", Permissions: []string{"experiences:update:any"}})
+	require.ErrorContains(t, err, "experiences:update is only granted on the principal's own experiences")
+	_, _, err = s.Create(as("editor"), &models.APIKeyDto{Name: "ci", Permissions: []string{"experiences:*"}})
+	require.ErrorIs(t, err, ErrForbidden)
+
+	// Only principals acting on every owner's experiences choose the subject.
+	_, _, err = s.Create(as("editor"), &models.APIKeyDto{Name: "ci", Subject: "alice", Permissions: []string{"experiences:read"}})
+	require.ErrorContains(t, err, "cannot set the subject")
+	k, _, err := s.Create(as("admin"), &models.APIKeyDto{Name: "ci", Subject: "alice", Permissions: []string{"experiences:*:any"}})
+	require.NoError(t, err)
+	require.Equal(t, "alice", k.Subject)
+}
+
+func TestAPIKeyServiceManage(t *testing.T) {
+	access, err := policy.New(policy.Definition{Roles: map[string][]string{
+		"keymgr": {"apikeys:manage"},
+		"reader": {"apikeys:manage", "experiences:*:any"},
+		"admin":  {"apikeys:*", "experiences:*:any"},
+	}})
+	require.NoError(t, err)
+	s := NewAPIKeyService(NewMemoryAPIKeyStore(), access)
+	as := func(roles ...string) context.Context {
+		return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "p", Roles: roles})
+	}
+	strong, key, err := s.Create(as("admin"), &models.APIKeyDto{Name: "deploy", Permissions: []string{"experiences:*:any"}})
+	require.NoError(t, err)
+	weak, _, err := s.Create(as("admin"), &models.APIKeyDto{Name: "keys", Permissions: []string{"apikeys:manage"}})
+	require.NoError(t, err)
+
+	// A manager holding less than a key cannot take it over or cut it off.
+	_, _, err = s.Rotate(as("keymgr"), strong.ID)
+	require.ErrorIs(t, err, ErrForbidden)
+	require.ErrorContains(t, err, "cannot rotate api key "+strong.ID.Hex()+": cannot grant experiences:*:any")
+	_, err = s.Revoke(as("keymgr"), strong.ID)
+	require.ErrorIs(t, err, ErrForbidden)
+	_, err = s.Authenticate(context.Background(), func(string) string { return key })
+	require.NoError(t, err, "the key still works")
+
+	_, _, err = s.Rotate(as("keymgr"), weak.ID)
+	require.NoError(t, err)
+
+	// Keys with a chosen subject act as it, like any principal acting on
+	// every owner's experiences.
+	alice, _, err := s.Create(as("admin"), &models.APIKeyDto{Name: "alice", Subject: "alice", Permissions: []string{"apikeys:manage"}})
+	require.NoError(t, err)
+	_, _, err = s.Rotate(as("keymgr"), alice.ID)
+	require.ErrorContains(t, err, "cannot set the subject")
+	_, _, err = s.Rotate(as("reader"), alice.ID)
+	require.NoError(t, err)
+	_, err = s.Revoke(as("reader"), strong.ID)
+	require.NoError(t, err)
+}
+
+// revokingStore revokes a key right after it is read, as a concurrent
+// request would.
+type revokingStore struct {
+	APIKeyStore
+}
+
+func (s revokingStore) Get(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error) {
+	k, err := s.APIKeyStore.Get(ctx, id)
+	if err == nil {
+		_, err = s.APIKeyStore.Revoke(ctx, id, time.Now())
+	}
+	return k, err
+}
+
+func TestAPIKeyServiceRotateRevoked(t *testing.T) {
+	store := NewMemoryAPIKeyStore()
+	s := NewAPIKeyService(store, nil)
+	k, key, err := s.Create(context.Background(), &models.APIKeyDto{Name: "ci", Permissions: []string{"experiences:read"}})
+	require.NoError(t, err)
+
+	racing := NewAPIKeyService(revokingStore{store}, nil)
+	_, _, err = racing.Rotate(context.Background(), k.ID)
+	require.ErrorIs(t, err, ErrConflict)
+
+	stored, err := store.Get(context.Background(), k.ID)
+	require.NoError(t, err)
+	require.NotNil(t, stored.RevokedAt, "the revocation stands")
+	_, err = s.Authenticate(context.Background(), func(string) string { return key })
+	require.ErrorIs(t, err, auth.ErrInvalidCredentials)
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"GO-Project/models"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyStore keeps API keys, revoked ones included.
+type APIKeyStore interface {
+	Insert(ctx context.Context, k *models.APIKey) error
+	// Get fails with ErrNotFound for a key that does not exist.
+	Get(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error)
+	// List returns every key, oldest first.
+	List(ctx context.Context) ([]*models.APIKey, error)
+	// Rotate sets the hash of a key still holding hash and not revoked,
+	// failing with ErrConflict if it changed in between and ErrNotFound if
+	// there is no such key. It returns the updated key.
+	Rotate(ctx context.Context, id primitive.ObjectID, hash, newHash string, at time.Time) (*models.APIKey, error)
+	// Revoke sets the revocation time of a key not revoked yet, and
+	// returns the key, revoked at that or an earlier time. It fails with
+	// ErrNotFound if there is no such key.
+	Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.APIKey, error)
+	// Touch records that a key was used at. Unknown keys are ignored.
+	Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+func apiKeyNotFound(id primitive.ObjectID) error {
+	return newError(ErrNotFound, nil, "api key %s not found", id.Hex())
+}
+
+// apiKeyChanged reports a rotation of a key that was rotated or revoked
+// concurrently.
+func apiKeyChanged(id primitive.ObjectID) error {
+	return newError(ErrConflict, nil, "api key %s was rotated or revoked concurrently", id.Hex())
+}
+
+// memoryAPIKeyStore keeps keys in process memory, handing a snapshot to
+// persist after every write like the memory backend does.
+type memoryAPIKeyStore struct {
+	mu      sync.Mutex
+	keys    map[primitive.ObjectID]*models.APIKey
+	persist func(keys []models.APIKey) error
+}
+
+// NewMemoryAPIKeyStore returns an APIKeyStore that is lost when the process
+// exits.
+func NewMemoryAPIKeyStore() APIKeyStore {
+	return newMemoryAPIKeyStore(nil, nil)
+}
+
+func newMemoryAPIKeyStore(keys []models.APIKey, persist func([]models.APIKey) error) *memoryAPIKeyStore {
+	s := &memoryAPIKeyStore{keys: make(map[primitive.ObjectID]*models.APIKey, len(keys)), persist: persist}
+	for _, k := range keys {
+		s.keys[k.ID] = k.Clone()
+	}
+	return s
+}
+
+func (s *memoryAPIKeyStore) Insert(ctx context.Context, k *models.APIKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.keys[k.ID]; ok {
+		return newError(ErrConflict, nil, "api key %s already exists", k.ID.Hex())
+	}
+	s.keys[k.ID] = k.Clone()
+	if err := s.save(); err != nil {
+		delete(s.keys, k.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *memoryAPIKeyStore) Get(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[id]
+	if !ok {
+		return nil, apiKeyNotFound(id)
+	}
+	return k.Clone(), nil
+}
+
+func (s *memoryAPIKeyStore) List(ctx context.Context) ([]*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]*models.APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k.Clone())
+	}
+	sortAPIKeys(keys)
+	return keys, nil
+}
+
+func (s *memoryAPIKeyStore) Rotate(ctx context.Context, id primitive.ObjectID, hash, newHash string, at time.Time) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.keys[id]
+	switch {
+	case !ok:
+		return nil, apiKeyNotFound(id)
+	case previous.Hash != hash || previous.RevokedAt != nil:
+		return nil, apiKeyChanged(id)
+	}
+	k := previous.Clone()
+	k.Hash, k.RotatedAt = newHash, &at
+	s.keys[id] = k
+	if err := s.save(); err != nil {
+		s.keys[id] = previous
+		return nil, err
+	}
+	return k.Clone(), nil
+}
+
+func (s *memoryAPIKeyStore) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.keys[id]
+	switch {
+	case !ok:
+		return nil, apiKeyNotFound(id)
+	case previous.RevokedAt != nil:
+		return previous.Clone(), nil
+	}
+	k := previous.Clone()
+	k.RevokedAt = &at
+	s.keys[id] = k
+	if err := s.save(); err != nil {
+		s.keys[id] = previous
+		return nil, err
+	}
+	return k.Clone(), nil
+}
+
+func (s *memoryAPIKeyStore) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[id]
+	if !ok {
+		return nil
+	}
+	previous := k.LastUsedAt
+	k.LastUsedAt = &at
+	if err := s.save(); err != nil {
+		k.LastUsedAt = previous
+		return err
+	}
+	return nil
+}
+
+// save hands the keys, oldest first, to the persistence hook. Callers must
+// hold s.mu.
+func (s *memoryAPIKeyStore) save() error {
+	if s.persist == nil {
+		return nil
+	}
+	keys := make([]*models.APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k)
+	}
+	sortAPIKeys(keys)
+	snapshot := make([]models.APIKey, len(keys))
+	for i, k := range keys {
+		snapshot[i] = *k
+	}
+	return s.persist(snapshot)
+}
+
+// sortAPIKeys orders keys by ID, which is by creation time.
+func sortAPIKeys(keys []*models.APIKey) {
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID.Hex() < keys[j].ID.Hex() })
+}
+
+// apiKeyDocument is the on-disk layout of the file API key store.
+type apiKeyDocument struct {
+	Keys []models.APIKey `bson:"keys"`
+}
+
+// NewFileAPIKeyStore returns an APIKeyStore backed by a relaxed extended
+// JSON file at path, rewritten atomically on every write.
+func NewFileAPIKeyStore(path string) (APIKeyStore, error) {
+	keys, err := readAPIKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newMemoryAPIKeyStore(keys, func(keys []models.APIKey) error {
+		data, err := bson.MarshalExtJSON(apiKeyDocument{Keys: keys}, false, false)
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, data)
+	}), nil
+}
+
+func readAPIKeyFile(path string) ([]models.APIKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc apiKeyDocument
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return doc.Keys, nil
+}
+
+type mongoAPIKeyStore struct {
+	coll *mongo.Collection
+}
+
+// NewMongoAPIKeyStore returns an APIKeyStore keeping keys in the given
+// collection. Keys are only looked up by _id, so it needs no index.
+func NewMongoAPIKeyStore(client *mongo.Client, database, collection string) APIKeyStore {
+	return &mongoAPIKeyStore{coll: client.Database(database).Collection(collection)}
+}
+
+func (s *mongoAPIKeyStore) Insert(ctx context.Context, k *models.APIKey) error {
+	_, err := s.coll.InsertOne(ctx, k)
+	if mongo.IsDuplicateKeyError(err) {
+		return newError(ErrConflict, err, "api key %s already exists", k.ID.Hex())
+	}
+	return wrapMongoError(err, k.ID)
+}
+
+func (s *mongoAPIKeyStore) Get(ctx context.Context, id primitive.ObjectID) (*models.APIKey, error) {
+	var k models.APIKey
+	err := s.coll.FindOne(ctx, bson.M{"_id": id}).Decode(&k)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, apiKeyNotFound(id)
+	}
+	if err != nil {
+		return nil, wrapMongoError(err, id)
+	}
+	return &k, nil
+}
+
+func (s *mongoAPIKeyStore) List(ctx context.Context) ([]*models.APIKey, error) {
+	cursor, err := s.coll.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+	keys := []*models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+	return keys, nil
+}
+
+func (s *mongoAPIKeyStore) Rotate(ctx context.Context, id primitive.ObjectID, hash, newHash string, at time.Time) (*models.APIKey, error) {
+	var k models.APIKey
+	err := s.coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "hash": hash, "revokedAt": nil},
+		bson.M{"$set": bson.M{"hash": newHash, "rotatedAt": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&k)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if _, err := s.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, apiKeyChanged(id)
+	}
+	if err != nil {
+		return nil, wrapMongoError(err, id)
+	}
+	return &k, nil
+}
+
+func (s *mongoAPIKeyStore) Revoke(ctx context.Context, id primitive.ObjectID, at time.Time) (*models.APIKey, error) {
+	var k models.APIKey
+	err := s.coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&k)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Missing, or revoked already.
+		return s.Get(ctx, id)
+	}
+	if err != nil {
+		return nil, wrapMongoError(err, id)
+	}
+	return &k, nil
+}
+
+func (s *mongoAPIKeyStore) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := s.coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastUsedAt": at}})
+	return wrapMongoError(err, id)
+}
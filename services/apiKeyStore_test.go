@@ -0,0 +1,151 @@
+package services
+
+import (
+	"GO-Project/models"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func testAPIKeyStore(t *testing.T, s APIKeyStore) (first, second *models.APIKey) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	first = &models.APIKey{ID: primitive.NewObjectIDFromTimestamp(now.Add(-time.Hour)), Name: "ci", Subject: "ci-bot", Permissions: []string{"experiences:read"}, Hash: "h1", CreatedAt: now}
+	second = &models.APIKey{ID: primitive.NewObjectIDFromTimestamp(now), Name: "deploy", Subject: "deploy-bot", Permissions: []string{"experiences:*"}, Hash: "h2", CreatedAt: now}
+
+	require.NoError(t, s.Insert(ctx, second))
+	require.NoError(t, s.Insert(ctx, first))
+	require.ErrorIs(t, s.Insert(ctx, first), ErrConflict)
+
+	keys, err := s.List(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []*models.APIKey{first, second}, keys)
+
+	// Stored keys do not change with the ones handed to the store.
+	first.Permissions[0] = "experiences:delete"
+	got, err := s.Get(ctx, first.ID)
+	require.NoError(t, err)
+	require.Equal(t, "experiences:read", got.Permissions[0])
+
+	require.NoError(t, s.Touch(ctx, first.ID, now))
+	got, err = s.Rotate(ctx, first.ID, "h1", "h3", now)
+	require.NoError(t, err)
+	require.Equal(t, "h3", got.Hash)
+	require.Equal(t, now, *got.RotatedAt)
+	require.Equal(t, now, *got.LastUsedAt, "rotating keeps the last use")
+	_, err = s.Rotate(ctx, first.ID, "h1", "h4", now)
+	require.ErrorIs(t, err, ErrConflict, "the hash read is stale")
+
+	got, err = s.Revoke(ctx, first.ID, now)
+	require.NoError(t, err)
+	require.Equal(t, now, *got.RevokedAt)
+	again, err := s.Revoke(ctx, first.ID, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, now, *again.RevokedAt, "revoking again keeps the first time")
+	_, err = s.Rotate(ctx, first.ID, "h3", "h4", now)
+	require.ErrorIs(t, err, ErrConflict, "revoked keys are not rotated")
+	got, err = s.Get(ctx, first.ID)
+	require.NoError(t, err)
+	require.Equal(t, "h3", got.Hash)
+
+	missing := primitive.NewObjectID()
+	_, err = s.Get(ctx, missing)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Rotate(ctx, missing, "h", "h", now)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = s.Revoke(ctx, missing, now)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, s.Touch(ctx, missing, now))
+
+	first.Permissions[0] = "experiences:read"
+	first.Hash, first.RotatedAt, first.RevokedAt, first.LastUsedAt = "h3", &now, &now, &now
+	return first, second
+}
+
+func TestMemoryAPIKeyStore(t *testing.T) {
+	testAPIKeyStore(t, NewMemoryAPIKeyStore())
+}
+
+func TestFileAPIKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiences.apikeys.json")
+	s, err := NewFileAPIKeyStore(path)
+	require.NoError(t, err)
+	first, second := testAPIKeyStore(t, s)
+
+	reopened, err := NewFileAPIKeyStore(path)
+	require.NoError(t, err)
+	keys, err := reopened.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Equal(t, first.ID, keys[0].ID)
+	require.NotNil(t, keys[0].RevokedAt)
+	require.Equal(t, second, keys[1])
+}
+
+func TestMongoAPIKeyStore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	id := primitive.NewObjectID()
+
+	mt.Run("Get", func(mt *mtest.T) {
+		s := NewMongoAPIKeyStore(mt.Client, "TODOLIST", "experience_apikeys")
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "TODOLIST.experience_apikeys", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: id},
+			{Key: "name", Value: "ci"},
+			{Key: "hash", Value: "h1"},
+		}))
+		k, err := s.Get(context.Background(), id)
+		require.NoError(t, err)
+		require.Equal(t, "ci", k.Name)
+		require.Equal(t, "h1", k.Hash)
+	})
+
+	mt.Run("Get, not found", func(mt *mtest.T) {
+		s := NewMongoAPIKeyStore(mt.Client, "TODOLIST", "experience_apikeys")
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "TODOLIST.experience_apikeys", mtest.FirstBatch))
+		_, err := s.Get(context.Background(), id)
+		require.ErrorIs(t, err, ErrNotFound)
+		require.ErrorContains(t, err, "api key")
+	})
+
+	mt.Run("Rotate, changed meanwhile", func(mt *mtest.T) {
+		s := NewMongoAPIKeyStore(mt.Client, "TODOLIST", "experience_apikeys")
+
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+			mtest.CreateCursorResponse(0, "TODOLIST.experience_apikeys", mtest.FirstBatch, bson.D{{Key: "_id", Value: id}, {Key: "hash", Value: "h2"}}),
+		)
+		_, err := s.Rotate(context.Background(), id, "h1", "h3", time.Now())
+		require.ErrorIs(t, err, ErrConflict)
+		query := mt.GetStartedEvent().Command.Lookup("query").Document()
+		require.Equal(t, "h1", query.Lookup("hash").StringValue())
+		require.Equal(t, bson.TypeNull, query.Lookup("revokedAt").Type)
+	})
+
+	mt.Run("Revoke, not found", func(mt *mtest.T) {
+		s := NewMongoAPIKeyStore(mt.Client, "TODOLIST", "experience_apikeys")
+
+		mt.AddMockResponses(
+			bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: nil}},
+			mtest.CreateCursorResponse(0, "TODOLIST.experience_apikeys", mtest.FirstBatch),
+		)
+		_, err := s.Revoke(context.Background(), id, time.Now())
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+
+	mt.Run("Touch", func(mt *mtest.T) {
+		s := NewMongoAPIKeyStore(mt.Client, "TODOLIST", "experience_apikeys")
+
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+		require.NoError(t, s.Touch(context.Background(), id, time.Now()))
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u").Document()
+		require.NotNil(t, update.Lookup("$set", "lastUsedAt").Value)
+	})
+}
@@ -0,0 +1,68 @@
+package services_test
+
+import (
+	"GO-Project/services"
+	"GO-Project/services/servicetest"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	servicetest.Run(t, func(t *testing.T) services.ExperienceService {
+		return services.NewMemoryExperienceService()
+	})
+}
+
+func TestFileConformance(t *testing.T) {
+	servicetest.Run(t, func(t *testing.T) services.ExperienceService {
+		s, err := services.NewFileExperienceService(filepath.Join(t.TempDir(), "experiences.json"))
+		require.NoError(t, err)
+		return s
+	})
+}
+
+// TestHistoryConformance checks that recording history does not change how
+// the wrapped backend behaves.
+func TestHistoryConformance(t *testing.T) {
+	servicetest.Run(t, func(t *testing.T) services.ExperienceService {
+		dir := t.TempDir()
+		s, err := services.NewFileExperienceService(filepath.Join(dir, "experiences.json"))
+		require.NoError(t, err)
+		store, err := services.NewFileHistoryStore(filepath.Join(dir, "experiences.history.jsonl"))
+		require.NoError(t, err)
+		return services.NewHistoryService(s, store)
+	})
+}
+
+// TestMongoConformance runs against a real server when MONGO_TEST_URI is
+// set. Each subtest gets its own collection, dropped afterwards.
+func TestMongoConformance(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	require.NoError(t, err)
+	require.NoError(t, client.Ping(ctx, nil))
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+
+	servicetest.Run(t, func(t *testing.T) services.ExperienceService {
+		collection := "conformance_" + primitive.NewObjectID().Hex()
+		t.Cleanup(func() {
+			_ = client.Database("servicetest").Collection(collection).Drop(context.Background())
+		})
+		return services.NewExperienceService(client, "servicetest", collection)
+	})
+}
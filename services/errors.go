@@ -0,0 +1,92 @@
+package services
+
+import (
+	"GO-Project/validation"
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Error categories returned by every ExperienceService implementation.
+// Callers test for them with errors.Is.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrValidation  = errors.New("validation failed")
+	ErrUnavailable = errors.New("service unavailable")
+	// ErrPreconditionFailed reports a write whose Precondition did not hold.
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrAborted reports an operation of an atomic batch that was not
+	// applied because another one failed.
+	ErrAborted = errors.New("aborted")
+	// ErrForbidden reports a request the principal is not allowed to make.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Error pairs an error category with a message that is safe to show to
+// clients. The underlying cause, typically a driver error, is kept for
+// logging and errors.Is/As but never exposed by Error's Message.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+
+	// Fields lists per-field violations for ErrValidation.
+	Fields []FieldError
+}
+
+// FieldError explains why a single input field was rejected.
+type FieldError = validation.FieldError
+
+// NewValidationError reports rejected input. It returns nil when errs is
+// empty so callers can return its result directly.
+func NewValidationError(errs validation.Errors) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &Error{Kind: ErrValidation, Message: "request validation failed", Fields: errs}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+func newError(kind error, cause error, format string, args ...any) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...), Err: cause}
+}
+
+func notFound(id primitive.ObjectID) error {
+	return newError(ErrNotFound, nil, "experience %s not found", id.Hex())
+}
+
+// wrapMongoError classifies a driver error. Errors that fit no category are
+// returned unchanged and end up as internal server errors.
+func wrapMongoError(err error, id primitive.ObjectID) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return newError(ErrNotFound, err, "experience %s not found", id.Hex())
+	case mongo.IsDuplicateKeyError(err):
+		return newError(ErrConflict, err, "experience already exists")
+	case errors.Is(err, context.Canceled):
+		return err
+	case mongo.IsTimeout(err), mongo.IsNetworkError(err):
+		return newError(ErrUnavailable, err, "storage is temporarily unavailable")
+	default:
+		return err
+	}
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestWrapMongoError(t *testing.T) {
+	id := primitive.NewObjectID()
+	other := errors.New("boom")
+
+	tests := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"no documents", mongo.ErrNoDocuments, ErrNotFound},
+		{"duplicate key", mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}}, ErrConflict},
+		{"deadline", fmt.Errorf("find: %w", context.DeadlineExceeded), ErrUnavailable},
+		{"canceled", context.Canceled, context.Canceled},
+		{"unknown", other, other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := wrapMongoError(tt.err, id)
+			require.ErrorIs(t, err, tt.kind)
+			require.ErrorContains(t, err, tt.err.Error(), "the cause must stay visible in logs")
+		})
+	}
+
+	require.NoError(t, wrapMongoError(nil, id))
+}
+
+func TestErrorMessageHidesCause(t *testing.T) {
+	err := wrapMongoError(mongo.ErrNoDocuments, primitive.NilObjectID)
+
+	var serviceErr *Error
+	require.ErrorAs(t, err, &serviceErr)
+	require.Equal(t, "experience 000000000000000000000000 not found", serviceErr.Message)
+	require.NotContains(t, serviceErr.Message, mongo.ErrNoDocuments.Error())
+}
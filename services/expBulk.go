@@ -0,0 +1,156 @@
+package services
+
+import (
+	"GO-Project/models"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of BulkOperation.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// MaxBulkOperations bounds the size of a batch.
+const MaxBulkOperations = 100
+
+// BulkOperation is one write of a batch passed to Bulk.
+type BulkOperation struct {
+	// Op is BulkCreate, BulkUpdate or BulkDelete.
+	Op string
+	// ID names the experience to update or delete.
+	ID primitive.ObjectID
+	// Experience is the payload of a create or the full replacement of an
+	// update.
+	Experience *models.ExperienceDto
+	// Cond guards an update or delete like it does Update and Delete.
+	Cond Precondition
+}
+
+// BulkResult is the outcome of the BulkOperation at the same index.
+type BulkResult struct {
+	// ID is the experience written, generated for a create.
+	ID primitive.ObjectID
+	// Experience is the state a create or update left, nil for a delete
+	// and when Err is set.
+	Experience *models.Experience
+	Err        error
+}
+
+// checkBulk rejects batches that cannot be run at all. Fields are named
+// after the members of the bulk request body.
+func checkBulk(ops []BulkOperation) error {
+	if len(ops) == 0 {
+		return NewValidationError([]FieldError{{Field: "operations", Rule: "required", Message: "is required"}})
+	}
+	if len(ops) > MaxBulkOperations {
+		return NewValidationError([]FieldError{{Field: "operations", Rule: "max", Message: fmt.Sprintf("must have at most %d items", MaxBulkOperations)}})
+	}
+
+	var errs []FieldError
+	seen := map[primitive.ObjectID]bool{}
+	for i, op := range ops {
+		field := fmt.Sprintf("operations[%d].", i)
+		switch op.Op {
+		case BulkCreate, BulkUpdate, BulkDelete:
+		default:
+			errs = append(errs, FieldError{Field: field + "op", Rule: "oneof", Message: "must be one of create, update, delete"})
+			continue
+		}
+		if op.Op != BulkDelete && op.Experience == nil {
+			errs = append(errs, FieldError{Field: field + "data", Rule: "required", Message: "is required"})
+		}
+		if op.Op == BulkCreate {
+			continue
+		}
+		switch {
+		case op.ID.IsZero():
+			errs = append(errs, FieldError{Field: field + "id", Rule: "required", Message: "is required"})
+		case seen[op.ID]:
+			// Writes to the same experience would depend on their order,
+			// which unordered batches do not keep.
+			errs = append(errs, FieldError{Field: field + "id", Rule: "unique", Message: "is already written by another operation"})
+		}
+		seen[op.ID] = true
+	}
+	return NewValidationError(errs)
+}
+
+// stageBulk computes the experience every operation writes, reading the
+// live experiences to change with current, which returns nil for missing
+// ones. Created experiences belong to owner. Operations that cannot be
+// applied get their error in results and a nil staged experience.
+func stageBulk(ops []BulkOperation, current func(id primitive.ObjectID) *models.Experience, owner string, now time.Time) (results []BulkResult, staged []*models.Experience) {
+	results = make([]BulkResult, len(ops))
+	staged = make([]*models.Experience, len(ops))
+	for i, op := range ops {
+		if op.Op == BulkCreate {
+			experience := &models.Experience{
+				ID:        primitive.NewObjectID(),
+				Version:   1,
+				CreatedAt: now,
+				OwnerID:   owner,
+			}
+			op.Experience.ApplyTo(experience)
+			results[i].ID, staged[i] = experience.ID, experience
+			continue
+		}
+
+		results[i].ID = op.ID
+		previous := current(op.ID)
+		if previous == nil {
+			results[i].Err = notFound(op.ID)
+			continue
+		}
+		if err := op.Cond.check(previous); err != nil {
+			results[i].Err = err
+			continue
+		}
+		experience := previous.Clone()
+		if op.Op == BulkUpdate {
+			op.Experience.ApplyTo(experience)
+			experience.UpdatedAt = now
+		} else {
+			experience.DeletedAt = &now
+		}
+		experience.Version++
+		staged[i] = experience
+	}
+	return results, staged
+}
+
+// bulkFailed reports whether any operation failed.
+func bulkFailed(results []BulkResult) bool {
+	for _, r := range results {
+		if r.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// abortBulk marks every operation that did not fail itself as aborted, as
+// an atomic batch applies none of them once one fails.
+func abortBulk(results []BulkResult) []BulkResult {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Experience = nil
+			results[i].Err = newError(ErrAborted, nil, "not applied because another operation of the batch failed")
+		}
+	}
+	return results
+}
+
+// finishBulk reports the staged experiences of the operations written.
+func finishBulk(ops []BulkOperation, results []BulkResult, staged []*models.Experience) []BulkResult {
+	for i := range results {
+		if results[i].Err == nil && ops[i].Op != BulkDelete {
+			results[i].Experience = staged[i].Clone()
+		}
+	}
+	return results
+}
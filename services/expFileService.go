@@ -0,0 +1,80 @@
+package services
+
+import (
+	"GO-Project/models"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fileDocument is the on-disk layout of the file backend. It is written as
+// relaxed extended JSON so the bson tags on the models stay the single
+// source of truth for field names.
+type fileDocument struct {
+	Experiences []models.Experience `bson:"experiences"`
+}
+
+// NewFileExperienceService returns a backend that keeps all experiences in a
+// single JSON file at path, created on first write. Every write rewrites the
+// file atomically, so it is meant for local development and tests rather
+// than large data sets.
+func NewFileExperienceService(path string) (ExperienceService, error) {
+	items, err := readExperienceFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newMemoryExperienceService(items, func(items []models.Experience) error {
+		return writeExperienceFile(path, items)
+	}), nil
+}
+
+func readExperienceFile(path string) ([]models.Experience, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc fileDocument
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return doc.Experiences, nil
+}
+
+func writeExperienceFile(path string, items []models.Experience) error {
+	data, err := bson.MarshalExtJSON(fileDocument{Experiences: items}, false, false)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces the file at path with data, so readers and a
+// crash mid-write see either the old or the new content.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"GO-Project/models"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileExperienceServicePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiences.json")
+
+	e, err := NewFileExperienceService(path)
+	require.NoError(t, err)
+
+	_, err = e.Create(context.Background(), &models.ExperienceDto{Company: "Acme", Title: "Hello", StartDate: time.Now()})
+	require.NoError(t, err)
+	_, err = e.Create(context.Background(), &models.ExperienceDto{Company: "Acme", Title: "World", StartDate: time.Now()})
+	require.NoError(t, err)
+
+	reopened, err := NewFileExperienceService(path)
+	require.NoError(t, err)
+
+	result, err := reopened.FindAll(context.Background(), ListQuery{})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 2)
+	require.Equal(t, "Hello", result.Items[0].Title)
+	require.Equal(t, "World", result.Items[1].Title)
+}
+
+func TestFileExperienceServiceUpgradesLegacyDocuments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiences.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"experiences": [{
+		"_id": {"$oid": "65b0c0ffee0000000000abcd"},
+		"experience": "Five years of Go",
+		"createdAt": {"$date": "2024-01-24T10:00:00Z"}
+	}]}`), 0o600))
+
+	e, err := NewFileExperienceService(path)
+	require.NoError(t, err)
+
+	result, err := e.FindAll(context.Background(), ListQuery{})
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	require.Equal(t, "Five years of Go", result.Items[0].Summary)
+	require.Empty(t, result.Items[0].Experience)
+}
+
+func TestFileExperienceServiceRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiences.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+	_, err := NewFileExperienceService(path)
+	require.Error(t, err)
+}
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"GO-Project/auth"
+	"GO-Project/models"
+	"GO-Project/patch"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HistoryService is an ExperienceService that records a revision of every
+// write and can list, compare and restore them. Recording is best effort: a
+// write is reported as applied even if its revision could not be stored,
+// which is logged, so that clients never retry a change already made.
+type HistoryService interface {
+	ExperienceService
+	// Revisions lists the revisions of an experience, oldest first. They
+	// stay available while the experience is in the trash and are removed
+	// with it when it is purged.
+	Revisions(ctx context.Context, id primitive.ObjectID) ([]models.Revision, error)
+	Revision(ctx context.Context, id primitive.ObjectID, number int64) (*models.Revision, error)
+	// Diff lists the fields that differ between the snapshots of two
+	// revisions.
+	Diff(ctx context.Context, id primitive.ObjectID, from, to int64) ([]models.Change, error)
+	// Restore replaces the experience with the snapshot of a revision,
+	// itself recorded as a new revision.
+	Restore(ctx context.Context, id primitive.ObjectID, number int64, cond Precondition) (*models.Experience, error)
+}
+
+// HistoryStore keeps revisions. Revisions are never changed once appended;
+// they are only removed, all at once, when their experience is purged.
+type HistoryStore interface {
+	// Append stores r, failing with ErrConflict if the experience already
+	// has a revision with its number.
+	Append(ctx context.Context, r *models.Revision) error
+	// List returns the revisions of an experience ordered by number.
+	List(ctx context.Context, experienceID primitive.ObjectID) ([]models.Revision, error)
+	Get(ctx context.Context, experienceID primitive.ObjectID, number int64) (*models.Revision, error)
+	// Delete removes every revision of the experiences.
+	Delete(ctx context.Context, experienceIDs ...primitive.ObjectID) error
+}
+
+type actorKey struct{}
+
+// WithActor returns a context attributing the writes made with it to actor
+// in the revision history.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorOf returns who is writing with ctx: the actor set with WithActor,
+// else the authenticated principal, else "anonymous".
+func actorOf(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.Subject
+	}
+	return "anonymous"
+}
+
+type historyService struct {
+	ExperienceService
+	store HistoryStore
+}
+
+// NewHistoryService records the writes made through svc in store. Any
+// backend can be wrapped; writes to the backend that bypass the returned
+// service are not recorded.
+func NewHistoryService(svc ExperienceService, store HistoryStore) HistoryService {
+	return &historyService{ExperienceService: svc, store: store}
+}
+
+func (h *historyService) Create(ctx context.Context, exp *models.ExperienceDto) (*models.Experience, error) {
+	created, err := h.ExperienceService.Create(ctx, exp)
+	if err != nil {
+		return nil, err
+	}
+	h.record(ctx, models.ActionCreate, nil, created, 0)
+	return created, nil
+}
+
+func (h *historyService) Update(ctx context.Context, id primitive.ObjectID, exp *models.ExperienceDto, cond Precondition) (*models.Experience, error) {
+	return h.write(ctx, id, cond, models.ActionUpdate, 0, func(pin Precondition) (*models.Experience, error) {
+		return h.ExperienceService.Update(ctx, id, exp, pin)
+	})
+}
+
+func (h *historyService) Patch(ctx context.Context, id primitive.ObjectID, p patch.Patch, cond Precondition) (*models.Experience, error) {
+	return h.write(ctx, id, cond, models.ActionPatch, 0, func(pin Precondition) (*models.Experience, error) {
+		return h.ExperienceService.Patch(ctx, id, p, pin)
+	})
+}
+
+func (h *historyService) Delete(ctx context.Context, id primitive.ObjectID, cond Precondition) error {
+	_, err := h.write(ctx, id, cond, models.ActionDelete, 0, func(pin Precondition) (*models.Experience, error) {
+		return nil, h.ExperienceService.Delete(ctx, id, pin)
+	})
+	return err
+}
+
+// Undelete is recorded like a create: from nothing to the restored state.
+func (h *historyService) Undelete(ctx context.Context, id primitive.ObjectID, cond Precondition) (*models.Experience, error) {
+	restored, err := h.ExperienceService.Undelete(ctx, id, cond)
+	if err != nil {
+		return nil, err
+	}
+	h.record(ctx, models.ActionUndelete, nil, restored, 0)
+	return restored, nil
+}
+
+// Purge removes the history along with the experience, so nothing of it is
+// kept.
+func (h *historyService) Purge(ctx context.Context, id primitive.ObjectID, cond Precondition) error {
+	if err := h.ExperienceService.Purge(ctx, id, cond); err != nil {
+		return err
+	}
+	return h.store.Delete(ctx, id)
+}
+
+func (h *historyService) PurgeDeleted(ctx context.Context, cutoff time.Time) ([]primitive.ObjectID, error) {
+	purged, err := h.ExperienceService.PurgeDeleted(ctx, cutoff)
+	if len(purged) > 0 {
+		err = errors.Join(err, h.store.Delete(ctx, purged...))
+	}
+	return purged, err
+}
+
+// Bulk pins every update and delete to the version it read, like write,
+// and records a revision of each operation applied. An operation that loses
+// a race is reported as a conflict rather than retried.
+func (h *historyService) Bulk(ctx context.Context, ops []BulkOperation, atomic bool) ([]BulkResult, error) {
+	befores := make([]*models.Experience, len(ops))
+	// failures holds the errors found while reading; their operations are
+	// pinned to no version so that the backend fails them as well.
+	failures := make([]error, len(ops))
+	pinned := slices.Clone(ops)
+	for i, op := range ops {
+		if op.Op != BulkUpdate && op.Op != BulkDelete || op.ID.IsZero() {
+			continue
+		}
+		before, err := h.ExperienceService.FindById(ctx, op.ID)
+		if err == nil {
+			err = op.Cond.check(before)
+		}
+		switch {
+		case err == nil:
+			befores[i] = before
+			pinned[i].Cond = Precondition{IfMatch: []int64{before.Version}}
+		case errors.Is(err, ErrNotFound), errors.Is(err, ErrPreconditionFailed):
+			failures[i] = err
+			pinned[i].Cond = Precondition{IfMatch: []int64{}}
+		default:
+			return nil, err
+		}
+	}
+
+	results, err := h.ExperienceService.Bulk(ctx, pinned, atomic)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range results {
+		switch {
+		case r.Err != nil:
+			if errors.Is(r.Err, ErrPreconditionFailed) && failures[i] != nil {
+				results[i].Err = failures[i]
+			} else if errors.Is(r.Err, ErrPreconditionFailed) && befores[i] != nil {
+				results[i].Err = modifiedConcurrently(r.ID)
+			}
+		case ops[i].Op == BulkCreate:
+			h.record(ctx, models.ActionCreate, nil, r.Experience, 0)
+		case ops[i].Op == BulkUpdate:
+			h.record(ctx, models.ActionUpdate, befores[i], r.Experience, 0)
+		case ops[i].Op == BulkDelete:
+			h.record(ctx, models.ActionDelete, befores[i], nil, 0)
+		}
+	}
+	return results, nil
+}
+
+// write reads the experience, checks cond and makes the write pinned to the
+// version read, so the state recorded as "before" is exactly the one the
+// write replaced. A write that loses a race is retried from the read.
+func (h *historyService) write(ctx context.Context, id primitive.ObjectID, cond Precondition, action string, restoredFrom int64, do func(pin Precondition) (*models.Experience, error)) (*models.Experience, error) {
+	for attempt := 1; ; attempt++ {
+		before, err := h.ExperienceService.FindById(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := cond.check(before); err != nil {
+			return nil, err
+		}
+
+		after, err := do(Precondition{IfMatch: []int64{before.Version}})
+		if errors.Is(err, ErrPreconditionFailed) {
+			if attempt == writeAttempts {
+				return nil, modifiedConcurrently(id)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		h.record(ctx, action, before, after, restoredFrom)
+		return after, nil
+	}
+}
+
+// record stores the revision of a write already made. Failing to must not
+// fail the write, so it is only logged.
+func (h *historyService) record(ctx context.Context, action string, before, after *models.Experience, restoredFrom int64) {
+	r := &models.Revision{
+		ID:           primitive.NewObjectID(),
+		Action:       action,
+		Actor:        actorOf(ctx),
+		At:           time.Now(),
+		RestoredFrom: restoredFrom,
+		Changes:      diff(before, after),
+	}
+	if after != nil {
+		r.ExperienceID, r.Number, r.Snapshot = after.ID, after.Version, after.Clone()
+	} else {
+		r.ExperienceID, r.Number = before.ID, before.Version+1
+	}
+	if err := h.store.Append(ctx, r); err != nil {
+		slog.Error("record revision",
+			slog.String("experience", r.ExperienceID.Hex()),
+			slog.Int64("number", r.Number),
+			slog.String("action", action),
+			slog.Any("err", err))
+	}
+}
+
+func (h *historyService) Revisions(ctx context.Context, id primitive.ObjectID) ([]models.Revision, error) {
+	return h.access(ctx, id)
+}
+
+// access returns the revisions of an experience in the scope of ctx and
+// fails with ErrNotFound for the others. The owner is read from the
+// history, which outlives the experience in the trash.
+func (h *historyService) access(ctx context.Context, id primitive.ObjectID) ([]models.Revision, error) {
+	revisions, err := h.store.List(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range revisions {
+		if r.Snapshot == nil {
+			continue
+		}
+		if !scopeOf(ctx).allows(r.Snapshot) {
+			return nil, notFound(id)
+		}
+		return revisions, nil
+	}
+	// Experiences written before history was kept have none yet.
+	if _, err := h.ExperienceService.FindById(ctx, id); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// checkAccess is access for the methods reading single revisions, which
+// admins skip.
+func (h *historyService) checkAccess(ctx context.Context, id primitive.ObjectID) error {
+	if !scopeOf(ctx).restricted {
+		return nil
+	}
+	_, err := h.access(ctx, id)
+	return err
+}
+
+func (h *historyService) Revision(ctx context.Context, id primitive.ObjectID, number int64) (*models.Revision, error) {
+	if err := h.checkAccess(ctx, id); err != nil {
+		return nil, err
+	}
+	return h.store.Get(ctx, id, number)
+}
+
+func (h *historyService) Diff(ctx context.Context, id primitive.ObjectID, from, to int64) ([]models.Change, error) {
+	if err := h.checkAccess(ctx, id); err != nil {
+		return nil, err
+	}
+	a, err := h.store.Get(ctx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := h.store.Get(ctx, id, to)
+	if err != nil {
+		return nil, err
+	}
+	return diff(a.Snapshot, b.Snapshot), nil
+}
+
+func (h *historyService) Restore(ctx context.Context, id primitive.ObjectID, number int64, cond Precondition) (*models.Experience, error) {
+	if err := h.checkAccess(ctx, id); err != nil {
+		return nil, err
+	}
+	r, err := h.store.Get(ctx, id, number)
+	if err != nil {
+		return nil, err
+	}
+	if r.Snapshot == nil {
+		return nil, newError(ErrValidation, nil, "revision %d deleted the experience and cannot be restored", number)
+	}
+	exp := models.NewExperienceDto(r.Snapshot)
+	return h.write(ctx, id, cond, models.ActionRestore, number, func(pin Precondition) (*models.Experience, error) {
+		return h.ExperienceService.Update(ctx, id, exp, pin)
+	})
+}
+
+// diff compares the client-editable fields of two states of an experience;
+// nil stands for an experience that does not exist.
+func diff(before, after *models.Experience) []models.Change {
+	var a, b map[string]any
+	if before != nil {
+		a = before.PatchDocument()
+	}
+	if after != nil {
+		b = after.PatchDocument()
+	}
+
+	fields := make([]string, 0, len(a)+len(b))
+	for field := range a {
+		fields = append(fields, field)
+	}
+	for field := range b {
+		if _, ok := a[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []models.Change{}
+	for _, field := range fields {
+		from, to := encodeValue(a, field), encodeValue(b, field)
+		if from != to {
+			changes = append(changes, models.Change{Field: field, From: from, To: to})
+		}
+	}
+	return changes
+}
+
+// encodeValue returns the JSON encoding of doc[field], null if it is absent.
+func encodeValue(doc map[string]any, field string) string {
+	value, ok := doc[field]
+	if !ok {
+		return "null"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "null"
+	}
+	return string(data)
+}
+
+func revisionNotFound(id primitive.ObjectID, number int64) error {
+	return newError(ErrNotFound, nil, "revision %d of experience %s not found", number, id.Hex())
+}
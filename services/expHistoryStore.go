@@ -0,0 +1,291 @@
+package services
+
+import (
+	"GO-Project/models"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// memoryHistoryStore keeps revisions in process memory. Like the memory
+// backend, it can hand its writes to hooks, which is how the file history
+// store is built: appendTo gets every new revision and rewrite the full
+// history after revisions were removed.
+type memoryHistoryStore struct {
+	mu        sync.RWMutex
+	revisions map[primitive.ObjectID][]models.Revision
+	appendTo  func(r models.Revision) error
+	rewrite   func(all []models.Revision) error
+}
+
+// NewMemoryHistoryStore returns a HistoryStore that is lost when the process
+// exits.
+func NewMemoryHistoryStore() HistoryStore {
+	return newMemoryHistoryStore(nil, nil, nil)
+}
+
+func newMemoryHistoryStore(revisions []models.Revision, appendTo func(models.Revision) error, rewrite func([]models.Revision) error) *memoryHistoryStore {
+	s := &memoryHistoryStore{revisions: map[primitive.ObjectID][]models.Revision{}, appendTo: appendTo, rewrite: rewrite}
+	for _, r := range revisions {
+		s.revisions[r.ExperienceID] = append(s.revisions[r.ExperienceID], r)
+	}
+	for _, list := range s.revisions {
+		sort.Slice(list, func(i, j int) bool { return list[i].Number < list[j].Number })
+	}
+	return s
+}
+
+func (s *memoryHistoryStore) Append(ctx context.Context, r *models.Revision) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.revisions[r.ExperienceID]
+	i := sort.Search(len(list), func(i int) bool { return list[i].Number >= r.Number })
+	if i < len(list) && list[i].Number == r.Number {
+		return newError(ErrConflict, nil, "revision %d of experience %s already exists", r.Number, r.ExperienceID.Hex())
+	}
+	if s.appendTo != nil {
+		if err := s.appendTo(*r); err != nil {
+			return err
+		}
+	}
+	list = append(list, models.Revision{})
+	copy(list[i+1:], list[i:])
+	list[i] = *cloneRevision(r)
+	s.revisions[r.ExperienceID] = list
+	return nil
+}
+
+func (s *memoryHistoryStore) List(ctx context.Context, experienceID primitive.ObjectID) ([]models.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := s.revisions[experienceID]
+	revisions := make([]models.Revision, len(list))
+	for i := range list {
+		revisions[i] = *cloneRevision(&list[i])
+	}
+	return revisions, nil
+}
+
+func (s *memoryHistoryStore) Get(ctx context.Context, experienceID primitive.ObjectID, number int64) (*models.Revision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i, r := range s.revisions[experienceID] {
+		if r.Number == number {
+			return cloneRevision(&s.revisions[experienceID][i]), nil
+		}
+	}
+	return nil, revisionNotFound(experienceID, number)
+}
+
+func (s *memoryHistoryStore) Delete(ctx context.Context, experienceIDs ...primitive.ObjectID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := map[primitive.ObjectID][]models.Revision{}
+	for _, id := range experienceIDs {
+		if list, ok := s.revisions[id]; ok {
+			removed[id] = list
+			delete(s.revisions, id)
+		}
+	}
+	if len(removed) == 0 || s.rewrite == nil {
+		return nil
+	}
+	if err := s.rewrite(s.all()); err != nil {
+		for id, list := range removed {
+			s.revisions[id] = list
+		}
+		return err
+	}
+	return nil
+}
+
+// all returns every revision ordered by experience and number. Callers must
+// hold s.mu.
+func (s *memoryHistoryStore) all() []models.Revision {
+	ids := make([]primitive.ObjectID, 0, len(s.revisions))
+	for id := range s.revisions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return compareIds(ids[i], ids[j]) < 0 })
+
+	var all []models.Revision
+	for _, id := range ids {
+		all = append(all, s.revisions[id]...)
+	}
+	return all
+}
+
+// cloneRevision returns a deep copy of r, so stored revisions cannot be
+// changed through the values handed out.
+func cloneRevision(r *models.Revision) *models.Revision {
+	c := *r
+	c.Changes = append([]models.Change(nil), r.Changes...)
+	if r.Snapshot != nil {
+		c.Snapshot = r.Snapshot.Clone()
+	}
+	return &c
+}
+
+// NewFileHistoryStore returns a HistoryStore backed by a file at path
+// holding one relaxed extended JSON revision per line. New revisions are
+// appended, so recording stays cheap however long the history grows; the
+// file is only rewritten when experiences are purged.
+func NewFileHistoryStore(path string) (HistoryStore, error) {
+	revisions, err := readHistoryFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newMemoryHistoryStore(revisions,
+		func(r models.Revision) error { return appendHistoryFile(path, r) },
+		func(all []models.Revision) error { return writeHistoryFile(path, all) },
+	), nil
+}
+
+func readHistoryFile(path string) ([]models.Revision, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []models.Revision
+	for n, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var r models.Revision
+		if err := bson.UnmarshalExtJSON(line, false, &r); err != nil {
+			return nil, fmt.Errorf("read %s line %d: %w", path, n+1, err)
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, nil
+}
+
+func appendHistoryFile(path string, r models.Revision) error {
+	data, err := bson.MarshalExtJSON(r, false, false)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeHistoryFile(path string, revisions []models.Revision) error {
+	var buf bytes.Buffer
+	for _, r := range revisions {
+		data, err := bson.MarshalExtJSON(r, false, false)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+type mongoHistoryStore struct {
+	coll *mongo.Collection
+}
+
+// NewMongoHistoryStore returns a HistoryStore keeping revisions in the given
+// collection. EnsureHistoryIndexes creates the index it relies on.
+func NewMongoHistoryStore(client *mongo.Client, database, collection string) HistoryStore {
+	return &mongoHistoryStore{coll: client.Database(database).Collection(collection)}
+}
+
+// EnsureHistoryIndexes creates the unique (experienceId, number) index that
+// orders revisions and rejects a second revision for the same version.
+func EnsureHistoryIndexes(ctx context.Context, client *mongo.Client, database, collection string) error {
+	_, err := client.Database(database).Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "experienceId", Value: 1}, {Key: "number", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (s *mongoHistoryStore) Append(ctx context.Context, r *models.Revision) error {
+	if _, err := s.coll.InsertOne(ctx, r); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return newError(ErrConflict, err, "revision %d of experience %s already exists", r.Number, r.ExperienceID.Hex())
+		}
+		return wrapMongoError(err, r.ExperienceID)
+	}
+	return nil
+}
+
+func (s *mongoHistoryStore) List(ctx context.Context, experienceID primitive.ObjectID) ([]models.Revision, error) {
+	cur, err := s.coll.Find(ctx, bson.M{"experienceId": experienceID}, options.Find().SetSort(bson.D{{Key: "number", Value: 1}}))
+	if err != nil {
+		return nil, wrapMongoError(err, experienceID)
+	}
+	revisions := []models.Revision{}
+	if err := cur.All(ctx, &revisions); err != nil {
+		return nil, wrapMongoError(err, experienceID)
+	}
+	return revisions, nil
+}
+
+func (s *mongoHistoryStore) Get(ctx context.Context, experienceID primitive.ObjectID, number int64) (*models.Revision, error) {
+	var r models.Revision
+	err := s.coll.FindOne(ctx, bson.M{"experienceId": experienceID, "number": number}).Decode(&r)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, revisionNotFound(experienceID, number)
+	}
+	if err != nil {
+		return nil, wrapMongoError(err, experienceID)
+	}
+	return &r, nil
+}
+
+func (s *mongoHistoryStore) Delete(ctx context.Context, experienceIDs ...primitive.ObjectID) error {
+	if len(experienceIDs) == 0 {
+		return nil
+	}
+	_, err := s.coll.DeleteMany(ctx, bson.M{"experienceId": bson.M{"$in": experienceIDs}})
+	return wrapMongoError(err, primitive.NilObjectID)
+}
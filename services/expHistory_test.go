@@ -0,0 +1,340 @@
+package services
+
+import (
+	"GO-Project/auth"
+	"GO-Project/models"
+	"GO-Project/patch"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func historyDto(title string) *models.ExperienceDto {
+	return &models.ExperienceDto{Company: "Acme", Title: title, StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func fields(changes []models.Change) []string {
+	names := make([]string, len(changes))
+	for i, c := range changes {
+		names[i] = c.Field
+	}
+	return names
+}
+
+func TestHistoryService(t *testing.T) {
+	ctx := WithActor(context.Background(), "alice")
+	h := NewHistoryService(NewMemoryExperienceService(), NewMemoryHistoryStore())
+
+	created, err := h.Create(ctx, historyDto("Developer"))
+	require.NoError(t, err)
+	id := created.ID
+
+	_, err = h.Update(ctx, id, historyDto("Senior developer"), Precondition{})
+	require.NoError(t, err)
+	p, err := patch.ParseMerge([]byte(`{"skills": ["go"]}`))
+	require.NoError(t, err)
+	_, err = h.Patch(context.Background(), id, p, Precondition{})
+	require.NoError(t, err)
+
+	revisions, err := h.Revisions(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	for i, action := range []string{models.ActionCreate, models.ActionUpdate, models.ActionPatch} {
+		require.Equal(t, int64(i+1), revisions[i].Number)
+		require.Equal(t, action, revisions[i].Action)
+		require.Equal(t, revisions[i].Number, revisions[i].Snapshot.Version)
+	}
+	require.Equal(t, "alice", revisions[1].Actor)
+	require.Equal(t, "anonymous", revisions[2].Actor)
+	require.Contains(t, fields(revisions[0].Changes), "company")
+	require.Equal(t, []models.Change{{Field: "title", From: `"Developer"`, To: `"Senior developer"`}}, revisions[1].Changes)
+	require.Equal(t, []models.Change{{Field: "skills", From: `[]`, To: `["go"]`}}, revisions[2].Changes)
+
+	changes, err := h.Diff(ctx, id, 1, 3)
+	require.NoError(t, err)
+	require.Equal(t, []string{"skills", "title"}, fields(changes))
+
+	restored, err := h.Restore(ctx, id, 1, Precondition{IfMatch: []int64{3}})
+	require.NoError(t, err)
+	require.Equal(t, "Developer", restored.Title)
+	require.Empty(t, restored.Skills)
+	require.Equal(t, int64(4), restored.Version)
+
+	_, err = h.Restore(ctx, id, 1, Precondition{IfMatch: []int64{3}})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	require.NoError(t, h.Delete(ctx, id, Precondition{}))
+	revisions, err = h.Revisions(ctx, id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 5)
+	require.Equal(t, int64(1), revisions[3].RestoredFrom)
+	require.Equal(t, models.ActionDelete, revisions[4].Action)
+	require.Nil(t, revisions[4].Snapshot)
+	require.Contains(t, revisions[4].Changes, models.Change{Field: "title", From: `"Developer"`, To: "null"})
+
+	_, err = h.Restore(ctx, id, 5, Precondition{})
+	require.ErrorIs(t, err, ErrValidation)
+	_, err = h.Restore(ctx, id, 2, Precondition{})
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = h.Revision(ctx, id, 9)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestHistoryServicePrincipalActor(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "user-1"})
+	h := NewHistoryService(NewMemoryExperienceService(), NewMemoryHistoryStore())
+
+	created, err := h.Create(ctx, historyDto("Developer"))
+	require.NoError(t, err)
+	_, err = h.Update(WithActor(ctx, "importer"), created.ID, historyDto("Lead"), Precondition{})
+	require.NoError(t, err)
+
+	revisions, err := h.Revisions(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, "user-1", revisions[0].Actor)
+	require.Equal(t, "importer", revisions[1].Actor)
+}
+
+func TestHistoryServiceOwnership(t *testing.T) {
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "bob"})
+	admin := WithAllOwners(auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "root"}))
+	h := NewHistoryService(NewMemoryExperienceService(), NewMemoryHistoryStore())
+
+	created, err := h.Create(alice, historyDto("Developer"))
+	require.NoError(t, err)
+	id := created.ID
+	_, err = h.Update(alice, id, historyDto("Lead"), Precondition{})
+	require.NoError(t, err)
+	require.NoError(t, h.Delete(alice, id, Precondition{}))
+
+	_, err = h.Revisions(bob, id)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = h.Revision(bob, id, 1)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = h.Diff(bob, id, 1, 2)
+	require.ErrorIs(t, err, ErrNotFound)
+	_, err = h.Restore(bob, id, 1, Precondition{})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// The history of a trashed experience stays readable by its owner.
+	revisions, err := h.Revisions(alice, id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	_, err = h.Revision(alice, id, 1)
+	require.NoError(t, err)
+	changes, err := h.Diff(admin, id, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+}
+
+func TestHistoryServiceBulk(t *testing.T) {
+	ctx := WithActor(context.Background(), "importer")
+	h := NewHistoryService(NewMemoryExperienceService(), NewMemoryHistoryStore())
+	updated, err := h.Create(ctx, historyDto("Developer"))
+	require.NoError(t, err)
+	deleted, err := h.Create(ctx, historyDto("Intern"))
+	require.NoError(t, err)
+	stale, err := h.Create(ctx, historyDto("Stale"))
+	require.NoError(t, err)
+
+	results, err := h.Bulk(ctx, []BulkOperation{
+		{Op: BulkCreate, Experience: historyDto("Lead")},
+		{Op: BulkUpdate, ID: updated.ID, Experience: historyDto("Senior developer")},
+		{Op: BulkDelete, ID: deleted.ID},
+		{Op: BulkDelete, ID: stale.ID, Cond: Precondition{IfMatch: []int64{2}}},
+		{Op: BulkUpdate, ID: primitive.NewObjectID(), Experience: historyDto("x")},
+	}, false)
+	require.NoError(t, err)
+	require.ErrorIs(t, results[3].Err, ErrPreconditionFailed)
+	require.ErrorIs(t, results[4].Err, ErrNotFound)
+
+	for i, want := range []struct {
+		id     primitive.ObjectID
+		action string
+	}{
+		{results[0].ID, models.ActionCreate},
+		{updated.ID, models.ActionUpdate},
+		{deleted.ID, models.ActionDelete},
+	} {
+		revisions, err := h.Revisions(ctx, want.id)
+		require.NoError(t, err, i)
+		last := revisions[len(revisions)-1]
+		require.Equal(t, want.action, last.Action, i)
+		require.Equal(t, "importer", last.Actor, i)
+	}
+	revisions, err := h.Revisions(ctx, updated.ID)
+	require.NoError(t, err)
+	require.Equal(t, []models.Change{{Field: "title", From: `"Developer"`, To: `"Senior developer"`}}, revisions[1].Changes)
+	revisions, err = h.Revisions(ctx, stale.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+
+	// An aborted batch records nothing.
+	results, err = h.Bulk(ctx, []BulkOperation{
+		{Op: BulkUpdate, ID: updated.ID, Experience: historyDto("Never")},
+		{Op: BulkDelete, ID: stale.ID, Cond: Precondition{IfMatch: []int64{2}}},
+	}, true)
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, ErrAborted)
+	revisions, err = h.Revisions(ctx, updated.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+}
+
+func TestHistoryServiceWithoutRevisions(t *testing.T) {
+	legacy := models.Experience{ID: primitive.NewObjectID(), Company: "Acme", Title: "Developer"}
+	h := NewHistoryService(newMemoryExperienceService([]models.Experience{legacy}, nil), NewMemoryHistoryStore())
+
+	revisions, err := h.Revisions(context.Background(), legacy.ID)
+	require.NoError(t, err)
+	require.Empty(t, revisions)
+
+	_, err = h.Revisions(context.Background(), primitive.NewObjectID())
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// The first recorded write still knows what it replaced.
+	_, err = h.Update(context.Background(), legacy.ID, historyDto("Lead"), Precondition{})
+	require.NoError(t, err)
+	revisions, err = h.Revisions(context.Background(), legacy.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), revisions[0].Number)
+	require.Contains(t, revisions[0].Changes, models.Change{Field: "title", From: `"Developer"`, To: `"Lead"`})
+}
+
+// failingHistoryStore cannot store revisions, as with the history backend
+// down.
+type failingHistoryStore struct {
+	HistoryStore
+}
+
+func (failingHistoryStore) Append(context.Context, *models.Revision) error {
+	return newError(ErrUnavailable, nil, "storage is temporarily unavailable")
+}
+
+func TestHistoryServiceRecordFails(t *testing.T) {
+	h := NewHistoryService(NewMemoryExperienceService(), failingHistoryStore{NewMemoryHistoryStore()})
+	ctx := context.Background()
+
+	// Writes that were applied are reported as such, so that clients do not
+	// retry them.
+	created, err := h.Create(ctx, historyDto("Developer"))
+	require.NoError(t, err)
+	updated, err := h.Update(ctx, created.ID, historyDto("Lead"), Precondition{IfMatch: []int64{1}})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), updated.Version)
+	require.NoError(t, h.Delete(ctx, created.ID, Precondition{}))
+	_, err = h.Undelete(ctx, created.ID, Precondition{})
+	require.NoError(t, err)
+	results, err := h.Bulk(ctx, []BulkOperation{
+		{Op: BulkCreate, Experience: historyDto("Imported")},
+		{Op: BulkUpdate, ID: created.ID, Experience: historyDto("Manager")},
+	}, false)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+
+	stored, err := h.FindById(ctx, created.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Manager", stored.Title)
+}
+
+func TestHistoryServicePurge(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "experiences.history.jsonl")
+	store, err := NewFileHistoryStore(path)
+	require.NoError(t, err)
+	h := NewHistoryService(NewMemoryExperienceService(), store)
+
+	kept, err := h.Create(ctx, historyDto("kept"))
+	require.NoError(t, err)
+	purged, err := h.Create(ctx, historyDto("purged"))
+	require.NoError(t, err)
+	require.NoError(t, h.Delete(ctx, purged.ID, Precondition{}))
+	_, err = h.Undelete(ctx, purged.ID, Precondition{})
+	require.NoError(t, err)
+
+	revisions, err := h.Revisions(ctx, purged.ID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	require.Equal(t, models.ActionUndelete, revisions[2].Action)
+	require.Equal(t, int64(3), revisions[2].Number)
+	require.Contains(t, revisions[2].Changes, models.Change{Field: "title", From: "null", To: `"purged"`})
+
+	require.NoError(t, h.Delete(ctx, purged.ID, Precondition{}))
+	require.NoError(t, h.Purge(ctx, purged.ID, Precondition{}))
+	_, err = h.Revisions(ctx, purged.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	reopened, err := NewFileHistoryStore(path)
+	require.NoError(t, err)
+	remaining, err := reopened.List(ctx, purged.ID)
+	require.NoError(t, err)
+	require.Empty(t, remaining)
+	remaining, err = reopened.List(ctx, kept.ID)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+}
+
+func TestFileHistoryStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiences.history.jsonl")
+	store, err := NewFileHistoryStore(path)
+	require.NoError(t, err)
+
+	id := primitive.NewObjectID()
+	snapshot := &models.Experience{ID: id, Company: "Acme", Title: "Developer", Version: 2}
+	for _, n := range []int64{2, 1} {
+		require.NoError(t, store.Append(context.Background(), &models.Revision{
+			ID: primitive.NewObjectID(), ExperienceID: id, Number: n, Action: models.ActionUpdate,
+			Changes: []models.Change{{Field: "title", From: `"a"`, To: `"b"`}}, Snapshot: snapshot,
+		}))
+	}
+	err = store.Append(context.Background(), &models.Revision{ExperienceID: id, Number: 2})
+	require.ErrorIs(t, err, ErrConflict)
+
+	reopened, err := NewFileHistoryStore(path)
+	require.NoError(t, err)
+	revisions, err := reopened.List(context.Background(), id)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, int64(1), revisions[0].Number)
+	require.Equal(t, "Developer", revisions[1].Snapshot.Title)
+	require.Equal(t, `"b"`, revisions[1].Changes[0].To)
+}
+
+func TestMongoHistoryStore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Append duplicate", func(mt *mtest.T) {
+		s := NewMongoHistoryStore(mt.Client, "TODOLIST", "experience_revisions")
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}))
+		err := s.Append(context.Background(), &models.Revision{ID: primitive.NewObjectID(), ExperienceID: primitive.NewObjectID(), Number: 2})
+		require.ErrorIs(t, err, ErrConflict)
+	})
+
+	mt.Run("List", func(mt *mtest.T) {
+		s := NewMongoHistoryStore(mt.Client, "TODOLIST", "experience_revisions")
+		id := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "TODOLIST.experience_revisions", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "experienceId", Value: id}, {Key: "number", Value: 1}, {Key: "action", Value: "create"}},
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "experienceId", Value: id}, {Key: "number", Value: 2}, {Key: "action", Value: "delete"}},
+		))
+		revisions, err := s.List(context.Background(), id)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		require.Equal(t, models.ActionDelete, revisions[1].Action)
+	})
+
+	mt.Run("Get not found", func(mt *mtest.T) {
+		s := NewMongoHistoryStore(mt.Client, "TODOLIST", "experience_revisions")
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "TODOLIST.experience_revisions", mtest.FirstBatch))
+		_, err := s.Get(context.Background(), primitive.NewObjectID(), 3)
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
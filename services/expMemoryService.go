@@ -0,0 +1,402 @@
+package services
+
+import (
+	"GO-Project/models"
+	"GO-Project/patch"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryExperienceService keeps experiences in process memory. When persist
+// is set it is called with a snapshot after every successful write, which is
+// how the file backend is built on top of it.
+type memoryExperienceService struct {
+	mu      sync.RWMutex
+	items   map[primitive.ObjectID]models.Experience
+	index   *searchIndex
+	persist func(items []models.Experience) error
+}
+
+// NewMemoryExperienceService returns a backend that needs no database. Data
+// is lost when the process exits.
+func NewMemoryExperienceService() ExperienceService {
+	return newMemoryExperienceService(nil, nil)
+}
+
+func newMemoryExperienceService(items []models.Experience, persist func([]models.Experience) error) *memoryExperienceService {
+	m := &memoryExperienceService{
+		items:   make(map[primitive.ObjectID]models.Experience, len(items)),
+		index:   newSearchIndex(),
+		persist: persist,
+	}
+	for _, item := range items {
+		item.Upgrade()
+		m.items[item.ID] = item
+		if item.DeletedAt == nil {
+			m.index.add(&item)
+		}
+	}
+	return m
+}
+
+func (m *memoryExperienceService) Create(ctx context.Context, exp *models.ExperienceDto) (*models.Experience, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var experience = models.Experience{
+		ID:        primitive.NewObjectID(),
+		Version:   1,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Time{},
+		OwnerID:   ownerOf(ctx),
+	}
+	exp.ApplyTo(&experience)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.items[experience.ID] = experience
+	if err := m.save(); err != nil {
+		delete(m.items, experience.ID)
+		return nil, err
+	}
+	m.index.add(&experience)
+	return experience.Clone(), nil
+}
+
+func (m *memoryExperienceService) Update(ctx context.Context, id primitive.ObjectID, exp *models.ExperienceDto, cond Precondition) (*models.Experience, error) {
+	return m.replace(ctx, id, false, cond, func(experience *models.Experience) error {
+		exp.ApplyTo(experience)
+		experience.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+func (m *memoryExperienceService) Patch(ctx context.Context, id primitive.ObjectID, p patch.Patch, cond Precondition) (*models.Experience, error) {
+	return m.replace(ctx, id, false, cond, func(experience *models.Experience) error {
+		dto, err := applyPatch(experience, p)
+		if err != nil {
+			return err
+		}
+		dto.ApplyTo(experience)
+		experience.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// replace stores the experience, live or trashed, after change has been
+// applied to a copy of it and its version bumped, keeping the search index
+// to live experiences.
+func (m *memoryExperienceService) replace(ctx context.Context, id primitive.ObjectID, trashed bool, cond Precondition, change func(experience *models.Experience) error) (*models.Experience, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, ok := m.items[id]
+	if !ok || (previous.DeletedAt != nil) != trashed || !scopeOf(ctx).allows(&previous) {
+		return nil, notFound(id)
+	}
+	if err := cond.check(&previous); err != nil {
+		return nil, err
+	}
+
+	experience := previous.Clone()
+	if err := change(experience); err != nil {
+		return nil, err
+	}
+	experience.Version++
+
+	m.items[id] = *experience
+	if err := m.save(); err != nil {
+		m.items[id] = previous
+		return nil, err
+	}
+	if previous.DeletedAt == nil {
+		m.index.remove(&previous)
+	}
+	if experience.DeletedAt == nil {
+		m.index.add(experience)
+	}
+	return experience.Clone(), nil
+}
+
+func (m *memoryExperienceService) FindById(ctx context.Context, id primitive.ObjectID) (*models.Experience, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	experience, ok := m.items[id]
+	if !ok || experience.DeletedAt != nil || !scopeOf(ctx).allows(&experience) {
+		return nil, notFound(id)
+	}
+	return experience.Clone(), nil
+}
+
+func (m *memoryExperienceService) FindAll(ctx context.Context, query ListQuery) (*ListResult, error) {
+	return m.list(ctx, query, false)
+}
+
+func (m *memoryExperienceService) list(ctx context.Context, query ListQuery, trashed bool) (*ListResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	plan, err := newListPlan(query)
+	if err != nil {
+		return nil, err
+	}
+	plan.trashed = trashed
+	plan.scope = scopeOf(ctx)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matched []*models.Experience
+	for _, experience := range m.items {
+		if plan.matches(&experience) {
+			matched = append(matched, experience.Clone())
+		}
+	}
+	total := int64(len(matched))
+
+	sort.Slice(matched, func(i, j int) bool {
+		return plan.less(matched[i], matched[j])
+	})
+
+	start := 0
+	if plan.keyset != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return plan.afterKeyset(matched[i])
+		})
+	} else {
+		start = int(min(max(plan.skip(), 0), total))
+	}
+	end := min(start+plan.Limit+1, len(matched))
+	return plan.finish(matched[start:end], total), nil
+}
+
+func (m *memoryExperienceService) Search(ctx context.Context, query SearchQuery) (*SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	query, terms, err := newSearchPlan(query)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	scope := scopeOf(ctx)
+	var candidates []*models.Experience
+	for id := range m.index.lookup(terms, query) {
+		if experience := m.items[id]; scope.allows(&experience) {
+			candidates = append(candidates, experience.Clone())
+		}
+	}
+	return rank(candidates, terms, query), nil
+}
+
+func (m *memoryExperienceService) Delete(ctx context.Context, id primitive.ObjectID, cond Precondition) error {
+	_, err := m.replace(ctx, id, false, cond, func(experience *models.Experience) error {
+		now := time.Now()
+		experience.DeletedAt = &now
+		return nil
+	})
+	return err
+}
+
+func (m *memoryExperienceService) Trash(ctx context.Context, query ListQuery) (*ListResult, error) {
+	return m.list(ctx, query, true)
+}
+
+func (m *memoryExperienceService) Undelete(ctx context.Context, id primitive.ObjectID, cond Precondition) (*models.Experience, error) {
+	return m.replace(ctx, id, true, cond, func(experience *models.Experience) error {
+		experience.DeletedAt = nil
+		return nil
+	})
+}
+
+func (m *memoryExperienceService) Purge(ctx context.Context, id primitive.ObjectID, cond Precondition) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	previous, ok := m.items[id]
+	if !ok || previous.DeletedAt == nil || !scopeOf(ctx).allows(&previous) {
+		return notFound(id)
+	}
+	if err := cond.check(&previous); err != nil {
+		return err
+	}
+
+	delete(m.items, id)
+	if err := m.save(); err != nil {
+		m.items[id] = previous
+		return err
+	}
+	return nil
+}
+
+func (m *memoryExperienceService) PurgeDeleted(ctx context.Context, cutoff time.Time) ([]primitive.ObjectID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scope := scopeOf(ctx)
+	expired := map[primitive.ObjectID]models.Experience{}
+	for id, experience := range m.items {
+		if experience.DeletedAt != nil && !experience.DeletedAt.After(cutoff) && scope.allows(&experience) {
+			expired[id] = experience
+			delete(m.items, id)
+		}
+	}
+	if len(expired) == 0 {
+		return []primitive.ObjectID{}, nil
+	}
+	if err := m.save(); err != nil {
+		for id, experience := range expired {
+			m.items[id] = experience
+		}
+		return nil, err
+	}
+
+	purged := make([]primitive.ObjectID, 0, len(expired))
+	for id := range expired {
+		purged = append(purged, id)
+	}
+	sort.Slice(purged, func(i, j int) bool { return compareIds(purged[i], purged[j]) < 0 })
+	return purged, nil
+}
+
+func (m *memoryExperienceService) AssignOwner(ctx context.Context, owner string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var assigned []primitive.ObjectID
+	for id, experience := range m.items {
+		if experience.OwnerID == "" {
+			experience.OwnerID = owner
+			m.items[id] = experience
+			assigned = append(assigned, id)
+		}
+	}
+	if len(assigned) == 0 {
+		return 0, nil
+	}
+	if err := m.save(); err != nil {
+		for _, id := range assigned {
+			experience := m.items[id]
+			experience.OwnerID = ""
+			m.items[id] = experience
+		}
+		return 0, err
+	}
+	return int64(len(assigned)), nil
+}
+
+// sorted returns the items in insertion order, which ObjectIDs encode.
+// Callers must hold m.mu.
+func (m *memoryExperienceService) sorted() []models.Experience {
+	items := make([]models.Experience, 0, len(m.items))
+	for _, item := range m.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID.Hex() < items[j].ID.Hex()
+	})
+	return items
+}
+
+// save hands a snapshot to the persistence hook. Callers must hold m.mu
+// for writing.
+func (m *memoryExperienceService) save() error {
+	if m.persist == nil {
+		return nil
+	}
+	return m.persist(m.sorted())
+}
+
+func (m *memoryExperienceService) Bulk(ctx context.Context, ops []BulkOperation, atomic bool) ([]BulkResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := checkBulk(ops); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scope := scopeOf(ctx)
+	results, staged := stageBulk(ops, func(id primitive.ObjectID) *models.Experience {
+		experience, ok := m.items[id]
+		if !ok || experience.DeletedAt != nil || !scope.allows(&experience) {
+			return nil
+		}
+		return &experience
+	}, ownerOf(ctx), time.Now())
+	if atomic && bulkFailed(results) {
+		return abortBulk(results), nil
+	}
+
+	// The whole batch is saved at once, which the file backend turns into
+	// a single rewrite.
+	previous := map[primitive.ObjectID]models.Experience{}
+	var written []*models.Experience
+	for i, experience := range staged {
+		if results[i].Err != nil {
+			continue
+		}
+		if old, ok := m.items[experience.ID]; ok {
+			previous[experience.ID] = old
+		}
+		m.items[experience.ID] = *experience.Clone()
+		written = append(written, experience)
+	}
+	if len(written) == 0 {
+		return results, nil
+	}
+	if err := m.save(); err != nil {
+		for _, experience := range written {
+			if old, ok := previous[experience.ID]; ok {
+				m.items[experience.ID] = old
+			} else {
+				delete(m.items, experience.ID)
+			}
+		}
+		return nil, err
+	}
+
+	for _, experience := range written {
+		if old, ok := previous[experience.ID]; ok {
+			m.index.remove(&old)
+		}
+		if experience.DeletedAt == nil {
+			m.index.add(experience)
+		}
+	}
+	return finishBulk(ops, results, staged), nil
+}
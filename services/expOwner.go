@@ -0,0 +1,57 @@
+package services
+
+import (
+	"GO-Project/auth"
+	"GO-Project/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type allOwnersKey struct{}
+
+// WithAllOwners returns a context acting on the experiences of every owner,
+// as the access policy allows admins to.
+func WithAllOwners(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allOwnersKey{}, true)
+}
+
+// ownerScope is the experiences a request may see and write. Experiences
+// outside of it are reported as not found, so their existence does not leak.
+type ownerScope struct {
+	// restricted limits the scope to the experiences of owner. Contexts
+	// made with WithAllOwners are not restricted, nor are requests without
+	// a principal, which are only made with authentication disabled or by
+	// the service itself.
+	restricted bool
+	owner      string
+}
+
+// scopeOf returns the scope of the principal of ctx.
+func scopeOf(ctx context.Context) ownerScope {
+	p, ok := auth.FromContext(ctx)
+	if all, _ := ctx.Value(allOwnersKey{}).(bool); !ok || all {
+		return ownerScope{}
+	}
+	return ownerScope{restricted: true, owner: p.Subject}
+}
+
+// ownerOf returns the owner of the experiences created with ctx.
+func ownerOf(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.Subject
+	}
+	return ""
+}
+
+func (s ownerScope) allows(e *models.Experience) bool {
+	return !s.restricted || e.OwnerID == s.owner
+}
+
+// restrict adds the scope to a Mongo filter.
+func (s ownerScope) restrict(filter bson.M) bson.M {
+	if s.restricted {
+		filter["ownerId"] = s.owner
+	}
+	return filter
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"GO-Project/models"
+	"GO-Project/patch"
+	"GO-Project/validation"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// readOnlyFields are members of the experience representation that clients
+// cannot change. A full replacement may carry them, as returned by GET; they
+// are ignored.
+var readOnlyFields = []string{"id", "version", "createdAt", "updatedAt", "deletedAt", "ownerId"}
+
+// DecodeExperience decodes a complete experience representation for a full
+// replacement. Unknown members are rejected and the result is validated.
+// data must be valid JSON.
+func DecodeExperience(data []byte) (*models.ExperienceDto, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, NewValidationError(validation.Errors{{Field: "body", Rule: "type", Message: "must be a JSON object"}})
+	}
+	if members == nil {
+		return nil, NewValidationError(validation.Errors{{Field: "body", Rule: "required", Message: "is required"}})
+	}
+	for _, name := range readOnlyFields {
+		delete(members, name)
+	}
+	data, err := json.Marshal(members)
+	if err != nil {
+		return nil, err
+	}
+	return decodeDto(data)
+}
+
+// decodeDto strictly decodes and validates an experience payload.
+func decodeDto(data []byte) (*models.ExperienceDto, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var dto models.ExperienceDto
+	if err := dec.Decode(&dto); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			return nil, NewValidationError(validation.Errors{{Field: field, Rule: "unknown", Message: "is not an editable field"}})
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return nil, NewValidationError(validation.Errors{{Field: typeErr.Field, Rule: "type", Message: "must be a JSON " + jsonKind(typeErr.Type.Kind().String())}})
+		default:
+			return nil, NewValidationError(validation.Errors{{Field: "body", Rule: "type", Message: err.Error()}})
+		}
+	}
+	if err := NewValidationError(validation.Struct(&dto)); err != nil {
+		return nil, err
+	}
+	return &dto, nil
+}
+
+func jsonKind(kind string) string {
+	switch kind {
+	case "slice":
+		return "array"
+	case "struct", "map":
+		return "object"
+	}
+	return kind
+}
+
+// applyPatch applies p to the editable fields of e and returns the
+// resulting payload, validated like a full replacement.
+func applyPatch(e *models.Experience, p patch.Patch) (*models.ExperienceDto, error) {
+	doc, err := json.Marshal(e.PatchDocument())
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := p.Apply(doc)
+	if err != nil {
+		var perr *patch.Error
+		switch {
+		case errors.Is(err, patch.ErrTestFailed):
+			return nil, newError(ErrConflict, err, "patch test failed: %s", err.Error())
+		case errors.As(err, &perr):
+			return nil, &Error{
+				Kind:    ErrValidation,
+				Message: "patch cannot be applied",
+				Err:     err,
+				Fields:  []FieldError{{Field: perr.Path, Rule: "patch", Message: perr.Message}},
+			}
+		}
+		return nil, err
+	}
+	return decodeDto(patched)
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"GO-Project/models"
+	"slices"
+)
+
+// Precondition makes a write conditional on the version of the stored
+// experience, the way the HTTP If-Match and If-None-Match headers do. Every
+// backend checks it atomically with the write, so a write made against a
+// stale read is rejected rather than silently overwriting a newer one. The
+// zero value always holds.
+type Precondition struct {
+	// IfMatch, when not nil, lists the versions the experience must be at.
+	// A non-nil empty list matches nothing.
+	IfMatch []int64
+	// IfNoneMatch lists versions the experience must not be at.
+	IfNoneMatch []int64
+	// IfNoneMatchAny fails the write whenever the experience exists.
+	IfNoneMatchAny bool
+}
+
+// check reports ErrPreconditionFailed unless the precondition holds for the
+// stored experience.
+func (p Precondition) check(e *models.Experience) error {
+	if p.IfMatch != nil && !slices.Contains(p.IfMatch, e.Version) ||
+		p.IfNoneMatchAny || slices.Contains(p.IfNoneMatch, e.Version) {
+		return newError(ErrPreconditionFailed, nil, "experience %s is at version %d", e.ID.Hex(), e.Version)
+	}
+	return nil
+}
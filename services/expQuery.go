@@ -0,0 +1,360 @@
+package services
+
+import (
+	"GO-Project/models"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+	// MaxPage bounds Page so that the items skipped stay far from
+	// overflowing; deeper listings use cursors.
+	MaxPage = 1_000_000
+)
+
+// ListQuery selects, orders and pages the experiences returned by FindAll.
+// The zero value lists the first page of everything in insertion order.
+type ListQuery struct {
+	// Page is the 1-based page number. It is ignored when Cursor is set.
+	Page  int
+	Limit int
+
+	// Cursor continues a previous listing from ListResult.NextCursor or
+	// PrevCursor. It carries its own sort order, which overrides Sort and
+	// Desc.
+	Cursor string
+
+	// Sort names one of SortFields; empty means createdAt.
+	Sort string
+	Desc bool
+
+	// Text matches experiences containing it, case-insensitively, in the
+	// company, title, location, summary, achievements or skills.
+	Text string
+	// Tags matches experiences having every listed skill.
+	Tags           []string
+	EmploymentType string
+	Remote         *bool
+	StartFrom      *time.Time
+	StartTo        *time.Time
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+}
+
+// ListResult is a single page of experiences.
+type ListResult struct {
+	Items []*models.Experience
+	// Total counts every experience matching the filters, across all pages.
+	Total int64
+	Page  int
+	Limit int
+
+	// NextCursor and PrevCursor are empty when there is nothing further in
+	// that direction.
+	NextCursor string
+	PrevCursor string
+}
+
+// sortField describes a field experiences can be ordered by. All of them
+// are indexed by the Mongo backend. Keyset paging relies on them never
+// being null: documents written before a field existed hold its zero value,
+// as upgraded by the other backends and backfilled by EnsureIndexes.
+type sortField struct {
+	bson  string
+	value func(e *models.Experience) any
+}
+
+// SortFields lists the names accepted by ListQuery.Sort.
+var SortFields = map[string]sortField{
+	"createdAt": {"createdAt", func(e *models.Experience) any { return e.CreatedAt }},
+	"updatedAt": {"updatedAt", func(e *models.Experience) any { return e.UpdatedAt }},
+	"startDate": {"startDate", func(e *models.Experience) any { return e.StartDate }},
+	"company":   {"company", func(e *models.Experience) any { return e.Company }},
+	"title":     {"title", func(e *models.Experience) any { return e.Title }},
+}
+
+// SortFieldNames returns the keys of SortFields in a stable order.
+func SortFieldNames() []string {
+	names := make([]string, 0, len(SortFields))
+	for name := range SortFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cursor is the decoded form of ListQuery.Cursor: the position of the last
+// (or, with Before, the first) item of the previous page.
+type cursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Value  string `json:"v"`
+	ID     string `json:"id"`
+	Before bool   `json:"b,omitempty"`
+}
+
+func encodeCursor(sortName string, desc bool, e *models.Experience, before bool) string {
+	c := cursor{Sort: sortName, Desc: desc, ID: e.ID.Hex(), Before: before}
+	switch v := SortFields[sortName].value(e).(type) {
+	case time.Time:
+		c.Value = v.UTC().Format(time.RFC3339Nano)
+	case string:
+		c.Value = v
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// keyset is a decoded, validated cursor.
+type keyset struct {
+	sort   string
+	desc   bool
+	value  any
+	id     primitive.ObjectID
+	before bool
+}
+
+func invalidCursor() error {
+	return newError(ErrValidation, nil, "cursor is invalid or expired")
+}
+
+func decodeCursor(s string) (*keyset, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalidCursor()
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, invalidCursor()
+	}
+	field, ok := SortFields[c.Sort]
+	if !ok {
+		return nil, invalidCursor()
+	}
+	id, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return nil, invalidCursor()
+	}
+
+	k := &keyset{sort: c.Sort, desc: c.Desc, id: id, before: c.Before}
+	switch field.value(&models.Experience{}).(type) {
+	case time.Time:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, invalidCursor()
+		}
+		k.value = t
+	default:
+		k.value = c.Value
+	}
+	return k, nil
+}
+
+// listPlan is a ListQuery with defaults applied and the cursor decoded,
+// shared by every backend.
+type listPlan struct {
+	ListQuery
+	field  sortField
+	keyset *keyset
+	// ascending is the order items are fetched in; it is the reverse of the
+	// requested order when paging backwards.
+	ascending bool
+	// trashed lists deleted experiences instead of live ones.
+	trashed bool
+	scope   ownerScope
+}
+
+func newListPlan(q ListQuery) (*listPlan, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.Page > MaxPage {
+		return nil, NewValidationError([]FieldError{{Field: "page", Rule: "max", Message: fmt.Sprintf("must be at most %d", MaxPage)}})
+	}
+	if q.Sort == "" {
+		q.Sort = "createdAt"
+	}
+
+	p := &listPlan{ListQuery: q}
+	if q.Cursor != "" {
+		k, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		p.keyset = k
+		p.Sort, p.Desc, p.Page = k.sort, k.desc, 0
+	}
+
+	field, ok := SortFields[p.Sort]
+	if !ok {
+		return nil, &Error{
+			Kind:    ErrValidation,
+			Message: "request validation failed",
+			Fields: []FieldError{{
+				Field:   "sort",
+				Rule:    "oneof",
+				Message: "must be one of " + strings.Join(SortFieldNames(), ", "),
+			}},
+		}
+	}
+	p.field = field
+	p.ascending = !p.Desc
+	if p.keyset != nil && p.keyset.before {
+		p.ascending = !p.ascending
+	}
+	return p, nil
+}
+
+// skip is the number of matching items before the requested page in page
+// mode.
+func (p *listPlan) skip() int64 {
+	if p.keyset != nil {
+		return 0
+	}
+	return int64(p.Page-1) * int64(p.Limit)
+}
+
+// finish turns the fetched items, at most Limit+1 of them in fetch order,
+// into a ListResult with cursors for the neighbouring pages.
+func (p *listPlan) finish(items []*models.Experience, total int64) *ListResult {
+	more := len(items) > p.Limit
+	if more {
+		items = items[:p.Limit]
+	}
+	if p.keyset != nil && p.keyset.before {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	result := &ListResult{Items: items, Total: total, Page: p.Page, Limit: p.Limit}
+	if len(items) == 0 {
+		return result
+	}
+
+	var hasNext, hasPrev bool
+	switch {
+	case p.keyset == nil:
+		hasNext = more
+		hasPrev = p.Page > 1
+	case p.keyset.before:
+		hasNext = true
+		hasPrev = more
+	default:
+		hasNext = more
+		hasPrev = true
+	}
+	if hasNext {
+		result.NextCursor = encodeCursor(p.Sort, p.Desc, items[len(items)-1], false)
+	}
+	if hasPrev {
+		result.PrevCursor = encodeCursor(p.Sort, p.Desc, items[0], true)
+	}
+	return result
+}
+
+// matches applies the filters of the query to e. It is the in-process
+// equivalent of mongoFilter.
+func (p *listPlan) matches(e *models.Experience) bool {
+	if (e.DeletedAt != nil) != p.trashed || !p.scope.allows(e) {
+		return false
+	}
+	if p.Text != "" {
+		needle := strings.ToLower(p.Text)
+		haystack := []string{e.Company, e.Title, e.Location, e.Summary}
+		haystack = append(haystack, e.Achievements...)
+		haystack = append(haystack, e.Skills...)
+		found := false
+		for _, s := range haystack {
+			if strings.Contains(strings.ToLower(s), needle) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, tag := range p.Tags {
+		found := false
+		for _, skill := range e.Skills {
+			if skill == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if p.EmploymentType != "" && e.EmploymentType != p.EmploymentType {
+		return false
+	}
+	if p.Remote != nil && e.Remote != *p.Remote {
+		return false
+	}
+	return inRange(e.StartDate, p.StartFrom, p.StartTo) && inRange(e.CreatedAt, p.CreatedFrom, p.CreatedTo)
+}
+
+func inRange(t time.Time, from, to *time.Time) bool {
+	if from != nil && t.Before(*from) {
+		return false
+	}
+	if to != nil && t.After(*to) {
+		return false
+	}
+	return true
+}
+
+// less orders items in fetch order, breaking ties by id.
+func (p *listPlan) less(a, b *models.Experience) bool {
+	c := compareValues(p.field.value(a), p.field.value(b))
+	if c == 0 {
+		c = compareIds(a.ID, b.ID)
+	}
+	if p.ascending {
+		return c < 0
+	}
+	return c > 0
+}
+
+// afterKeyset reports whether e comes after the cursor position in fetch
+// order.
+func (p *listPlan) afterKeyset(e *models.Experience) bool {
+	c := compareValues(p.field.value(e), p.keyset.value)
+	if c == 0 {
+		c = compareIds(e.ID, p.keyset.id)
+	}
+	if p.ascending {
+		return c > 0
+	}
+	return c < 0
+}
+
+func compareValues(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+func compareIds(a, b primitive.ObjectID) int {
+	return strings.Compare(a.Hex(), b.Hex())
+}
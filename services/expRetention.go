@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RunTrashRetention purges the experiences that have been in the trash for
+// longer than retention, once straight away and then every interval, until
+// ctx is done. Each pass is bounded by interval so a stuck backend cannot
+// pile up passes.
+func RunTrashRetention(ctx context.Context, svc ExperienceService, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purgeExpired(ctx, svc, retention, interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purgeExpired(ctx context.Context, svc ExperienceService, retention, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cutoff := time.Now().Add(-retention)
+	purged, err := svc.PurgeDeleted(ctx, cutoff)
+	if len(purged) > 0 {
+		slog.Info("purged expired trash", slog.Int("count", len(purged)), slog.Time("deletedBefore", cutoff))
+	}
+	if err != nil && ctx.Err() == nil {
+		slog.Error("purge expired trash", slog.Any("err", err))
+	}
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunTrashRetention(t *testing.T) {
+	s := NewMemoryExperienceService()
+	expired, err := s.Create(context.Background(), historyDto("expired"))
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(context.Background(), expired.ID, Precondition{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunTrashRetention(ctx, s, time.Nanosecond, time.Hour)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool {
+		trash, err := s.Trash(context.Background(), ListQuery{})
+		return err == nil && len(trash.Items) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunTrashRetention did not stop with its context")
+	}
+}
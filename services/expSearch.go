@@ -0,0 +1,368 @@
+package services
+
+import (
+	"GO-Project/models"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// searchCandidateLimit bounds how many documents the Mongo backend hands to
+// the scorer for a single search, and so its SearchResult.Total.
+const searchCandidateLimit = 500
+
+// SearchQuery is a free-text search over experiences.
+type SearchQuery struct {
+	Text  string
+	Limit int
+	// Prefix lets a term match the start of a longer word, e.g. "mong" finds
+	// "mongodb".
+	Prefix bool
+	// Fuzzy lets a term of four or more letters match a word one edit away,
+	// e.g. "kubernetse" finds "kubernetes".
+	Fuzzy bool
+}
+
+// SearchResult lists the best matches, most relevant first.
+type SearchResult struct {
+	Hits  []SearchHit
+	Total int
+	Limit int
+}
+
+type SearchHit struct {
+	Experience *models.Experience
+	Score      float64
+	Highlights []Highlight
+}
+
+// Highlight is an excerpt of a matching field with every matched word
+// wrapped in <mark>. The rest of the text is HTML-escaped.
+type Highlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"`
+}
+
+// searchField is a field taking part in search. The weights are mirrored by
+// the Mongo text index.
+type searchField struct {
+	name   string
+	weight float64
+	values func(e *models.Experience) []string
+}
+
+var searchFields = []searchField{
+	{"title", 5, func(e *models.Experience) []string { return []string{e.Title} }},
+	{"company", 4, func(e *models.Experience) []string { return []string{e.Company} }},
+	{"skills", 3, func(e *models.Experience) []string { return e.Skills }},
+	{"location", 2, func(e *models.Experience) []string { return []string{e.Location} }},
+	{"summary", 1, func(e *models.Experience) []string { return []string{e.Summary} }},
+	{"achievements", 1, func(e *models.Experience) []string { return e.Achievements }},
+}
+
+// Relative value of each kind of match; an exact word counts fully.
+const (
+	matchNone   = 0
+	matchFuzzy  = 0.4
+	matchPrefix = 0.7
+	matchExact  = 1
+)
+
+// token is a word of a field value with its byte offsets.
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize splits s into lower-cased runs of letters and digits.
+func tokenize(s string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range s {
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case word && start < 0:
+			start = i
+		case !word && start >= 0:
+			tokens = append(tokens, token{strings.ToLower(s[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(s[start:]), start, len(s)})
+	}
+	return tokens
+}
+
+// searchTerms returns the distinct words of a query.
+func searchTerms(text string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, t := range tokenize(text) {
+		if !seen[t.text] {
+			seen[t.text] = true
+			terms = append(terms, t.text)
+		}
+	}
+	return terms
+}
+
+// newSearchPlan applies defaults and rejects queries without any words.
+func newSearchPlan(q SearchQuery) (SearchQuery, []string, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	terms := searchTerms(q.Text)
+	if len(terms) == 0 {
+		return q, nil, &Error{
+			Kind:    ErrValidation,
+			Message: "request validation failed",
+			Fields:  []FieldError{{Field: "q", Rule: "required", Message: "must contain at least one word"}},
+		}
+	}
+	return q, terms, nil
+}
+
+// match grades how well the word matches the query term.
+func match(word, term string, q SearchQuery) float64 {
+	switch {
+	case word == term:
+		return matchExact
+	case q.Prefix && strings.HasPrefix(word, term):
+		return matchPrefix
+	case q.Fuzzy && utf8.RuneCountInString(term) >= 4 && withinOneEdit(word, term):
+		return matchFuzzy
+	}
+	return matchNone
+}
+
+// withinOneEdit reports whether a can be turned into b by inserting,
+// deleting or substituting a single rune.
+func withinOneEdit(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) > len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(rb)-len(ra) > 1 {
+		return false
+	}
+	i := 0
+	for i < len(ra) && ra[i] == rb[i] {
+		i++
+	}
+	switch {
+	case i == len(rb):
+		return true
+	case len(ra) == len(rb):
+		return string(ra[i+1:]) == string(rb[i+1:])
+	default:
+		return string(ra[i:]) == string(rb[i+1:])
+	}
+}
+
+// score ranks e against the query terms. Every term contributes its best
+// match in each field, weighted by the field and damped by repetition, so
+// experiences matching more terms, in more important fields, come first.
+// A zero score means e does not match.
+func score(e *models.Experience, terms []string, q SearchQuery) (float64, []Highlight) {
+	var total float64
+	var highlights []Highlight
+	for _, field := range searchFields {
+		best := make([]float64, len(terms))
+		count := make([]int, len(terms))
+		snippet := ""
+		for _, value := range field.values(e) {
+			matched := false
+			for _, tok := range tokenize(value) {
+				for i, term := range terms {
+					if m := match(tok.text, term, q); m > 0 {
+						best[i] = math.Max(best[i], m)
+						count[i]++
+						matched = true
+					}
+				}
+			}
+			if matched && snippet == "" {
+				snippet = highlight(value, terms, q)
+			}
+		}
+		for i := range terms {
+			if count[i] > 0 {
+				total += field.weight * best[i] * (1 + math.Log(float64(count[i])))
+			}
+		}
+		if snippet != "" {
+			highlights = append(highlights, Highlight{Field: field.name, Snippet: snippet})
+		}
+	}
+	return total, highlights
+}
+
+// snippetRadius is how much text, in bytes, is kept either side of the
+// first match in a long value.
+const snippetRadius = 80
+
+// highlight returns an excerpt of value around its first match with every
+// matching word marked.
+func highlight(value string, terms []string, q SearchQuery) string {
+	var marks []token
+	for _, tok := range tokenize(value) {
+		for _, term := range terms {
+			if match(tok.text, term, q) > 0 {
+				marks = append(marks, tok)
+				break
+			}
+		}
+	}
+	if len(marks) == 0 {
+		return ""
+	}
+
+	from, to := 0, len(value)
+	if from < marks[0].start-snippetRadius {
+		from = min(wordBoundary(value, marks[0].start-snippetRadius)+1, marks[0].start)
+	}
+	if to > marks[0].end+snippetRadius {
+		to = wordBoundary(value, marks[0].end+snippetRadius)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range marks {
+		if m.start < from || m.end > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(value[pos:m.start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(value[m.start:m.end]))
+		b.WriteString("</mark>")
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(value[pos:to]))
+	if to < len(value) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// wordBoundary moves i forward to the next space so snippets do not start
+// or end mid-word, staying on a rune boundary.
+func wordBoundary(s string, i int) int {
+	for i < len(s) && (!utf8.RuneStart(s[i]) || !unicode.IsSpace(rune(s[i]))) {
+		i++
+	}
+	return i
+}
+
+// rank scores the candidates and keeps the best q.Limit, most relevant
+// first and ties in insertion order.
+func rank(candidates []*models.Experience, terms []string, q SearchQuery) *SearchResult {
+	hits := []SearchHit{}
+	for _, e := range candidates {
+		if s, highlights := score(e, terms, q); s > 0 {
+			hits = append(hits, SearchHit{Experience: e, Score: math.Round(s*1000) / 1000, Highlights: highlights})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return compareIds(hits[i].Experience.ID, hits[j].Experience.ID) < 0
+	})
+
+	result := &SearchResult{Hits: hits, Total: len(hits), Limit: q.Limit}
+	if len(hits) > q.Limit {
+		result.Hits = hits[:q.Limit]
+	}
+	return result
+}
+
+// searchIndex is an inverted index from words to the experiences containing
+// them, used by backends without a native text index.
+type searchIndex struct {
+	postings map[string]map[primitive.ObjectID]struct{}
+	// vocabulary is the sorted list of indexed words, for prefix and fuzzy
+	// lookups.
+	vocabulary []string
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{postings: map[string]map[primitive.ObjectID]struct{}{}}
+}
+
+func words(e *models.Experience) map[string]bool {
+	set := map[string]bool{}
+	for _, field := range searchFields {
+		for _, value := range field.values(e) {
+			for _, tok := range tokenize(value) {
+				set[tok.text] = true
+			}
+		}
+	}
+	return set
+}
+
+func (ix *searchIndex) add(e *models.Experience) {
+	for word := range words(e) {
+		ids, ok := ix.postings[word]
+		if !ok {
+			ids = map[primitive.ObjectID]struct{}{}
+			ix.postings[word] = ids
+			i := sort.SearchStrings(ix.vocabulary, word)
+			ix.vocabulary = append(ix.vocabulary, "")
+			copy(ix.vocabulary[i+1:], ix.vocabulary[i:])
+			ix.vocabulary[i] = word
+		}
+		ids[e.ID] = struct{}{}
+	}
+}
+
+func (ix *searchIndex) remove(e *models.Experience) {
+	for word := range words(e) {
+		ids := ix.postings[word]
+		delete(ids, e.ID)
+		if len(ids) == 0 {
+			delete(ix.postings, word)
+			i := sort.SearchStrings(ix.vocabulary, word)
+			ix.vocabulary = append(ix.vocabulary[:i], ix.vocabulary[i+1:]...)
+		}
+	}
+}
+
+// lookup returns the ids of experiences containing a word matching any of
+// the terms. It does not modify the index.
+func (ix *searchIndex) lookup(terms []string, q SearchQuery) map[primitive.ObjectID]struct{} {
+	found := map[primitive.ObjectID]struct{}{}
+	collect := func(word string) {
+		for id := range ix.postings[word] {
+			found[id] = struct{}{}
+		}
+	}
+	for _, term := range terms {
+		collect(term)
+		if q.Prefix {
+			for i := sort.SearchStrings(ix.vocabulary, term); i < len(ix.vocabulary) && strings.HasPrefix(ix.vocabulary[i], term); i++ {
+				collect(ix.vocabulary[i])
+			}
+		}
+		if q.Fuzzy && utf8.RuneCountInString(term) >= 4 {
+			for _, word := range ix.vocabulary {
+				if withinOneEdit(word, term) {
+					collect(word)
+				}
+			}
+		}
+	}
+	return found
+}
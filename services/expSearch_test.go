@@ -0,0 +1,50 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithinOneEdit(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"golang", "golang", true},
+		{"golang", "golan", true},
+		{"golan", "golang", true},
+		{"golang", "gulang", true},
+		{"golang", "xgolang", true},
+		{"golang", "glang", true},
+		{"golang", "gloang", false},
+		{"golang", "go", false},
+		{"ไทย", "ไทบ", true},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, withinOneEdit(tt.a, tt.b), "%q %q", tt.a, tt.b)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	var words []string
+	for _, tok := range tokenize("Node.js & MongoDB, C++ (ไทย)") {
+		words = append(words, tok.text)
+	}
+	require.Equal(t, []string{"node", "js", "mongodb", "c", "ไทย"}, words)
+}
+
+// TestSearchPatternCoversMatches checks that the Mongo candidate pattern
+// finds everything the scorer would accept.
+func TestSearchPatternCoversMatches(t *testing.T) {
+	q := SearchQuery{Prefix: true, Fuzzy: true}
+	pattern := regexp.MustCompile("(?i)" + searchPattern([]string{"golang", "k8s"}, q))
+
+	for _, word := range []string{"golang", "GOLANG", "golangci", "golan", "gollang", "gxlang", "k8s", "k8sctl"} {
+		require.True(t, pattern.MatchString("using "+word+" daily"), word)
+	}
+	for _, word := range []string{"glong", "k9s"} {
+		require.False(t, pattern.MatchString("using "+word+" daily"), word)
+	}
+}
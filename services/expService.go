@@ -2,102 +2,705 @@ package services
 
 import (
 	"GO-Project/models"
+	"GO-Project/patch"
 	"context"
-	"fmt"
+	"errors"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ExperienceService stores experiences. Every method only sees the
+// experiences owned by the principal of ctx, or all of them with
+// WithAllOwners and without a principal; the others are reported as not
+// found.
 type ExperienceService interface {
-	Create(ctx context.Context, experience *models.ExperienceDto) error
-	Update(id primitive.ObjectID, experience *models.ExperienceDto) error
-	FindById(id primitive.ObjectID) (*models.ExperienceDto, error)
-	FindAll() ([]*models.ExperienceDto, error)
-	Delete(id primitive.ObjectID) error
+	Create(ctx context.Context, experience *models.ExperienceDto) (*models.Experience, error)
+	// Update, Patch and Delete only write while cond holds and fail with
+	// ErrPreconditionFailed otherwise.
+	Update(ctx context.Context, id primitive.ObjectID, experience *models.ExperienceDto, cond Precondition) (*models.Experience, error)
+	// Patch applies p to the stored experience and validates the result as
+	// a whole. Concurrent writes never interleave with it.
+	Patch(ctx context.Context, id primitive.ObjectID, p patch.Patch, cond Precondition) (*models.Experience, error)
+	FindById(ctx context.Context, id primitive.ObjectID) (*models.Experience, error)
+	FindAll(ctx context.Context, query ListQuery) (*ListResult, error)
+	Search(ctx context.Context, query SearchQuery) (*SearchResult, error)
+	// Delete moves the experience to the trash. Trashed experiences are
+	// hidden from every method but the ones below.
+	Delete(ctx context.Context, id primitive.ObjectID, cond Precondition) error
+	// Trash lists the deleted experiences, filtered and paged like FindAll.
+	Trash(ctx context.Context, query ListQuery) (*ListResult, error)
+	// Undelete takes an experience out of the trash.
+	Undelete(ctx context.Context, id primitive.ObjectID, cond Precondition) (*models.Experience, error)
+	// Purge permanently removes an experience from the trash.
+	Purge(ctx context.Context, id primitive.ObjectID, cond Precondition) error
+	// PurgeDeleted permanently removes every experience deleted at or
+	// before cutoff and returns their ids.
+	PurgeDeleted(ctx context.Context, cutoff time.Time) ([]primitive.ObjectID, error)
+	// Bulk runs a batch of writes and reports the outcome of each in a
+	// result at the same index. Every operation that can be applied is;
+	// with atomic set, either all of them are or none is. The error is only
+	// for batches that could not be run at all.
+	Bulk(ctx context.Context, ops []BulkOperation, atomic bool) ([]BulkResult, error)
+	// AssignOwner gives owner every experience without one, trashed ones
+	// included, and returns how many it changed. Those are the experiences
+	// created before ownership existed or with authentication disabled,
+	// which only principals acting on every owner's experiences see.
+	AssignOwner(ctx context.Context, owner string) (int64, error)
 }
 
 type experienceServiceImpl struct {
 	expCollection *mongo.Collection
-	ctx           context.Context
 }
 
-func NewExperienceService(client *mongo.Client) ExperienceService {
-	collection := client.Database("TODOLIST").Collection("experience")
+func NewExperienceService(client *mongo.Client, database, collection string) ExperienceService {
+	coll := client.Database(database).Collection(collection)
 	return &experienceServiceImpl{
-		expCollection: collection,
-		ctx:           context.TODO(),
+		expCollection: coll,
 	}
 }
 
-func (e *experienceServiceImpl) Create(ctx context.Context, exp *models.ExperienceDto) error {
+func (e *experienceServiceImpl) Create(ctx context.Context, exp *models.ExperienceDto) (*models.Experience, error) {
 	var experience = models.Experience{
-		ID:         primitive.NewObjectID(),
-		Experience: exp.Experience,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Time{},
+		ID:        primitive.NewObjectID(),
+		Version:   1,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Time{},
+		OwnerID:   ownerOf(ctx),
 	}
-	_, err := e.expCollection.InsertOne(ctx, experience)
-	return err
+	exp.ApplyTo(&experience)
+
+	if _, err := e.expCollection.InsertOne(ctx, experience); err != nil {
+		return nil, wrapMongoError(err, experience.ID)
+	}
+	return &experience, nil
 }
 
-func (e *experienceServiceImpl) Update(id primitive.ObjectID, exp *models.ExperienceDto) error {
-	var experience models.ExperienceDto
+func (e *experienceServiceImpl) Update(ctx context.Context, id primitive.ObjectID, exp *models.ExperienceDto, cond Precondition) (*models.Experience, error) {
+	return e.replace(ctx, id, false, cond, func(experience *models.Experience) error {
+		exp.ApplyTo(experience)
+		experience.UpdatedAt = time.Now()
+		return nil
+	})
+}
 
-	err := e.expCollection.FindOne(e.ctx, bson.M{"_id": id}).Decode(&experience)
-	if err != nil {
-		return err
+func (e *experienceServiceImpl) Patch(ctx context.Context, id primitive.ObjectID, p patch.Patch, cond Precondition) (*models.Experience, error) {
+	return e.replace(ctx, id, false, cond, func(experience *models.Experience) error {
+		dto, err := applyPatch(experience, p)
+		if err != nil {
+			return err
+		}
+		dto.ApplyTo(experience)
+		experience.UpdatedAt = time.Now()
+		return nil
+	})
+}
+
+// writeAttempts bounds how often a write re-reads a document that was
+// changed between its read and its compare-and-swap.
+const writeAttempts = 3
+
+// replace rewrites the experience, live or trashed, after change has been
+// applied to a copy of it and its version bumped. The write only succeeds if
+// the document still has the version that was read, so cond is checked
+// against exactly the state being replaced.
+func (e *experienceServiceImpl) replace(ctx context.Context, id primitive.ObjectID, trashed bool, cond Precondition, change func(experience *models.Experience) error) (*models.Experience, error) {
+	for attempt := 1; ; attempt++ {
+		current, err := e.findOne(ctx, id, trashed)
+		if err != nil {
+			return nil, err
+		}
+		if err := cond.check(current); err != nil {
+			return nil, err
+		}
+		experience := current.Clone()
+		if err := change(experience); err != nil {
+			return nil, err
+		}
+		experience.Version++
+
+		// Replacing the whole document also rewrites version 1 documents
+		// in the current layout.
+		result, err := e.expCollection.ReplaceOne(ctx, versionFilter(id, current.Version), experience)
+		if err != nil {
+			return nil, wrapMongoError(err, id)
+		}
+		if result.MatchedCount == 1 {
+			return experience, nil
+		}
+		if attempt == writeAttempts {
+			return nil, modifiedConcurrently(id)
+		}
 	}
+}
 
-	exp.UpdatedAt = time.Now()
+func modifiedConcurrently(id primitive.ObjectID) error {
+	return newError(ErrConflict, nil, "experience %s was modified concurrently", id.Hex())
+}
 
-	update := bson.M{
-		"experience": exp.Experience,
-		"updatedAt":  exp.UpdatedAt,
+// versionFilter matches the document only while it still has the given
+// version. Documents written before versioning have no version field and
+// count as version 1.
+func versionFilter(id primitive.ObjectID, version int64) bson.M {
+	if version == 1 {
+		return bson.M{"_id": id, "version": bson.M{"$in": bson.A{1, nil}}}
 	}
-	fmt.Println(update)
+	return bson.M{"_id": id, "version": version}
+}
 
-	_, err = e.expCollection.UpdateOne(e.ctx, bson.M{"_id": id}, bson.M{"$set": update})
-	return err
+func (e *experienceServiceImpl) FindById(ctx context.Context, id primitive.ObjectID) (*models.Experience, error) {
+	return e.findOne(ctx, id, false)
 }
 
-func (e *experienceServiceImpl) FindById(id primitive.ObjectID) (*models.ExperienceDto, error) {
-	var experience models.ExperienceDto
+// findOne reads a live experience, or with trashed set, a deleted one, of
+// the scope of ctx.
+func (e *experienceServiceImpl) findOne(ctx context.Context, id primitive.ObjectID, trashed bool) (*models.Experience, error) {
+	var experience models.Experience
 
-	err := e.expCollection.FindOne(e.ctx, bson.M{"_id": id}).Decode(&experience)
+	filter := scopeOf(ctx).restrict(bson.M{"_id": id, "deletedAt": trashFilter(trashed)})
+	err := e.expCollection.FindOne(ctx, filter).Decode(&experience)
 	if err != nil {
-		return nil, err
+		return nil, wrapMongoError(err, id)
 	}
 
+	experience.Upgrade()
 	return &experience, nil
 }
 
-func (e *experienceServiceImpl) FindAll() ([]*models.ExperienceDto, error) {
-	cursor, err := e.expCollection.Find(e.ctx, bson.M{})
+func (e *experienceServiceImpl) FindAll(ctx context.Context, query ListQuery) (*ListResult, error) {
+	return e.list(ctx, query, false)
+}
+
+func (e *experienceServiceImpl) list(ctx context.Context, query ListQuery, trashed bool) (*ListResult, error) {
+	plan, err := newListPlan(query)
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(e.ctx)
+	plan.trashed = trashed
+	plan.scope = scopeOf(ctx)
 
-	var experiences []*models.ExperienceDto
-	err = cursor.All(e.ctx, &experiences)
+	filter := mongoFilter(plan)
+	total, err := e.expCollection.CountDocuments(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+
+	if plan.keyset != nil {
+		filter = bson.M{"$and": bson.A{filter, mongoKeyset(plan)}}
+	}
+	direction := 1
+	if !plan.ascending {
+		direction = -1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: plan.field.bson, Value: direction}, {Key: "_id", Value: direction}}).
+		SetSkip(plan.skip()).
+		SetLimit(int64(plan.Limit) + 1)
+
+	cursor, err := e.expCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+	defer cursor.Close(ctx)
+
+	var experiences []*models.Experience
+	err = cursor.All(ctx, &experiences)
+	if err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+
+	for _, experience := range experiences {
+		experience.Upgrade()
+	}
+	return plan.finish(experiences, total), nil
+}
+
+// mongoFilter translates the filters of a query into a Mongo filter
+// document. It must select the same experiences as listPlan.matches.
+func mongoFilter(p *listPlan) bson.M {
+	filter := p.scope.restrict(bson.M{"deletedAt": trashFilter(p.trashed)})
+	if p.Text != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(p.Text), Options: "i"}
+		// experience is searched as well so that version 1 documents, not
+		// yet rewritten, match on their summary.
+		var or bson.A
+		for _, field := range []string{"company", "title", "location", "summary", "achievements", "skills", "experience"} {
+			or = append(or, bson.M{field: pattern})
+		}
+		filter["$or"] = or
+	}
+	if len(p.Tags) > 0 {
+		filter["skills"] = bson.M{"$all": p.Tags}
+	}
+	if p.EmploymentType != "" {
+		filter["employmentType"] = p.EmploymentType
 	}
+	if p.Remote != nil {
+		filter["remote"] = *p.Remote
+	}
+	if r := mongoRange(p.StartFrom, p.StartTo); r != nil {
+		filter["startDate"] = r
+	}
+	if r := mongoRange(p.CreatedFrom, p.CreatedTo); r != nil {
+		filter["createdAt"] = r
+	}
+	return filter
+}
 
-	return experiences, nil
+// trashFilter matches the deletedAt of trashed or of live experiences. Live
+// ones have no deletedAt at all, which a nil comparison matches.
+func trashFilter(trashed bool) any {
+	if trashed {
+		return bson.M{"$ne": nil}
+	}
+	return nil
+}
+
+func mongoRange(from, to *time.Time) bson.M {
+	if from == nil && to == nil {
+		return nil
+	}
+	r := bson.M{}
+	if from != nil {
+		r["$gte"] = *from
+	}
+	if to != nil {
+		r["$lte"] = *to
+	}
+	return r
 }
 
-func (e *experienceServiceImpl) Delete(id primitive.ObjectID) error {
-	var experience models.ExperienceDto
+// mongoKeyset selects the documents after the cursor position in fetch
+// order.
+func mongoKeyset(p *listPlan) bson.M {
+	op := "$gt"
+	if !p.ascending {
+		op = "$lt"
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{p.field.bson: bson.M{op: p.keyset.value}},
+		bson.M{p.field.bson: p.keyset.value, "_id": bson.M{op: p.keyset.id}},
+	}}
+}
 
-	err := e.expCollection.FindOne(e.ctx, bson.M{"_id": id}).Decode(&experience)
+func (e *experienceServiceImpl) Search(ctx context.Context, query SearchQuery) (*SearchResult, error) {
+	query, terms, err := newSearchPlan(query)
 	if err != nil {
+		return nil, err
+	}
+
+	scope := scopeOf(ctx)
+	found := map[primitive.ObjectID]*models.Experience{}
+	collect := func(filter bson.M, opts *options.FindOptions) error {
+		filter["deletedAt"] = trashFilter(false)
+		scope.restrict(filter)
+		cursor, err := e.expCollection.Find(ctx, filter, opts.SetLimit(searchCandidateLimit))
+		if err != nil {
+			return err
+		}
+		var experiences []*models.Experience
+		if err := cursor.All(ctx, &experiences); err != nil {
+			return err
+		}
+		for _, experience := range experiences {
+			experience.Upgrade()
+			found[experience.ID] = experience
+		}
+		return nil
+	}
+
+	// The text index finds whole words. Prefix and fuzzy matches, and every
+	// match when the index is missing, come from a regular expression scan;
+	// both only gather candidates for the shared scorer.
+	textIndexed := true
+	err = collect(
+		bson.M{"$text": bson.M{"$search": strings.Join(terms, " ")}},
+		options.Find().
+			SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+			SetSort(bson.M{"score": bson.M{"$meta": "textScore"}}),
+	)
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorCode(errIndexNotFound) {
+		textIndexed, err = false, nil
+	}
+	if err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+
+	if query.Prefix || query.Fuzzy || !textIndexed {
+		pattern := primitive.Regex{Pattern: searchPattern(terms, query), Options: "i"}
+		var or bson.A
+		for _, field := range searchFields {
+			or = append(or, bson.M{field.name: pattern})
+		}
+		or = append(or, bson.M{"experience": pattern})
+		if err := collect(bson.M{"$or": or}, options.Find().SetSort(bson.M{"_id": 1})); err != nil {
+			return nil, wrapMongoError(err, primitive.NilObjectID)
+		}
+	}
+
+	candidates := make([]*models.Experience, 0, len(found))
+	for _, experience := range found {
+		candidates = append(candidates, experience)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return compareIds(candidates[i].ID, candidates[j].ID) < 0
+	})
+	return rank(candidates, terms, query), nil
+}
+
+// errIndexNotFound is returned by $text queries on a collection without a
+// text index.
+const errIndexNotFound = 27
+
+// searchPattern matches every text containing a word that could match one
+// of the terms. It may match more; the scorer has the final say.
+func searchPattern(terms []string, q SearchQuery) string {
+	var alternatives []string
+	for _, term := range terms {
+		alternatives = append(alternatives, regexp.QuoteMeta(term))
+		if !q.Fuzzy || len([]rune(term)) < 4 {
+			continue
+		}
+		runes := []rune(term)
+		for i := range runes {
+			head, tail := regexp.QuoteMeta(string(runes[:i])), regexp.QuoteMeta(string(runes[i+1:]))
+			alternatives = append(alternatives,
+				head+"."+tail,
+				head+tail,
+				head+"."+regexp.QuoteMeta(string(runes[i:])),
+			)
+		}
+	}
+	return strings.Join(alternatives, "|")
+}
+
+// EnsureIndexes creates the indexes FindAll and Search rely on, after
+// backfilling the sort fields. It is idempotent and called once at startup.
+func EnsureIndexes(ctx context.Context, client *mongo.Client, database, collection string) error {
+	if err := backfillSortFields(ctx, client.Database(database).Collection(collection)); err != nil {
 		return err
 	}
 
-	_, err = e.expCollection.DeleteOne(e.ctx, bson.M{"_id": id})
+	var indexes []mongo.IndexModel
+	for _, name := range SortFieldNames() {
+		indexes = append(indexes,
+			mongo.IndexModel{Keys: bson.D{{Key: SortFields[name].bson, Value: 1}, {Key: "_id", Value: 1}}},
+			// Lists of a single owner, the ones made by everyone but
+			// admins.
+			mongo.IndexModel{Keys: bson.D{{Key: "ownerId", Value: 1}, {Key: SortFields[name].bson, Value: 1}, {Key: "_id", Value: 1}}},
+		)
+	}
+	indexes = append(indexes,
+		mongo.IndexModel{Keys: bson.D{{Key: "skills", Value: 1}}},
+		// Sparse, as only trashed experiences have the field; used by Trash
+		// and the retention purge.
+		mongo.IndexModel{Keys: bson.D{{Key: "deletedAt", Value: 1}}, Options: options.Index().SetSparse(true)},
+	)
+
+	// A collection holds a single text index. Words are not stemmed so that
+	// it matches exactly what the shared scorer does.
+	var keys, weights bson.D
+	for _, field := range searchFields {
+		keys = append(keys, bson.E{Key: field.name, Value: "text"})
+		weights = append(weights, bson.E{Key: field.name, Value: int(field.weight)})
+	}
+	keys = append(keys, bson.E{Key: "experience", Value: "text"})
+	indexes = append(indexes, mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName("experience_text").
+			SetWeights(weights).
+			SetDefaultLanguage("none"),
+	})
+
+	coll := client.Database(database).Collection(collection)
+	if _, err := coll.Indexes().CreateMany(ctx, indexes); err != nil {
+		return wrapMongoError(err, primitive.NilObjectID)
+	}
+	return nil
+}
+
+// backfillSortFields stores the zero value of every sort field missing from
+// a document, as schema version 1 documents may lack company, title and
+// startDate. Keyset filters never match a missing field, and Mongo orders
+// it before the zero value, so without this such documents would drop out
+// of cursor pages and sort unlike they do in the other backends, which
+// upgrade them to the zero value.
+func backfillSortFields(ctx context.Context, coll *mongo.Collection) error {
+	var zero models.Experience
+	zero.Upgrade()
+	for _, name := range SortFieldNames() {
+		field := SortFields[name]
+		_, err := coll.UpdateMany(ctx,
+			bson.M{field.bson: nil},
+			bson.M{"$set": bson.M{field.bson: field.value(&zero)}})
+		if err != nil {
+			return wrapMongoError(err, primitive.NilObjectID)
+		}
+	}
+	return nil
+}
+
+func (e *experienceServiceImpl) Delete(ctx context.Context, id primitive.ObjectID, cond Precondition) error {
+	_, err := e.replace(ctx, id, false, cond, func(experience *models.Experience) error {
+		now := time.Now()
+		experience.DeletedAt = &now
+		return nil
+	})
 	return err
 }
+
+func (e *experienceServiceImpl) Trash(ctx context.Context, query ListQuery) (*ListResult, error) {
+	return e.list(ctx, query, true)
+}
+
+func (e *experienceServiceImpl) Undelete(ctx context.Context, id primitive.ObjectID, cond Precondition) (*models.Experience, error) {
+	return e.replace(ctx, id, true, cond, func(experience *models.Experience) error {
+		experience.DeletedAt = nil
+		return nil
+	})
+}
+
+func (e *experienceServiceImpl) Purge(ctx context.Context, id primitive.ObjectID, cond Precondition) error {
+	for attempt := 1; ; attempt++ {
+		current, err := e.findOne(ctx, id, true)
+		if err != nil {
+			return err
+		}
+		if err := cond.check(current); err != nil {
+			return err
+		}
+
+		result, err := e.expCollection.DeleteOne(ctx, versionFilter(id, current.Version))
+		if err != nil {
+			return wrapMongoError(err, id)
+		}
+		if result.DeletedCount == 1 {
+			return nil
+		}
+		if attempt == writeAttempts {
+			return modifiedConcurrently(id)
+		}
+	}
+}
+
+func (e *experienceServiceImpl) PurgeDeleted(ctx context.Context, cutoff time.Time) ([]primitive.ObjectID, error) {
+	filter := scopeOf(ctx).restrict(bson.M{"deletedAt": bson.M{"$lte": cutoff}})
+	cursor, err := e.expCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+	var expired []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &expired); err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+
+	// Deleting one by one, still filtered on deletedAt, skips experiences
+	// restored in the meantime and reports exactly the ones purged.
+	purged := []primitive.ObjectID{}
+	for _, doc := range expired {
+		filter["_id"] = doc.ID
+		result, err := e.expCollection.DeleteOne(ctx, filter)
+		if err != nil {
+			return purged, wrapMongoError(err, doc.ID)
+		}
+		if result.DeletedCount == 1 {
+			purged = append(purged, doc.ID)
+		}
+	}
+	return purged, nil
+}
+
+func (e *experienceServiceImpl) AssignOwner(ctx context.Context, owner string) (int64, error) {
+	result, err := e.expCollection.UpdateMany(ctx,
+		bson.M{"ownerId": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"ownerId": owner}})
+	if err != nil {
+		return 0, wrapMongoError(err, primitive.NilObjectID)
+	}
+	return result.ModifiedCount, nil
+}
+
+func (e *experienceServiceImpl) Bulk(ctx context.Context, ops []BulkOperation, atomic bool) ([]BulkResult, error) {
+	if err := checkBulk(ops); err != nil {
+		return nil, err
+	}
+	current, err := e.bulkCurrent(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+	results, staged := stageBulk(ops, func(id primitive.ObjectID) *models.Experience { return current[id] }, ownerOf(ctx), time.Now())
+	if atomic && bulkFailed(results) {
+		return abortBulk(results), nil
+	}
+	if !atomic {
+		if err := e.bulkWrite(ctx, results, staged, current, false); err != nil {
+			return nil, err
+		}
+		return finishBulk(ops, results, staged), nil
+	}
+
+	session, err := e.expCollection.Database().Client().StartSession()
+	if err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+	defer session.EndSession(ctx)
+
+	written := results
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		// The callback runs again after transient errors, each time
+		// on the results of staging alone.
+		written = slices.Clone(results)
+		if err := e.bulkWrite(sc, written, staged, current, true); err != nil {
+			return nil, err
+		}
+		if bulkFailed(written) {
+			return nil, errBulkFailed
+		}
+		return nil, nil
+	})
+	var serverErr mongo.ServerError
+	switch {
+	case errors.Is(err, errBulkFailed):
+		return abortBulk(written), nil
+	case errors.As(err, &serverErr) && serverErr.HasErrorCode(errIllegalOperation):
+		return nil, newError(ErrUnavailable, err, "atomic batches need a MongoDB deployment with transactions")
+	case err != nil:
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+	return finishBulk(ops, written, staged), nil
+}
+
+// errBulkFailed aborts the transaction of an atomic batch with a failed
+// write.
+var errBulkFailed = errors.New("bulk write failed")
+
+// errIllegalOperation is returned for transactions on a standalone server.
+const errIllegalOperation = 20
+
+// bulkCurrent reads the live experiences of the scope of ctx the batch
+// updates or deletes.
+func (e *experienceServiceImpl) bulkCurrent(ctx context.Context, ops []BulkOperation) (map[primitive.ObjectID]*models.Experience, error) {
+	var ids []primitive.ObjectID
+	for _, op := range ops {
+		if op.Op != BulkCreate {
+			ids = append(ids, op.ID)
+		}
+	}
+	current := map[primitive.ObjectID]*models.Experience{}
+	if len(ids) == 0 {
+		return current, nil
+	}
+
+	cursor, err := e.expCollection.Find(ctx, scopeOf(ctx).restrict(bson.M{"_id": bson.M{"$in": ids}, "deletedAt": trashFilter(false)}))
+	if err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+	var experiences []*models.Experience
+	if err := cursor.All(ctx, &experiences); err != nil {
+		return nil, wrapMongoError(err, primitive.NilObjectID)
+	}
+	for _, experience := range experiences {
+		experience.Upgrade()
+		current[experience.ID] = experience
+	}
+	return current, nil
+}
+
+// bulkWrite writes the staged experiences of the operations that have not
+// failed with a single BulkWrite, replacing each stored one only while it
+// has the version read, and reports the writes that failed in results.
+// Ordered, it stops at the first failure, after which an atomic batch is
+// aborted anyway.
+func (e *experienceServiceImpl) bulkWrite(ctx context.Context, results []BulkResult, staged []*models.Experience, current map[primitive.ObjectID]*models.Experience, ordered bool) error {
+	var writes []mongo.WriteModel
+	var indexes []int
+	for i, experience := range staged {
+		if results[i].Err != nil {
+			continue
+		}
+		if previous, ok := current[experience.ID]; ok {
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(versionFilter(experience.ID, previous.Version)).
+				SetReplacement(experience))
+		} else {
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(experience))
+		}
+		indexes = append(indexes, i)
+	}
+	if len(writes) == 0 {
+		return nil
+	}
+
+	result, err := e.expCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(ordered))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			i := indexes[writeErr.Index]
+			results[i].Err = wrapMongoError(writeErr, results[i].ID)
+		}
+		if ordered {
+			return nil
+		}
+		err = nil
+	}
+	if err != nil {
+		return wrapMongoError(err, primitive.NilObjectID)
+	}
+
+	// A replace that lost a race matches nothing, which BulkWrite only
+	// reports in its count. The ones concerned are found by reading back
+	// what was written.
+	replaced := map[primitive.ObjectID]int{}
+	for _, i := range indexes {
+		if _, ok := current[results[i].ID]; ok && results[i].Err == nil {
+			replaced[results[i].ID] = i
+		}
+	}
+	if result != nil && result.MatchedCount == int64(len(replaced)) {
+		return nil
+	}
+	ids := make([]primitive.ObjectID, 0, len(replaced))
+	for id := range replaced {
+		ids = append(ids, id)
+	}
+	cursor, err := e.expCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return wrapMongoError(err, primitive.NilObjectID)
+	}
+	var stored []*models.Experience
+	if err := cursor.All(ctx, &stored); err != nil {
+		return wrapMongoError(err, primitive.NilObjectID)
+	}
+	for _, experience := range stored {
+		if i, ok := replaced[experience.ID]; ok && sameWrite(experience, staged[i]) {
+			delete(replaced, experience.ID)
+		}
+	}
+	for id, i := range replaced {
+		results[i].Err = modifiedConcurrently(id)
+	}
+	return nil
+}
+
+// sameWrite reports whether stored is the staged experience as written,
+// telling it apart from a concurrent write of the same version by the
+// timestamps of the batch, which MongoDB keeps to the millisecond.
+func sameWrite(stored, staged *models.Experience) bool {
+	sameTime := func(a, b *time.Time) bool {
+		return a == nil && b == nil || a != nil && b != nil && a.Equal(b.Truncate(time.Millisecond))
+	}
+	return stored.Version == staged.Version &&
+		sameTime(&stored.UpdatedAt, &staged.UpdatedAt) &&
+		sameTime(stored.DeletedAt, staged.DeletedAt)
+}
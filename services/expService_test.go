@@ -1,7 +1,9 @@
 package services
 
 import (
+	"GO-Project/auth"
 	"GO-Project/models"
+	"GO-Project/patch"
 	"context"
 	"testing"
 	"time"
@@ -17,7 +19,7 @@ func TestNewExperienceService(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Success", func(mt *mtest.T) {
-		something := NewExperienceService(mt.Client)
+		something := NewExperienceService(mt.Client, "TODOLIST", "experience")
 		require.NotNil(t, something)
 	})
 }
@@ -26,10 +28,10 @@ func TestCreate(t *testing.T) {
 
 	mt.Run("Failed, Created error", func(mt *mtest.T) {
 
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 
 		payload := &models.ExperienceDto{
-			Experience: "Hello",
+			Title: "Hello",
 		}
 
 		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
@@ -38,21 +40,21 @@ func TestCreate(t *testing.T) {
 			Message: "duplicate key error",
 		}))
 
-		err := e.Create(context.Background(), payload)
+		_, err := e.Create(context.Background(), payload)
 		require.Error(t, err)
 	})
 
 	mt.Run("OK", func(mt *mtest.T) {
 
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 
 		payload := &models.ExperienceDto{
-			Experience: "Hello11",
+			Title: "Hello11",
 		}
 
 		mt.AddMockResponses(mtest.CreateSuccessResponse())
 
-		err := e.Create(context.Background(), payload)
+		_, err := e.Create(context.Background(), payload)
 		require.NoError(t, err)
 	})
 }
@@ -60,41 +62,40 @@ func TestCreate(t *testing.T) {
 func TestUpdate(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 	mt.Run("Success", func(mt *mtest.T) {
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 
 		id := primitive.NewObjectID()
 		payload := &models.ExperienceDto{
-			Experience: "Hello00",
+			Title: "Hello00",
 		}
 
 		mt.AddMockResponses(
-			mtest.CreateCursorResponse(1, "services.mock", mtest.FirstBatch, bson.D{
+			mtest.CreateCursorResponse(0, "services.mock", mtest.FirstBatch, bson.D{
 				{Key: "_id", Value: id},
 				{Key: "experience", Value: "Hallo"},
 			}),
-			bson.D{
-				{Key: "ok", Value: 1},
-				{Key: "Value", Value: bson.D{
-					{Key: "_id", Value: id},
-					{Key: "experience", Value: payload.Experience},
-					{Key: "updatedAt", Value: time.Now()},
-				}},
-			},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}},
 		)
-		mt.AddMockResponses(bson.D{
-			{Key: "ok", Value: 1},
-			{Key: "Value", Value: bson.D{
-				{Key: "_id", Value: id},
-				{Key: "experience", Value: payload.Experience},
-				{Key: "updatedAt", Value: time.Now()},
-			}},
-		})
-		err := e.Update(id, payload)
+		updated, err := e.Update(context.Background(), id, payload, Precondition{})
 		require.NoError(t, err)
+		require.Equal(t, payload.Title, updated.Title)
+		require.Equal(t, int64(2), updated.Version)
+	})
+
+	mt.Run("Failed, stale If-Match", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "services.mock", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: id},
+			{Key: "version", Value: 2},
+		}))
+		_, err := e.Update(context.Background(), id, &models.ExperienceDto{Title: "x"}, Precondition{IfMatch: []int64{1}})
+		require.ErrorIs(t, err, ErrPreconditionFailed)
 	})
 
 	mt.Run("Failed, Updated Id not found", func(mt *mtest.T) {
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 
 		testErr := mtest.CommandError{
 			Message: mongo.ErrNoDocuments.Error(),
@@ -102,41 +103,127 @@ func TestUpdate(t *testing.T) {
 		}
 
 		mt.AddMockResponses(mtest.CreateCommandErrorResponse(testErr))
-		err := e.Update(primitive.NewObjectID(), &models.ExperienceDto{})
+		_, err := e.Update(context.Background(), primitive.NewObjectID(), &models.ExperienceDto{}, Precondition{})
 		require.Error(t, err)
 
 	})
 }
 
+func TestPatch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	found := func(id primitive.ObjectID) bson.D {
+		return mtest.CreateCursorResponse(0, "services.mock", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: id},
+			{Key: "company", Value: "Acme"},
+			{Key: "title", Value: "Developer"},
+			{Key: "startDate", Value: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+			{Key: "version", Value: 3},
+		})
+	}
+	replaced := func(n int) bson.D {
+		return bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: n}, {Key: "nModified", Value: n}}
+	}
+	p, err := patch.ParseMerge([]byte(`{"title": "Lead"}`))
+	require.NoError(t, err)
+
+	mt.Run("Success", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(found(id), replaced(1))
+		patched, err := e.Patch(context.Background(), id, p, Precondition{})
+		require.NoError(t, err)
+		require.Equal(t, "Lead", patched.Title)
+		require.Equal(t, "Acme", patched.Company)
+		require.Equal(t, int64(4), patched.Version)
+	})
+
+	mt.Run("Success after a concurrent write", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(found(id), replaced(0), found(id), replaced(1))
+		_, err := e.Patch(context.Background(), id, p, Precondition{})
+		require.NoError(t, err)
+	})
+
+	mt.Run("Failed, keeps conflicting", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		for i := 0; i < writeAttempts; i++ {
+			mt.AddMockResponses(found(id), replaced(0))
+		}
+		_, err := e.Patch(context.Background(), id, p, Precondition{})
+		require.ErrorIs(t, err, ErrConflict)
+	})
+}
+
 func TestById(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Success", func(mt *mtest.T) {
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 		id := primitive.NewObjectID()
 		payload := &models.ExperienceDto{
-			Experience: "Hello",
+			Title: "Hello",
 		}
 
 		mt.AddMockResponses(
 			mtest.CreateCursorResponse(1, "services.mock", mtest.FirstBatch, bson.D{
 				{Key: "_id", Value: id},
-				{Key: "experience", Value: payload.Experience},
+				{Key: "title", Value: payload.Title},
 			}))
 		mt.AddMockResponses(bson.D{
 			{Key: "ok", Value: 1},
 			{Key: "Value", Value: bson.D{
-				{Key: "experience", Value: payload.Experience},
+				{Key: "title", Value: payload.Title},
 			}},
 		})
 
-		payload, nil := e.FindById(id)
-		require.Nil(t, nil, *payload)
+		found, err := e.FindById(context.Background(), id)
+		require.NoError(t, err)
+		require.Equal(t, payload.Title, found.Title)
+
+	})
 
+	mt.Run("Success, legacy document upgraded", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "services.mock", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: id},
+				{Key: "experience", Value: "Five years of Go"},
+			}))
+
+		payload, err := e.FindById(context.Background(), id)
+		require.NoError(t, err)
+		require.Equal(t, "Five years of Go", payload.Summary)
+		require.Empty(t, payload.Experience)
+	})
+
+	mt.Run("Success, scoped to the owner", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "services.mock", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: id},
+				{Key: "ownerId", Value: "alice"},
+			}))
+
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+		found, err := e.FindById(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, "alice", found.OwnerID)
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		require.Equal(t, "alice", filter.Lookup("ownerId").StringValue())
 	})
 
 	mt.Run("Failed, FindById not found", func(mt *mtest.T) {
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 
 		testErr := mtest.CommandError{
 			Message: mongo.ErrNoDocuments.Error(),
@@ -144,7 +231,7 @@ func TestById(t *testing.T) {
 		}
 
 		mt.AddMockResponses(mtest.CreateCommandErrorResponse(testErr))
-		payload, nil := e.FindById(primitive.NewObjectID())
+		payload, nil := e.FindById(context.Background(), primitive.NewObjectID())
 		require.Error(t, nil, payload)
 	})
 
@@ -154,10 +241,10 @@ func TestFindAll(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 
 	mt.Run("Success: FindAll", func(mt *mtest.T) {
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 		id := primitive.NewObjectID()
 		payload := []*models.ExperienceDto{
-			{Experience: "Hello"},
+			{Title: "Hello"},
 		}
 
 		mt.AddMockResponses(
@@ -172,13 +259,51 @@ func TestFindAll(t *testing.T) {
 			}},
 		})
 
-		payload, err := e.FindAll()
-		require.Error(t, err, payload)
+		experiences, err := e.FindAll(context.Background(), ListQuery{})
+		require.Error(t, err, experiences)
 
 	})
 
+	mt.Run("Success, first page", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "TODOLIST.experience", mtest.FirstBatch, bson.D{{Key: "n", Value: 3}}),
+			mtest.CreateCursorResponse(0, "TODOLIST.experience", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "title", Value: "a"}},
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "title", Value: "b"}},
+			),
+		)
+
+		result, err := e.FindAll(context.Background(), ListQuery{Limit: 1, Tags: []string{"go"}})
+		require.NoError(t, err)
+		require.Equal(t, int64(3), result.Total)
+		require.Len(t, result.Items, 1)
+		require.Equal(t, "a", result.Items[0].Title)
+		require.NotEmpty(t, result.NextCursor)
+		require.Empty(t, result.PrevCursor)
+	})
+
+	mt.Run("Success, scoped to the owner", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, "services.mock", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: id},
+				{Key: "ownerId", Value: "alice"},
+			}))
+
+		ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+		found, err := e.FindById(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, "alice", found.OwnerID)
+		filter := mt.GetStartedEvent().Command.Lookup("filter").Document()
+		require.Equal(t, "alice", filter.Lookup("ownerId").StringValue())
+	})
+
 	mt.Run("Failed, FindById not found", func(mt *mtest.T) {
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 
 		testErr := mtest.CommandError{
 			Message: mongo.ErrNoDocuments.Error(),
@@ -186,15 +311,227 @@ func TestFindAll(t *testing.T) {
 		}
 
 		mt.AddMockResponses(mtest.CreateCommandErrorResponse(testErr))
-		payload, err := e.FindAll()
+		payload, err := e.FindAll(context.Background(), ListQuery{})
 		require.Error(t, err, payload)
 	})
 }
 
+func TestSearch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Success, text index", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "TODOLIST.experience", mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "title", Value: "Go developer"}, {Key: "score", Value: 1.5}},
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "title", Value: "Go and Go"}},
+		))
+
+		result, err := e.Search(context.Background(), SearchQuery{Text: "go"})
+		require.NoError(t, err)
+		require.Equal(t, 2, result.Total)
+		require.Equal(t, "Go and Go", result.Hits[0].Experience.Title)
+	})
+
+	mt.Run("Success, without text index", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+
+		mt.AddMockResponses(
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: errIndexNotFound, Message: "text index required for $text query"}),
+			mtest.CreateCursorResponse(0, "TODOLIST.experience", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "summary", Value: "Wrote Go"}},
+			),
+		)
+
+		result, err := e.Search(context.Background(), SearchQuery{Text: "go"})
+		require.NoError(t, err)
+		require.Len(t, result.Hits, 1)
+		require.Equal(t, "summary", result.Hits[0].Highlights[0].Field)
+	})
+
+	mt.Run("Failed", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "bad"}))
+		_, err := e.Search(context.Background(), SearchQuery{Text: "go"})
+		require.Error(t, err)
+	})
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("Skips experiences restored meanwhile", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		purged, restored := primitive.NewObjectID(), primitive.NewObjectID()
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "TODOLIST.experience", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: purged}},
+				bson.D{{Key: "_id", Value: restored}},
+			),
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}},
+			bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}},
+		)
+		ids, err := e.PurgeDeleted(context.Background(), time.Now())
+		require.NoError(t, err)
+		require.Equal(t, []primitive.ObjectID{purged}, ids)
+	})
+}
+
+func TestBulk(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	found := func(id primitive.ObjectID) bson.D {
+		return mtest.CreateCursorResponse(0, "TODOLIST.experience", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: id},
+			{Key: "company", Value: "Acme"},
+			{Key: "title", Value: "Developer"},
+			{Key: "version", Value: 3},
+		})
+	}
+	written := func(n int) bson.D {
+		return bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: n}, {Key: "nModified", Value: n}}
+	}
+	ops := func(id primitive.ObjectID) []BulkOperation {
+		return []BulkOperation{
+			{Op: BulkCreate, Experience: &models.ExperienceDto{Title: "New"}},
+			{Op: BulkUpdate, ID: id, Experience: &models.ExperienceDto{Title: "Lead"}},
+		}
+	}
+
+	mt.Run("Success", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(found(id), written(1), written(1))
+		results, err := e.Bulk(context.Background(), ops(id), false)
+		require.NoError(t, err)
+		require.NoError(t, results[0].Err)
+		require.Equal(t, "New", results[0].Experience.Title)
+		require.NoError(t, results[1].Err)
+		require.Equal(t, "Lead", results[1].Experience.Title)
+		require.Equal(t, int64(4), results[1].Experience.Version)
+	})
+
+	mt.Run("Failed, write lost a race", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		// The read back finds the version written by someone else.
+		mt.AddMockResponses(found(id), written(1), written(0),
+			mtest.CreateCursorResponse(0, "TODOLIST.experience", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: id},
+				{Key: "version", Value: 4},
+				{Key: "updatedAt", Value: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+			}))
+		results, err := e.Bulk(context.Background(), ops(id), false)
+		require.NoError(t, err)
+		require.NoError(t, results[0].Err)
+		require.ErrorIs(t, results[1].Err, ErrConflict)
+		require.Nil(t, results[1].Experience)
+	})
+
+	mt.Run("Failed, duplicate key", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(found(id),
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key error"}),
+			written(1))
+		results, err := e.Bulk(context.Background(), ops(id), false)
+		require.NoError(t, err)
+		require.ErrorIs(t, results[0].Err, ErrConflict)
+		require.NoError(t, results[1].Err)
+	})
+
+	mt.Run("Atomic, committed", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(found(id), written(1), written(1), bson.D{{Key: "ok", Value: 1}})
+		results, err := e.Bulk(context.Background(), ops(id), true)
+		require.NoError(t, err)
+		require.NoError(t, results[0].Err)
+		require.NoError(t, results[1].Err)
+
+		events := mt.GetAllStartedEvents()
+		require.Equal(t, "commitTransaction", events[len(events)-1].CommandName)
+	})
+
+	mt.Run("Atomic, rolled back", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		mt.AddMockResponses(found(id), written(1), written(0),
+			mtest.CreateCursorResponse(0, "TODOLIST.experience", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: id},
+				{Key: "version", Value: 4},
+			}),
+			bson.D{{Key: "ok", Value: 1}})
+		results, err := e.Bulk(context.Background(), ops(id), true)
+		require.NoError(t, err)
+		require.ErrorIs(t, results[0].Err, ErrAborted)
+		require.Nil(t, results[0].Experience)
+		require.ErrorIs(t, results[1].Err, ErrConflict)
+
+		events := mt.GetAllStartedEvents()
+		require.Equal(t, "abortTransaction", events[len(events)-1].CommandName)
+	})
+
+	mt.Run("Atomic, no transactions", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{
+			Code:    errIllegalOperation,
+			Message: "Transaction numbers are only allowed on a replica set member or mongos",
+		}))
+		_, err := e.Bulk(context.Background(), ops(primitive.NewObjectID())[:1], true)
+		require.ErrorIs(t, err, ErrUnavailable)
+	})
+
+	mt.Run("Atomic, aborted without writing", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "TODOLIST.experience", mtest.FirstBatch))
+		results, err := e.Bulk(context.Background(), ops(primitive.NewObjectID()), true)
+		require.NoError(t, err)
+		require.ErrorIs(t, results[0].Err, ErrAborted)
+		require.ErrorIs(t, results[1].Err, ErrNotFound)
+	})
+}
+
+func TestMongoFilter(t *testing.T) {
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	remote := false
+	plan, err := newListPlan(ListQuery{
+		Text:      "a.b",
+		Tags:      []string{"go"},
+		Remote:    &remote,
+		StartFrom: &from,
+	})
+	require.NoError(t, err)
+
+	filter := mongoFilter(plan)
+	require.Equal(t, bson.M{"$all": []string{"go"}}, filter["skills"])
+	require.Equal(t, false, filter["remote"])
+	require.Equal(t, bson.M{"$gte": from}, filter["startDate"])
+	require.Contains(t, filter["$or"], bson.M{"title": primitive.Regex{Pattern: `a\.b`, Options: "i"}})
+	require.NotContains(t, filter, "createdAt")
+	require.Nil(t, filter["deletedAt"])
+	require.Contains(t, filter, "deletedAt")
+
+	plan.trashed = true
+	require.Equal(t, bson.M{"$ne": nil}, mongoFilter(plan)["deletedAt"])
+
+	require.NotContains(t, filter, "ownerId")
+	plan.scope = ownerScope{restricted: true, owner: "alice"}
+	require.Equal(t, "alice", mongoFilter(plan)["ownerId"])
+}
+
 func TestDelete(t *testing.T) {
 	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
 	mt.Run("Success", func(mt *mtest.T) {
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 		id := primitive.NewObjectID()
 
 		mt.AddMockResponses(
@@ -205,13 +542,28 @@ func TestDelete(t *testing.T) {
 			{Key: "ok", Value: 1},
 		})
 
-		err := e.Delete(id)
+		err := e.Delete(context.Background(), id, Precondition{})
 		require.Error(t, err)
 
 	})
 
+	mt.Run("Success after a concurrent write", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		id := primitive.NewObjectID()
+
+		found := mtest.CreateCursorResponse(0, "services.mock", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: id},
+			{Key: "version", Value: 2},
+		})
+		replaced := func(n int) bson.D {
+			return bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: n}, {Key: "nModified", Value: n}}
+		}
+		mt.AddMockResponses(found, replaced(0), found, replaced(1))
+		require.NoError(t, e.Delete(context.Background(), id, Precondition{IfMatch: []int64{2}}))
+	})
+
 	mt.Run("Failed", func(mt *mtest.T) {
-		e := NewExperienceService(mt.Client)
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
 		id := primitive.NewObjectID()
 
 		testErr := mtest.CommandError{
@@ -220,7 +572,57 @@ func TestDelete(t *testing.T) {
 		}
 
 		mt.AddMockResponses(mtest.CreateCommandErrorResponse(testErr))
-		err := e.Delete(id)
+		err := e.Delete(context.Background(), id, Precondition{})
 		require.Error(t, err)
 	})
 }
+
+func TestEnsureIndexes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Backfills sort fields", func(mt *mtest.T) {
+		for range SortFieldNames() {
+			mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}})
+		}
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		require.NoError(t, EnsureIndexes(context.Background(), mt.Client, "TODOLIST", "experience"))
+
+		backfilled := map[string]bson.RawValue{}
+		for range SortFieldNames() {
+			update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+			require.True(t, update.Lookup("multi").Boolean())
+			set := update.Lookup("u", "$set").Document()
+			elements, err := set.Elements()
+			require.NoError(t, err)
+			require.Len(t, elements, 1)
+			name := elements[0].Key()
+			require.Equal(t, bson.TypeNull, update.Lookup("q", name).Type, name)
+			backfilled[name] = elements[0].Value()
+		}
+		require.Equal(t, "", backfilled["title"].StringValue())
+		require.Equal(t, "", backfilled["company"].StringValue())
+		require.Equal(t, bson.TypeDateTime, backfilled["startDate"].Type)
+		require.Equal(t, "createIndexes", mt.GetStartedEvent().CommandName)
+	})
+}
+
+func TestAssignOwner(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Success", func(mt *mtest.T) {
+		e := NewExperienceService(mt.Client, "TODOLIST", "experience")
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 2}, {Key: "nModified", Value: 2}})
+
+		assigned, err := e.AssignOwner(context.Background(), "alice")
+		require.NoError(t, err)
+		require.Equal(t, int64(2), assigned)
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		require.True(t, update.Lookup("multi").Boolean())
+		owners, err := update.Lookup("q", "ownerId", "$in").Array().Values()
+		require.NoError(t, err)
+		require.Len(t, owners, 2)
+		require.Equal(t, bson.TypeNull, owners[0].Type)
+		require.Equal(t, "alice", update.Lookup("u", "$set", "ownerId").StringValue())
+	})
+}
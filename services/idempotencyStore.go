@@ -0,0 +1,247 @@
+package services
+
+import (
+	"GO-Project/models"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IdempotencyStore keeps the outcome of requests made with an
+// Idempotency-Key until the record expires. Expired records count as
+// absent.
+type IdempotencyStore interface {
+	// Reserve claims r.Key for the request r describes, in progress until
+	// Complete or Release. If the key is already held, nothing is stored
+	// and the record holding it is returned instead.
+	Reserve(ctx context.Context, r *models.IdempotencyRecord) (*models.IdempotencyRecord, error)
+	// Complete stores the response of a reserved key.
+	Complete(ctx context.Context, r *models.IdempotencyRecord) error
+	// Release frees a key still in progress, so that the request can be
+	// retried.
+	Release(ctx context.Context, key string) error
+}
+
+// memoryIdempotencyStore keeps records in process memory, handing a
+// snapshot to persist after every write like the memory backend does.
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]models.IdempotencyRecord
+	persist func(records []models.IdempotencyRecord) error
+}
+
+// NewMemoryIdempotencyStore returns an IdempotencyStore that is lost when
+// the process exits.
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return newMemoryIdempotencyStore(nil, nil)
+}
+
+func newMemoryIdempotencyStore(records []models.IdempotencyRecord, persist func([]models.IdempotencyRecord) error) *memoryIdempotencyStore {
+	s := &memoryIdempotencyStore{records: make(map[string]models.IdempotencyRecord, len(records)), persist: persist}
+	for _, r := range records {
+		s.records[r.Key] = r
+	}
+	return s
+}
+
+func (s *memoryIdempotencyStore) Reserve(ctx context.Context, r *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	previous, ok := s.records[r.Key]
+	if ok && previous.ExpiresAt.After(now) {
+		return previous.Clone(), nil
+	}
+	for key, record := range s.records {
+		if !record.ExpiresAt.After(now) {
+			delete(s.records, key)
+		}
+	}
+
+	s.records[r.Key] = *r.Clone()
+	if err := s.save(); err != nil {
+		delete(s.records, r.Key)
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, r *models.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.records[r.Key]
+	if !ok || previous.Fingerprint != r.Fingerprint {
+		return idempotencyKeyLost(r.Key)
+	}
+	s.records[r.Key] = *r.Clone()
+	if err := s.save(); err != nil {
+		s.records[r.Key] = previous
+		return err
+	}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.records[key]
+	if !ok || previous.Status != 0 {
+		return nil
+	}
+	delete(s.records, key)
+	if err := s.save(); err != nil {
+		s.records[key] = previous
+		return err
+	}
+	return nil
+}
+
+// save hands the records, ordered by key, to the persistence hook. Callers
+// must hold s.mu.
+func (s *memoryIdempotencyStore) save() error {
+	if s.persist == nil {
+		return nil
+	}
+	records := make([]models.IdempotencyRecord, 0, len(s.records))
+	for _, r := range s.records {
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+	return s.persist(records)
+}
+
+// idempotencyKeyLost reports a key whose reservation expired or was taken
+// over before its request completed.
+func idempotencyKeyLost(key string) error {
+	return newError(ErrConflict, nil, "idempotency key %q is no longer reserved", key)
+}
+
+// idempotencyDocument is the on-disk layout of the file idempotency store.
+type idempotencyDocument struct {
+	Records []models.IdempotencyRecord `bson:"records"`
+}
+
+// NewFileIdempotencyStore returns an IdempotencyStore backed by a relaxed
+// extended JSON file at path, rewritten atomically on every write. Expired
+// records are dropped from it as new keys are reserved.
+func NewFileIdempotencyStore(path string) (IdempotencyStore, error) {
+	records, err := readIdempotencyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newMemoryIdempotencyStore(records, func(records []models.IdempotencyRecord) error {
+		data, err := bson.MarshalExtJSON(idempotencyDocument{Records: records}, false, false)
+		if err != nil {
+			return err
+		}
+		return writeFileAtomic(path, data)
+	}), nil
+}
+
+func readIdempotencyFile(path string) ([]models.IdempotencyRecord, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc idempotencyDocument
+	if err := bson.UnmarshalExtJSON(data, false, &doc); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return doc.Records, nil
+}
+
+type mongoIdempotencyStore struct {
+	coll *mongo.Collection
+}
+
+// NewMongoIdempotencyStore returns an IdempotencyStore keeping records in
+// the given collection. EnsureIdempotencyIndexes creates the TTL index that
+// removes expired ones.
+func NewMongoIdempotencyStore(client *mongo.Client, database, collection string) IdempotencyStore {
+	return &mongoIdempotencyStore{coll: client.Database(database).Collection(collection)}
+}
+
+// EnsureIdempotencyIndexes creates the TTL index on expiresAt. MongoDB
+// removes expired records in the background, up to a minute late, which is
+// why the store ignores them itself.
+func EnsureIdempotencyIndexes(ctx context.Context, client *mongo.Client, database, collection string) error {
+	_, err := client.Database(database).Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func (s *mongoIdempotencyStore) Reserve(ctx context.Context, r *models.IdempotencyRecord) (*models.IdempotencyRecord, error) {
+	for attempt := 1; ; attempt++ {
+		// The upsert takes over a missing or expired key. A live one is not
+		// matched, so the insert fails on the duplicate _id.
+		_, err := s.coll.ReplaceOne(ctx,
+			bson.M{"_id": r.Key, "expiresAt": bson.M{"$lte": time.Now()}},
+			r,
+			options.Replace().SetUpsert(true))
+		if err == nil {
+			return nil, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, wrapMongoError(err, primitive.NilObjectID)
+		}
+
+		var existing models.IdempotencyRecord
+		err = s.coll.FindOne(ctx, bson.M{"_id": r.Key}).Decode(&existing)
+		switch {
+		case err == nil:
+			return &existing, nil
+		case !errors.Is(err, mongo.ErrNoDocuments):
+			return nil, wrapMongoError(err, primitive.NilObjectID)
+		case attempt == writeAttempts:
+			return nil, newError(ErrConflict, err, "idempotency key %q was modified concurrently", r.Key)
+		}
+		// The record expired and was removed in between.
+	}
+}
+
+func (s *mongoIdempotencyStore) Complete(ctx context.Context, r *models.IdempotencyRecord) error {
+	result, err := s.coll.ReplaceOne(ctx, bson.M{"_id": r.Key, "fingerprint": r.Fingerprint}, r)
+	if err != nil {
+		return wrapMongoError(err, primitive.NilObjectID)
+	}
+	if result.MatchedCount == 0 {
+		return idempotencyKeyLost(r.Key)
+	}
+	return nil
+}
+
+func (s *mongoIdempotencyStore) Release(ctx context.Context, key string) error {
+	_, err := s.coll.DeleteOne(ctx, bson.M{"_id": key, "status": 0})
+	return wrapMongoError(err, primitive.NilObjectID)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"GO-Project/models"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func testIdempotencyStore(t *testing.T, s IdempotencyStore) {
+	ctx := context.Background()
+	record := &models.IdempotencyRecord{Key: "k1", Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)}
+
+	existing, err := s.Reserve(ctx, record)
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	existing, err = s.Reserve(ctx, &models.IdempotencyRecord{Key: "k1", Fingerprint: "b", ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, "a", existing.Fingerprint)
+	require.Zero(t, existing.Status, "still in progress")
+
+	record.Status, record.Body = 201, []byte(`{"id":1}`)
+	record.Header = map[string]string{"Location": "/x"}
+	require.NoError(t, s.Complete(ctx, record))
+	existing, err = s.Reserve(ctx, &models.IdempotencyRecord{Key: "k1", Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, 201, existing.Status)
+	require.Equal(t, `{"id":1}`, string(existing.Body))
+	require.Equal(t, "/x", existing.Header["Location"])
+
+	// Completed keys are kept; released ones can be reserved again.
+	require.NoError(t, s.Release(ctx, "k1"))
+	existing, err = s.Reserve(ctx, &models.IdempotencyRecord{Key: "k1", Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.NotNil(t, existing)
+
+	_, err = s.Reserve(ctx, &models.IdempotencyRecord{Key: "k2", Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.NoError(t, s.Release(ctx, "k2"))
+	existing, err = s.Reserve(ctx, &models.IdempotencyRecord{Key: "k2", Fingerprint: "b", ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Nil(t, existing)
+
+	// An expired key is free again.
+	_, err = s.Reserve(ctx, &models.IdempotencyRecord{Key: "k3", Fingerprint: "a", ExpiresAt: time.Now().Add(-time.Second)})
+	require.NoError(t, err)
+	existing, err = s.Reserve(ctx, &models.IdempotencyRecord{Key: "k3", Fingerprint: "b", ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Nil(t, existing)
+	require.ErrorIs(t, s.Complete(ctx, &models.IdempotencyRecord{Key: "k3", Fingerprint: "a", Status: 201}), ErrConflict)
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	testIdempotencyStore(t, NewMemoryIdempotencyStore())
+}
+
+func TestFileIdempotencyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "experiences.idempotency.json")
+	s, err := NewFileIdempotencyStore(path)
+	require.NoError(t, err)
+	testIdempotencyStore(t, s)
+
+	reopened, err := NewFileIdempotencyStore(path)
+	require.NoError(t, err)
+	existing, err := reopened.Reserve(context.Background(), &models.IdempotencyRecord{Key: "k1", Fingerprint: "c", ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, 201, existing.Status)
+	require.Equal(t, `{"id":1}`, string(existing.Body))
+}
+
+func TestMongoIdempotencyStore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Reserve, free key", func(mt *mtest.T) {
+		s := NewMongoIdempotencyStore(mt.Client, "TODOLIST", "experience_idempotency")
+
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 0}, {Key: "upserted", Value: bson.A{bson.D{{Key: "index", Value: 0}, {Key: "_id", Value: "k1"}}}}})
+		existing, err := s.Reserve(context.Background(), &models.IdempotencyRecord{Key: "k1", Fingerprint: "a", ExpiresAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		require.Nil(t, existing)
+	})
+
+	mt.Run("Reserve, held key", func(mt *mtest.T) {
+		s := NewMongoIdempotencyStore(mt.Client, "TODOLIST", "experience_idempotency")
+
+		mt.AddMockResponses(
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key error"}),
+			mtest.CreateCursorResponse(0, "TODOLIST.experience_idempotency", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: "k1"},
+				{Key: "fingerprint", Value: "a"},
+				{Key: "status", Value: 201},
+			}),
+		)
+		existing, err := s.Reserve(context.Background(), &models.IdempotencyRecord{Key: "k1", Fingerprint: "b", ExpiresAt: time.Now().Add(time.Hour)})
+		require.NoError(t, err)
+		require.Equal(t, "a", existing.Fingerprint)
+		require.Equal(t, 201, existing.Status)
+	})
+
+	mt.Run("Complete, reservation lost", func(mt *mtest.T) {
+		s := NewMongoIdempotencyStore(mt.Client, "TODOLIST", "experience_idempotency")
+
+		mt.AddMockResponses(bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}})
+		err := s.Complete(context.Background(), &models.IdempotencyRecord{Key: "k1", Fingerprint: "a", Status: 201})
+		require.ErrorIs(t, err, ErrConflict)
+	})
+}
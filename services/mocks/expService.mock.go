@@ -3,7 +3,7 @@
 //
 // Generated by this command:
 //
-//	mockgen -source=expService.go ExperienceService
+//	mockgen -source=expService.go -destination=mocks/expService.mock.go ExperienceService
 //
 
 // Package mock_services is a generated GoMock package.
@@ -11,8 +11,11 @@ package mock_services
 
 import (
 	models "GO-Project/models"
+	patch "GO-Project/patch"
+	services "GO-Project/services"
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	primitive "go.mongodb.org/mongo-driver/bson/primitive"
 	gomock "go.uber.org/mock/gomock"
@@ -41,12 +44,43 @@ func (m *MockExperienceService) EXPECT() *MockExperienceServiceMockRecorder {
 	return m.recorder
 }
 
+// AssignOwner mocks base method.
+func (m *MockExperienceService) AssignOwner(ctx context.Context, owner string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignOwner", ctx, owner)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AssignOwner indicates an expected call of AssignOwner.
+func (mr *MockExperienceServiceMockRecorder) AssignOwner(ctx, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignOwner", reflect.TypeOf((*MockExperienceService)(nil).AssignOwner), ctx, owner)
+}
+
+// Bulk mocks base method.
+func (m *MockExperienceService) Bulk(ctx context.Context, ops []services.BulkOperation, atomic bool) ([]services.BulkResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Bulk", ctx, ops, atomic)
+	ret0, _ := ret[0].([]services.BulkResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Bulk indicates an expected call of Bulk.
+func (mr *MockExperienceServiceMockRecorder) Bulk(ctx, ops, atomic any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bulk", reflect.TypeOf((*MockExperienceService)(nil).Bulk), ctx, ops, atomic)
+}
+
 // Create mocks base method.
-func (m *MockExperienceService) Create(ctx context.Context, experience *models.ExperienceDto) error {
+func (m *MockExperienceService) Create(ctx context.Context, experience *models.ExperienceDto) (*models.Experience, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", ctx, experience)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*models.Experience)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
@@ -56,59 +90,149 @@ func (mr *MockExperienceServiceMockRecorder) Create(ctx, experience any) *gomock
 }
 
 // Delete mocks base method.
-func (m *MockExperienceService) Delete(id primitive.ObjectID) error {
+func (m *MockExperienceService) Delete(ctx context.Context, id primitive.ObjectID, cond services.Precondition) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, cond)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockExperienceServiceMockRecorder) Delete(id any) *gomock.Call {
+func (mr *MockExperienceServiceMockRecorder) Delete(ctx, id, cond any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockExperienceService)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockExperienceService)(nil).Delete), ctx, id, cond)
 }
 
 // FindAll mocks base method.
-func (m *MockExperienceService) FindAll() ([]*models.ExperienceDto, error) {
+func (m *MockExperienceService) FindAll(ctx context.Context, query services.ListQuery) (*services.ListResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindAll")
-	ret0, _ := ret[0].([]*models.ExperienceDto)
+	ret := m.ctrl.Call(m, "FindAll", ctx, query)
+	ret0, _ := ret[0].(*services.ListResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindAll indicates an expected call of FindAll.
-func (mr *MockExperienceServiceMockRecorder) FindAll() *gomock.Call {
+func (mr *MockExperienceServiceMockRecorder) FindAll(ctx, query any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockExperienceService)(nil).FindAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockExperienceService)(nil).FindAll), ctx, query)
 }
 
 // FindById mocks base method.
-func (m *MockExperienceService) FindById(id primitive.ObjectID) (*models.ExperienceDto, error) {
+func (m *MockExperienceService) FindById(ctx context.Context, id primitive.ObjectID) (*models.Experience, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindById", id)
-	ret0, _ := ret[0].(*models.ExperienceDto)
+	ret := m.ctrl.Call(m, "FindById", ctx, id)
+	ret0, _ := ret[0].(*models.Experience)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // FindById indicates an expected call of FindById.
-func (mr *MockExperienceServiceMockRecorder) FindById(id any) *gomock.Call {
+func (mr *MockExperienceServiceMockRecorder) FindById(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockExperienceService)(nil).FindById), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindById", reflect.TypeOf((*MockExperienceService)(nil).FindById), ctx, id)
 }
 
-// Update mocks base method.
-func (m *MockExperienceService) Update(id primitive.ObjectID, experience *models.ExperienceDto) error {
+// Patch mocks base method.
+func (m *MockExperienceService) Patch(ctx context.Context, id primitive.ObjectID, p patch.Patch, cond services.Precondition) (*models.Experience, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", id, experience)
+	ret := m.ctrl.Call(m, "Patch", ctx, id, p, cond)
+	ret0, _ := ret[0].(*models.Experience)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *MockExperienceServiceMockRecorder) Patch(ctx, id, p, cond any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockExperienceService)(nil).Patch), ctx, id, p, cond)
+}
+
+// Purge mocks base method.
+func (m *MockExperienceService) Purge(ctx context.Context, id primitive.ObjectID, cond services.Precondition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purge", ctx, id, cond)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// Purge indicates an expected call of Purge.
+func (mr *MockExperienceServiceMockRecorder) Purge(ctx, id, cond any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purge", reflect.TypeOf((*MockExperienceService)(nil).Purge), ctx, id, cond)
+}
+
+// PurgeDeleted mocks base method.
+func (m *MockExperienceService) PurgeDeleted(ctx context.Context, cutoff time.Time) ([]primitive.ObjectID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeleted", ctx, cutoff)
+	ret0, _ := ret[0].([]primitive.ObjectID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeleted indicates an expected call of PurgeDeleted.
+func (mr *MockExperienceServiceMockRecorder) PurgeDeleted(ctx, cutoff any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeleted", reflect.TypeOf((*MockExperienceService)(nil).PurgeDeleted), ctx, cutoff)
+}
+
+// Search mocks base method.
+func (m *MockExperienceService) Search(ctx context.Context, query services.SearchQuery) (*services.SearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, query)
+	ret0, _ := ret[0].(*services.SearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockExperienceServiceMockRecorder) Search(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockExperienceService)(nil).Search), ctx, query)
+}
+
+// Trash mocks base method.
+func (m *MockExperienceService) Trash(ctx context.Context, query services.ListQuery) (*services.ListResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Trash", ctx, query)
+	ret0, _ := ret[0].(*services.ListResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Trash indicates an expected call of Trash.
+func (mr *MockExperienceServiceMockRecorder) Trash(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Trash", reflect.TypeOf((*MockExperienceService)(nil).Trash), ctx, query)
+}
+
+// Undelete mocks base method.
+func (m *MockExperienceService) Undelete(ctx context.Context, id primitive.ObjectID, cond services.Precondition) (*models.Experience, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Undelete", ctx, id, cond)
+	ret0, _ := ret[0].(*models.Experience)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Undelete indicates an expected call of Undelete.
+func (mr *MockExperienceServiceMockRecorder) Undelete(ctx, id, cond any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Undelete", reflect.TypeOf((*MockExperienceService)(nil).Undelete), ctx, id, cond)
+}
+
+// Update mocks base method.
+func (m *MockExperienceService) Update(ctx context.Context, id primitive.ObjectID, experience *models.ExperienceDto, cond services.Precondition) (*models.Experience, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, id, experience, cond)
+	ret0, _ := ret[0].(*models.Experience)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // Update indicates an expected call of Update.
-func (mr *MockExperienceServiceMockRecorder) Update(id, experience any) *gomock.Call {
+func (mr *MockExperienceServiceMockRecorder) Update(ctx, id, experience, cond any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockExperienceService)(nil).Update), id, experience)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockExperienceService)(nil).Update), ctx, id, experience, cond)
 }
@@ -0,0 +1,970 @@
+// Package servicetest holds the behavioural contract every
+// services.ExperienceService implementation must satisfy.
+//
+// A backend proves conformance from its own tests:
+//
+//	func TestMemoryConformance(t *testing.T) {
+//		servicetest.Run(t, func(t *testing.T) services.ExperienceService {
+//			return services.NewMemoryExperienceService()
+//		})
+//	}
+package servicetest
+
+import (
+	"GO-Project/auth"
+	"GO-Project/models"
+	"GO-Project/patch"
+	"GO-Project/services"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Factory returns a new, empty service. It is called once per subtest and
+// should register any cleanup with t.
+type Factory func(t *testing.T) services.ExperienceService
+
+// timestampTolerance absorbs backends that store times at millisecond
+// precision, as MongoDB does.
+const timestampTolerance = 5 * time.Millisecond
+
+// Run executes the full conformance suite against the services returned by
+// newService.
+func Run(t *testing.T, newService Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s services.ExperienceService)
+	}{
+		{"CreateThenFindById", testCreateThenFindById},
+		{"RoundTripsAllFields", testRoundTripsAllFields},
+		{"LegacyExperienceBecomesSummary", testLegacyExperienceBecomesSummary},
+		{"CreateReturnsDistinctIds", testCreateReturnsDistinctIds},
+		{"FindByIdNotFound", testFindByIdNotFound},
+		{"Update", testUpdate},
+		{"UpdateNotFound", testUpdateNotFound},
+		{"PatchMerge", testPatchMerge},
+		{"PatchJSONPatch", testPatchJSONPatch},
+		{"PatchRejected", testPatchRejected},
+		{"PatchNotFound", testPatchNotFound},
+		{"Preconditions", testPreconditions},
+		{"Delete", testDelete},
+		{"DeleteNotFound", testDeleteNotFound},
+		{"Trash", testTrash},
+		{"PurgeDeleted", testPurgeDeleted},
+		{"Bulk", testBulk},
+		{"BulkAtomic", testBulkAtomic},
+		{"BulkRejected", testBulkRejected},
+		{"Ownership", testOwnership},
+		{"AssignOwner", testAssignOwner},
+		{"FindAllEmpty", testFindAllEmpty},
+		{"FindAllInsertionOrder", testFindAllInsertionOrder},
+		{"FindAllPages", testFindAllPages},
+		{"FindAllCursorWalk", testFindAllCursorWalk},
+		{"FindAllSort", testFindAllSort},
+		{"FindAllFilters", testFindAllFilters},
+		{"FindAllInvalidQuery", testFindAllInvalidQuery},
+		{"SearchRanking", testSearchRanking},
+		{"SearchPrefixAndFuzzy", testSearchPrefixAndFuzzy},
+		{"SearchFollowsWrites", testSearchFollowsWrites},
+		{"SearchHighlights", testSearchHighlights},
+		{"SearchEmptyQuery", testSearchEmptyQuery},
+		{"ReturnedValuesAreCopies", testReturnedValuesAreCopies},
+		{"CanceledContext", testCanceledContext},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newService(t))
+		})
+	}
+}
+
+// newDto returns a minimal valid payload whose title identifies it.
+func newDto(title string) *models.ExperienceDto {
+	return &models.ExperienceDto{
+		Company:   "Acme",
+		Title:     title,
+		StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func create(t *testing.T, s services.ExperienceService, title string) primitive.ObjectID {
+	t.Helper()
+	created, err := s.Create(context.Background(), newDto(title))
+	require.NoError(t, err)
+	require.False(t, created.ID.IsZero(), "Create must return the generated id")
+	return created.ID
+}
+
+func titles(result *services.ListResult) []string {
+	out := make([]string, len(result.Items))
+	for i, e := range result.Items {
+		out[i] = e.Title
+	}
+	return out
+}
+
+func requireNotFound(t *testing.T, err error) {
+	t.Helper()
+	require.ErrorIs(t, err, services.ErrNotFound)
+}
+
+func testCreateThenFindById(t *testing.T, s services.ExperienceService) {
+	before := time.Now()
+	created, err := s.Create(context.Background(), newDto("Backend developer"))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), created.Version)
+
+	exp, err := s.FindById(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.Equal(t, created.ID, exp.ID)
+	require.Equal(t, "Backend developer", exp.Title)
+	require.Equal(t, "Acme", exp.Company)
+	require.Equal(t, int64(1), exp.Version)
+	require.WithinRange(t, exp.CreatedAt, before.Add(-timestampTolerance), time.Now().Add(timestampTolerance))
+	require.WithinDuration(t, created.CreatedAt, exp.CreatedAt, timestampTolerance)
+	require.True(t, exp.UpdatedAt.IsZero(), "UpdatedAt must be zero until the first update")
+}
+
+func testRoundTripsAllFields(t *testing.T, s services.ExperienceService) {
+	end := time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC)
+	in := &models.ExperienceDto{
+		Company:        "Acme",
+		Title:          "Senior Engineer",
+		EmploymentType: "contract",
+		Location:       "Bangkok",
+		Remote:         true,
+		StartDate:      time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:        &end,
+		Summary:        "Built the billing platform.",
+		Achievements:   []string{"Cut costs by 30%", "Led a team of 5"},
+		Skills:         []string{"go", "mongodb"},
+	}
+
+	created, err := s.Create(context.Background(), in)
+	require.NoError(t, err)
+
+	out, err := s.FindById(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.Equal(t, in.Company, out.Company)
+	require.Equal(t, in.Title, out.Title)
+	require.Equal(t, in.EmploymentType, out.EmploymentType)
+	require.Equal(t, in.Location, out.Location)
+	require.Equal(t, in.Remote, out.Remote)
+	require.True(t, in.StartDate.Equal(out.StartDate))
+	require.NotNil(t, out.EndDate)
+	require.True(t, end.Equal(*out.EndDate))
+	require.Equal(t, in.Summary, out.Summary)
+	require.Equal(t, in.Achievements, out.Achievements)
+	require.Equal(t, in.Skills, out.Skills)
+	require.Empty(t, out.Experience)
+}
+
+func testLegacyExperienceBecomesSummary(t *testing.T, s services.ExperienceService) {
+	in := newDto("Developer")
+	in.Experience = "Wrote Go services"
+
+	created, err := s.Create(context.Background(), in)
+	require.NoError(t, err)
+
+	out, err := s.FindById(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Wrote Go services", out.Summary)
+	require.Empty(t, out.Experience)
+}
+
+func testCreateReturnsDistinctIds(t *testing.T, s services.ExperienceService) {
+	a := create(t, s, "a")
+	b := create(t, s, "b")
+	require.NotEqual(t, a, b)
+}
+
+func testFindByIdNotFound(t *testing.T, s services.ExperienceService) {
+	exp, err := s.FindById(context.Background(), primitive.NewObjectID())
+	requireNotFound(t, err)
+	require.Nil(t, exp)
+}
+
+func testUpdate(t *testing.T, s services.ExperienceService) {
+	id := create(t, s, "before")
+	created, err := s.FindById(context.Background(), id)
+	require.NoError(t, err)
+
+	end := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	replacement := newDto("after")
+	replacement.EndDate = &end
+	replacement.Skills = []string{"go"}
+	returned, err := s.Update(context.Background(), id, replacement, services.Precondition{})
+	require.NoError(t, err)
+	require.Equal(t, "after", returned.Title)
+	require.Equal(t, int64(2), returned.Version)
+
+	updated, err := s.FindById(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), updated.Version)
+	require.Equal(t, "after", updated.Title)
+	require.Equal(t, []string{"go"}, updated.Skills)
+	require.NotNil(t, updated.EndDate)
+	require.WithinDuration(t, created.CreatedAt, updated.CreatedAt, timestampTolerance, "Update must not touch CreatedAt")
+	require.False(t, updated.UpdatedAt.Before(created.CreatedAt.Add(-timestampTolerance)))
+}
+
+func testUpdateNotFound(t *testing.T, s services.ExperienceService) {
+	exp, err := s.Update(context.Background(), primitive.NewObjectID(), newDto("x"), services.Precondition{})
+	requireNotFound(t, err)
+	require.Nil(t, exp)
+}
+
+func mergePatch(t *testing.T, body string) patch.Patch {
+	t.Helper()
+	p, err := patch.ParseMerge([]byte(body))
+	require.NoError(t, err)
+	return p
+}
+
+func jsonPatch(t *testing.T, body string) patch.Patch {
+	t.Helper()
+	p, err := patch.ParseJSONPatch([]byte(body))
+	require.NoError(t, err)
+	return p
+}
+
+func testPatchMerge(t *testing.T, s services.ExperienceService) {
+	in := newDto("Developer")
+	in.Summary = "Kept"
+	in.Skills = []string{"go"}
+	created, err := s.Create(context.Background(), in)
+	require.NoError(t, err)
+
+	p := mergePatch(t, `{"title": "Senior developer", "location": "Bangkok", "skills": ["go", "rust"]}`)
+	patched, err := s.Patch(context.Background(), created.ID, p, services.Precondition{})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), patched.Version)
+
+	stored, err := s.FindById(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Senior developer", stored.Title)
+	require.Equal(t, "Bangkok", stored.Location)
+	require.Equal(t, []string{"go", "rust"}, stored.Skills)
+	require.Equal(t, "Kept", stored.Summary, "members absent from the patch are kept")
+	require.Equal(t, "Acme", stored.Company)
+	require.Equal(t, int64(2), stored.Version)
+	require.False(t, stored.UpdatedAt.IsZero())
+
+	p = mergePatch(t, `{"summary": null}`)
+	_, err = s.Patch(context.Background(), created.ID, p, services.Precondition{})
+	require.NoError(t, err)
+	stored, err = s.FindById(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.Empty(t, stored.Summary)
+}
+
+func testPatchJSONPatch(t *testing.T, s services.ExperienceService) {
+	in := newDto("Developer")
+	in.Achievements = []string{"first"}
+	created, err := s.Create(context.Background(), in)
+	require.NoError(t, err)
+
+	p := jsonPatch(t, `[
+		{"op": "test", "path": "/title", "value": "Developer"},
+		{"op": "add", "path": "/achievements/-", "value": "second"},
+		{"op": "replace", "path": "/remote", "value": true},
+		{"op": "add", "path": "/endDate", "value": "2022-01-01T00:00:00Z"}
+	]`)
+	_, err = s.Patch(context.Background(), created.ID, p, services.Precondition{})
+	require.NoError(t, err)
+
+	stored, err := s.FindById(context.Background(), created.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second"}, stored.Achievements)
+	require.True(t, stored.Remote)
+	require.NotNil(t, stored.EndDate)
+	require.True(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).Equal(*stored.EndDate))
+}
+
+func testPatchRejected(t *testing.T, s services.ExperienceService) {
+	id := create(t, s, "unchanged")
+
+	tests := []struct {
+		name  string
+		patch patch.Patch
+		kind  error
+	}{
+		{"invalid result", mergePatch(t, `{"title": ""}`), services.ErrValidation},
+		{"end before start", mergePatch(t, `{"endDate": "2019-01-01T00:00:00Z"}`), services.ErrValidation},
+		{"unknown member", mergePatch(t, `{"id": "x"}`), services.ErrValidation},
+		{"wrong type", mergePatch(t, `{"skills": "go"}`), services.ErrValidation},
+		{"whole document", mergePatch(t, `"x"`), services.ErrValidation},
+		{"missing path", jsonPatch(t, `[{"op": "remove", "path": "/nope"}]`), services.ErrValidation},
+		{"failed test", jsonPatch(t, `[
+			{"op": "replace", "path": "/title", "value": "changed"},
+			{"op": "test", "path": "/company", "value": "Globex"}
+		]`), services.ErrConflict},
+	}
+	for _, tt := range tests {
+		_, err := s.Patch(context.Background(), id, tt.patch, services.Precondition{})
+		require.ErrorIs(t, err, tt.kind, tt.name)
+	}
+
+	stored, err := s.FindById(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, "unchanged", stored.Title)
+	require.Equal(t, int64(1), stored.Version)
+}
+
+func testPatchNotFound(t *testing.T, s services.ExperienceService) {
+	p := mergePatch(t, `{"title": "x"}`)
+	exp, err := s.Patch(context.Background(), primitive.NewObjectID(), p, services.Precondition{})
+	requireNotFound(t, err)
+	require.Nil(t, exp)
+}
+
+func testPreconditions(t *testing.T, s services.ExperienceService) {
+	ctx := context.Background()
+	id := create(t, s, "v1")
+	stale := services.Precondition{IfMatch: []int64{1}}
+
+	updated, err := s.Update(ctx, id, newDto("v2"), stale)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), updated.Version)
+
+	// A second writer still holding version 1 is turned away by every
+	// kind of write.
+	_, err = s.Update(ctx, id, newDto("lost"), stale)
+	require.ErrorIs(t, err, services.ErrPreconditionFailed)
+	_, err = s.Patch(ctx, id, mergePatch(t, `{"title": "lost"}`), stale)
+	require.ErrorIs(t, err, services.ErrPreconditionFailed)
+	require.ErrorIs(t, s.Delete(ctx, id, stale), services.ErrPreconditionFailed)
+
+	for _, cond := range []services.Precondition{
+		{IfMatch: []int64{}},
+		{IfNoneMatch: []int64{2}},
+		{IfNoneMatchAny: true},
+	} {
+		_, err = s.Update(ctx, id, newDto("lost"), cond)
+		require.ErrorIs(t, err, services.ErrPreconditionFailed, "%+v", cond)
+	}
+
+	stored, err := s.FindById(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "v2", stored.Title)
+	require.Equal(t, int64(2), stored.Version)
+
+	patched, err := s.Patch(ctx, id, mergePatch(t, `{"title": "v3"}`), services.Precondition{IfMatch: []int64{1, 2}, IfNoneMatch: []int64{1}})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), patched.Version)
+	require.NoError(t, s.Delete(ctx, id, services.Precondition{IfMatch: []int64{3}}))
+}
+
+func testDelete(t *testing.T, s services.ExperienceService) {
+	keep := create(t, s, "keep")
+	drop := create(t, s, "drop")
+
+	require.NoError(t, s.Delete(context.Background(), drop, services.Precondition{}))
+
+	_, err := s.FindById(context.Background(), drop)
+	requireNotFound(t, err)
+	_, err = s.FindById(context.Background(), keep)
+	require.NoError(t, err)
+
+	all, err := s.FindAll(context.Background(), services.ListQuery{})
+	require.NoError(t, err)
+	require.Len(t, all.Items, 1)
+	require.Equal(t, int64(1), all.Total)
+}
+
+func testDeleteNotFound(t *testing.T, s services.ExperienceService) {
+	id := create(t, s, "once")
+	require.NoError(t, s.Delete(context.Background(), id, services.Precondition{}))
+	requireNotFound(t, s.Delete(context.Background(), id, services.Precondition{}))
+}
+
+func testTrash(t *testing.T, s services.ExperienceService) {
+	ctx := context.Background()
+	keep := create(t, s, "Kept developer")
+	drop := create(t, s, "Dropped developer")
+	require.NoError(t, s.Delete(ctx, drop, services.Precondition{}))
+
+	// A trashed experience is gone for every other method.
+	_, err := s.FindById(ctx, drop)
+	requireNotFound(t, err)
+	_, err = s.Update(ctx, drop, newDto("x"), services.Precondition{})
+	requireNotFound(t, err)
+	_, err = s.Patch(ctx, drop, mergePatch(t, `{"title": "x"}`), services.Precondition{})
+	requireNotFound(t, err)
+	requireNotFound(t, s.Delete(ctx, drop, services.Precondition{}))
+	all, err := s.FindAll(ctx, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Kept developer"}, titles(all))
+	require.Equal(t, []string{"Kept developer"}, hitTitles(search(t, s, services.SearchQuery{Text: "developer"})))
+
+	trash, err := s.Trash(ctx, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Dropped developer"}, titles(trash))
+	require.Equal(t, int64(1), trash.Total)
+	require.NotNil(t, trash.Items[0].DeletedAt)
+	require.Equal(t, int64(2), trash.Items[0].Version)
+
+	_, err = s.Undelete(ctx, drop, services.Precondition{IfMatch: []int64{1}})
+	require.ErrorIs(t, err, services.ErrPreconditionFailed)
+	restored, err := s.Undelete(ctx, drop, services.Precondition{IfMatch: []int64{2}})
+	require.NoError(t, err)
+	require.Nil(t, restored.DeletedAt)
+	require.Equal(t, int64(3), restored.Version)
+	_, err = s.Undelete(ctx, drop, services.Precondition{})
+	requireNotFound(t, err)
+	require.Len(t, search(t, s, services.SearchQuery{Text: "dropped"}).Hits, 1)
+
+	// Only trashed experiences can be purged.
+	requireNotFound(t, s.Purge(ctx, keep, services.Precondition{}))
+	require.NoError(t, s.Delete(ctx, drop, services.Precondition{}))
+	require.NoError(t, s.Purge(ctx, drop, services.Precondition{}))
+	trash, err = s.Trash(ctx, services.ListQuery{})
+	require.NoError(t, err)
+	require.Empty(t, trash.Items)
+	_, err = s.Undelete(ctx, drop, services.Precondition{})
+	requireNotFound(t, err)
+}
+
+func testPurgeDeleted(t *testing.T, s services.ExperienceService) {
+	ctx := context.Background()
+	create(t, s, "live")
+	old := create(t, s, "old")
+	require.NoError(t, s.Delete(ctx, old, services.Precondition{}))
+	cutoff := time.Now().Add(timestampTolerance)
+	time.Sleep(2 * timestampTolerance)
+	recent := create(t, s, "recent")
+	require.NoError(t, s.Delete(ctx, recent, services.Precondition{}))
+
+	purged, err := s.PurgeDeleted(ctx, cutoff)
+	require.NoError(t, err)
+	require.Equal(t, []primitive.ObjectID{old}, purged)
+
+	trash, err := s.Trash(ctx, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"recent"}, titles(trash))
+	all, err := s.FindAll(ctx, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"live"}, titles(all))
+
+	purged, err = s.PurgeDeleted(ctx, cutoff)
+	require.NoError(t, err)
+	require.Empty(t, purged)
+}
+
+func testBulk(t *testing.T, s services.ExperienceService) {
+	ctx := context.Background()
+	updated := create(t, s, "old title")
+	deleted := create(t, s, "to delete")
+	stale := create(t, s, "stale")
+	missing := primitive.NewObjectID()
+
+	results, err := s.Bulk(ctx, []services.BulkOperation{
+		{Op: services.BulkCreate, Experience: newDto("created")},
+		{Op: services.BulkUpdate, ID: updated, Experience: newDto("new title"), Cond: services.Precondition{IfMatch: []int64{1}}},
+		{Op: services.BulkDelete, ID: deleted},
+		{Op: services.BulkUpdate, ID: missing, Experience: newDto("x")},
+		{Op: services.BulkDelete, ID: stale, Cond: services.Precondition{IfMatch: []int64{7}}},
+	}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 5)
+
+	require.NoError(t, results[0].Err)
+	require.Equal(t, "created", results[0].Experience.Title)
+	require.Equal(t, int64(1), results[0].Experience.Version)
+	require.NoError(t, results[1].Err)
+	require.Equal(t, updated, results[1].ID)
+	require.Equal(t, "new title", results[1].Experience.Title)
+	require.Equal(t, int64(2), results[1].Experience.Version)
+	require.NoError(t, results[2].Err)
+	require.Equal(t, deleted, results[2].ID)
+	require.Nil(t, results[2].Experience)
+	requireNotFound(t, results[3].Err)
+	require.Equal(t, missing, results[3].ID)
+	require.ErrorIs(t, results[4].Err, services.ErrPreconditionFailed)
+
+	created, err := s.FindById(ctx, results[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, "created", created.Title)
+	all, err := s.FindAll(ctx, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"new title", "stale", "created"}, titles(all))
+	trash, err := s.Trash(ctx, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"to delete"}, titles(trash))
+	require.Equal(t, []string{"created"}, hitTitles(search(t, s, services.SearchQuery{Text: "created"})))
+	require.Empty(t, search(t, s, services.SearchQuery{Text: "old"}).Hits)
+}
+
+func testBulkAtomic(t *testing.T, s services.ExperienceService) {
+	ctx := context.Background()
+	id := create(t, s, "kept")
+
+	results, err := s.Bulk(ctx, []services.BulkOperation{
+		{Op: services.BulkCreate, Experience: newDto("never created")},
+		{Op: services.BulkUpdate, ID: id, Experience: newDto("never updated")},
+		{Op: services.BulkDelete, ID: primitive.NewObjectID()},
+	}, true)
+	require.NoError(t, err)
+	require.ErrorIs(t, results[0].Err, services.ErrAborted)
+	require.Nil(t, results[0].Experience)
+	require.ErrorIs(t, results[1].Err, services.ErrAborted)
+	requireNotFound(t, results[2].Err)
+
+	all, err := s.FindAll(ctx, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"kept"}, titles(all))
+	stored, err := s.FindById(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), stored.Version)
+
+	results, err = s.Bulk(ctx, []services.BulkOperation{
+		{Op: services.BulkCreate, Experience: newDto("created")},
+		{Op: services.BulkUpdate, ID: id, Experience: newDto("updated")},
+	}, true)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	require.NoError(t, results[1].Err)
+	all, err = s.FindAll(ctx, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"updated", "created"}, titles(all))
+}
+
+func testBulkRejected(t *testing.T, s services.ExperienceService) {
+	ctx := context.Background()
+	id := create(t, s, "untouched")
+
+	for name, ops := range map[string][]services.BulkOperation{
+		"empty":       nil,
+		"unknown op":  {{Op: "upsert", ID: id}},
+		"missing id":  {{Op: services.BulkDelete}},
+		"missing dto": {{Op: services.BulkCreate}},
+		"same id twice": {
+			{Op: services.BulkUpdate, ID: id, Experience: newDto("a")},
+			{Op: services.BulkDelete, ID: id},
+		},
+		"too many": make([]services.BulkOperation, services.MaxBulkOperations+1),
+	} {
+		_, err := s.Bulk(ctx, ops, false)
+		require.ErrorIs(t, err, services.ErrValidation, name)
+	}
+
+	stored, err := s.FindById(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, "untouched", stored.Title)
+}
+
+func testOwnership(t *testing.T, s services.ExperienceService) {
+	as := func(subject string) context.Context {
+		return auth.WithPrincipal(context.Background(), &auth.Principal{Subject: subject})
+	}
+	alice, bob, admin := as("alice"), as("bob"), services.WithAllOwners(as("root"))
+
+	mine, err := s.Create(alice, newDto("alice developer"))
+	require.NoError(t, err)
+	require.Equal(t, "alice", mine.OwnerID)
+	theirs, err := s.Create(bob, newDto("bob developer"))
+	require.NoError(t, err)
+	id := mine.ID
+
+	// Experiences of other owners do not exist for bob.
+	_, err = s.FindById(bob, id)
+	requireNotFound(t, err)
+	_, err = s.Update(bob, id, newDto("x"), services.Precondition{})
+	requireNotFound(t, err)
+	_, err = s.Patch(bob, id, mergePatch(t, `{"title": "x"}`), services.Precondition{})
+	requireNotFound(t, err)
+	requireNotFound(t, s.Delete(bob, id, services.Precondition{}))
+	results, err := s.Bulk(bob, []services.BulkOperation{
+		{Op: services.BulkCreate, Experience: newDto("bob created")},
+		{Op: services.BulkDelete, ID: id},
+	}, false)
+	require.NoError(t, err)
+	require.Equal(t, "bob", results[0].Experience.OwnerID)
+	requireNotFound(t, results[1].Err)
+
+	list, err := s.FindAll(bob, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob developer", "bob created"}, titles(list))
+	require.Equal(t, int64(2), list.Total)
+	hits, err := s.Search(bob, services.SearchQuery{Text: "developer"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob developer"}, hitTitles(hits))
+
+	// Acting on every owner's experiences keeps their owner.
+	list, err = s.FindAll(admin, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), list.Total)
+	updated, err := s.Update(admin, id, newDto("alice lead"), services.Precondition{})
+	require.NoError(t, err)
+	require.Equal(t, "alice", updated.OwnerID)
+	hits, err = s.Search(admin, services.SearchQuery{Text: "lead"})
+	require.NoError(t, err)
+	require.Len(t, hits.Hits, 1)
+
+	// The trash is scoped the same way.
+	require.NoError(t, s.Delete(alice, id, services.Precondition{}))
+	trash, err := s.Trash(bob, services.ListQuery{})
+	require.NoError(t, err)
+	require.Empty(t, trash.Items)
+	_, err = s.Undelete(bob, id, services.Precondition{})
+	requireNotFound(t, err)
+	requireNotFound(t, s.Purge(bob, id, services.Precondition{}))
+	purged, err := s.PurgeDeleted(bob, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, purged)
+	trash, err = s.Trash(admin, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice lead"}, titles(trash))
+	_, err = s.Undelete(alice, id, services.Precondition{})
+	require.NoError(t, err)
+
+	// Without a principal, as with authentication disabled, everything is
+	// visible.
+	found, err := s.FindById(context.Background(), theirs.ID)
+	require.NoError(t, err)
+	require.Equal(t, "bob", found.OwnerID)
+}
+
+func testAssignOwner(t *testing.T, s services.ExperienceService) {
+	alice := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "alice"})
+	bob := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "bob"})
+
+	// Experiences created without a principal have no owner.
+	unowned, err := s.Create(context.Background(), newDto("unowned"))
+	require.NoError(t, err)
+	require.Empty(t, unowned.OwnerID)
+	trashed, err := s.Create(context.Background(), newDto("trashed"))
+	require.NoError(t, err)
+	require.NoError(t, s.Delete(context.Background(), trashed.ID, services.Precondition{}))
+	_, err = s.Create(bob, newDto("bob developer"))
+	require.NoError(t, err)
+	_, err = s.FindById(alice, unowned.ID)
+	requireNotFound(t, err)
+
+	assigned, err := s.AssignOwner(context.Background(), "alice")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), assigned)
+
+	found, err := s.FindById(alice, unowned.ID)
+	require.NoError(t, err)
+	require.Equal(t, "alice", found.OwnerID)
+	require.Equal(t, unowned.Version, found.Version)
+	trash, err := s.Trash(alice, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"trashed"}, titles(trash))
+	list, err := s.FindAll(bob, services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob developer"}, titles(list))
+
+	assigned, err = s.AssignOwner(context.Background(), "carol")
+	require.NoError(t, err)
+	require.Zero(t, assigned)
+}
+
+func testFindAllEmpty(t *testing.T, s services.ExperienceService) {
+	all, err := s.FindAll(context.Background(), services.ListQuery{})
+	require.NoError(t, err)
+	require.Empty(t, all.Items)
+	require.Zero(t, all.Total)
+	require.Empty(t, all.NextCursor)
+	require.Empty(t, all.PrevCursor)
+}
+
+func testFindAllInsertionOrder(t *testing.T, s services.ExperienceService) {
+	for _, text := range []string{"first", "second", "third"} {
+		create(t, s, text)
+	}
+
+	all, err := s.FindAll(context.Background(), services.ListQuery{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"first", "second", "third"}, titles(all))
+}
+
+func testFindAllPages(t *testing.T, s services.ExperienceService) {
+	for _, title := range []string{"a", "b", "c", "d", "e"} {
+		create(t, s, title)
+	}
+
+	want := [][]string{{"a", "b"}, {"c", "d"}, {"e"}}
+	for i, page := range want {
+		result, err := s.FindAll(context.Background(), services.ListQuery{Page: i + 1, Limit: 2})
+		require.NoError(t, err)
+		require.Equal(t, page, titles(result))
+		require.Equal(t, int64(5), result.Total)
+		require.Equal(t, i+1, result.Page)
+		require.Equal(t, 2, result.Limit)
+		require.Equal(t, i < 2, result.NextCursor != "", "page %d next", i+1)
+		require.Equal(t, i > 0, result.PrevCursor != "", "page %d prev", i+1)
+	}
+
+	beyond, err := s.FindAll(context.Background(), services.ListQuery{Page: 4, Limit: 2})
+	require.NoError(t, err)
+	require.Empty(t, beyond.Items)
+	require.Equal(t, int64(5), beyond.Total)
+}
+
+func testFindAllCursorWalk(t *testing.T, s services.ExperienceService) {
+	for _, title := range []string{"a", "b", "c", "d", "e"} {
+		create(t, s, title)
+	}
+	// Schema version 1 documents have no title; upgraded or backfilled,
+	// they hold the zero value, and ties between them span a page boundary.
+	for i := 0; i < 2; i++ {
+		_, err := s.Create(context.Background(), &models.ExperienceDto{Experience: "legacy"})
+		require.NoError(t, err)
+	}
+
+	query := services.ListQuery{Limit: 2, Sort: "title", Desc: true}
+	var forward [][]string
+	var last *services.ListResult
+	for {
+		result, err := s.FindAll(context.Background(), query)
+		require.NoError(t, err)
+		require.Equal(t, int64(7), result.Total)
+		forward = append(forward, titles(result))
+		last = result
+		if result.NextCursor == "" {
+			break
+		}
+		query.Cursor = result.NextCursor
+	}
+	require.Equal(t, [][]string{{"e", "d"}, {"c", "b"}, {"a", ""}, {""}}, forward)
+
+	var backward [][]string
+	for cursor := last.PrevCursor; cursor != ""; {
+		result, err := s.FindAll(context.Background(), services.ListQuery{Limit: 2, Cursor: cursor})
+		require.NoError(t, err)
+		backward = append(backward, titles(result))
+		cursor = result.PrevCursor
+	}
+	require.Equal(t, [][]string{{"a", ""}, {"c", "b"}, {"e", "d"}}, backward)
+}
+
+func testFindAllSort(t *testing.T, s services.ExperienceService) {
+	for i, title := range []string{"b", "c", "a"} {
+		dto := newDto(title)
+		dto.StartDate = time.Date(2020+i, 1, 1, 0, 0, 0, 0, time.UTC)
+		_, err := s.Create(context.Background(), dto)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		sort string
+		desc bool
+		want []string
+	}{
+		{"", false, []string{"b", "c", "a"}},
+		{"createdAt", true, []string{"a", "c", "b"}},
+		{"title", false, []string{"a", "b", "c"}},
+		{"startDate", true, []string{"a", "c", "b"}},
+	}
+	for _, tt := range tests {
+		result, err := s.FindAll(context.Background(), services.ListQuery{Sort: tt.sort, Desc: tt.desc})
+		require.NoError(t, err)
+		require.Equal(t, tt.want, titles(result), "sort %q desc %v", tt.sort, tt.desc)
+	}
+}
+
+func testFindAllFilters(t *testing.T, s services.ExperienceService) {
+	go2019 := newDto("Go developer")
+	go2019.StartDate = time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC)
+	go2019.Skills = []string{"go", "mongodb"}
+	go2019.Remote = true
+	go2019.EmploymentType = "contract"
+
+	rust2021 := newDto("Rust developer")
+	rust2021.StartDate = time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC)
+	rust2021.Skills = []string{"rust", "go"}
+	rust2021.Summary = "Rewrote the BILLING engine."
+
+	manager := newDto("Manager")
+	manager.Company = "Globex"
+	manager.StartDate = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, dto := range []*models.ExperienceDto{go2019, rust2021, manager} {
+		_, err := s.Create(context.Background(), dto)
+		require.NoError(t, err)
+	}
+
+	date := func(year int) *time.Time {
+		t := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	remote := true
+	tests := []struct {
+		name  string
+		query services.ListQuery
+		want  []string
+	}{
+		{"text in title", services.ListQuery{Text: "DEVELOPER"}, []string{"Go developer", "Rust developer"}},
+		{"text in summary", services.ListQuery{Text: "billing"}, []string{"Rust developer"}},
+		{"text in company", services.ListQuery{Text: "globex"}, []string{"Manager"}},
+		{"text is literal", services.ListQuery{Text: "dev.*"}, []string{}},
+		{"one tag", services.ListQuery{Tags: []string{"go"}}, []string{"Go developer", "Rust developer"}},
+		{"all tags", services.ListQuery{Tags: []string{"go", "rust"}}, []string{"Rust developer"}},
+		{"employment type", services.ListQuery{EmploymentType: "contract"}, []string{"Go developer"}},
+		{"remote", services.ListQuery{Remote: &remote}, []string{"Go developer"}},
+		{"start from", services.ListQuery{StartFrom: date(2021)}, []string{"Rust developer", "Manager"}},
+		{"start range", services.ListQuery{StartFrom: date(2020), StartTo: date(2022)}, []string{"Rust developer"}},
+		{"created range", services.ListQuery{CreatedFrom: date(2000), CreatedTo: date(2001)}, []string{}},
+		{"combined", services.ListQuery{Tags: []string{"go"}, StartTo: date(2020)}, []string{"Go developer"}},
+	}
+	for _, tt := range tests {
+		result, err := s.FindAll(context.Background(), tt.query)
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.want, titles(result), tt.name)
+		require.Equal(t, int64(len(tt.want)), result.Total, tt.name)
+	}
+}
+
+func testFindAllInvalidQuery(t *testing.T, s services.ExperienceService) {
+	create(t, s, "a")
+
+	_, err := s.FindAll(context.Background(), services.ListQuery{Sort: "summary"})
+	require.ErrorIs(t, err, services.ErrValidation)
+	_, err = s.FindAll(context.Background(), services.ListQuery{Cursor: "not-a-cursor"})
+	require.ErrorIs(t, err, services.ErrValidation)
+}
+
+func hitTitles(result *services.SearchResult) []string {
+	out := make([]string, len(result.Hits))
+	for i, hit := range result.Hits {
+		out[i] = hit.Experience.Title
+	}
+	return out
+}
+
+func search(t *testing.T, s services.ExperienceService, query services.SearchQuery) *services.SearchResult {
+	t.Helper()
+	result, err := s.Search(context.Background(), query)
+	require.NoError(t, err)
+	return result
+}
+
+func testSearchRanking(t *testing.T, s services.ExperienceService) {
+	inSummary := newDto("Developer")
+	inSummary.Summary = "Maintained a Kubernetes cluster."
+	inTitle := newDto("Kubernetes engineer")
+	both := newDto("Kubernetes platform lead")
+	both.Summary = "Ran Kubernetes upgrades on the platform."
+	unrelated := newDto("Accountant")
+	for _, dto := range []*models.ExperienceDto{inSummary, inTitle, both, unrelated} {
+		_, err := s.Create(context.Background(), dto)
+		require.NoError(t, err)
+	}
+
+	result := search(t, s, services.SearchQuery{Text: "kubernetes platform"})
+	require.Equal(t, []string{"Kubernetes platform lead", "Kubernetes engineer", "Developer"}, hitTitles(result))
+	require.Equal(t, 3, result.Total)
+	require.Greater(t, result.Hits[0].Score, result.Hits[1].Score)
+	require.Greater(t, result.Hits[1].Score, result.Hits[2].Score)
+
+	limited := search(t, s, services.SearchQuery{Text: "kubernetes", Limit: 1})
+	require.Len(t, limited.Hits, 1)
+	require.Equal(t, 3, limited.Total)
+}
+
+func testSearchPrefixAndFuzzy(t *testing.T, s services.ExperienceService) {
+	dto := newDto("Database engineer")
+	dto.Skills = []string{"MongoDB", "Kubernetes"}
+	_, err := s.Create(context.Background(), dto)
+	require.NoError(t, err)
+
+	require.Empty(t, search(t, s, services.SearchQuery{Text: "mongo"}).Hits)
+	require.Len(t, search(t, s, services.SearchQuery{Text: "mongo", Prefix: true}).Hits, 1)
+
+	require.Empty(t, search(t, s, services.SearchQuery{Text: "kubernetse"}).Hits)
+	require.Empty(t, search(t, s, services.SearchQuery{Text: "kubernetse", Prefix: true}).Hits)
+	require.Len(t, search(t, s, services.SearchQuery{Text: "kubernetse", Fuzzy: true}).Hits, 0, "transpositions are two edits")
+	require.Len(t, search(t, s, services.SearchQuery{Text: "kubernets", Fuzzy: true}).Hits, 1)
+	require.Len(t, search(t, s, services.SearchQuery{Text: "enginer", Fuzzy: true}).Hits, 1)
+	require.Empty(t, search(t, s, services.SearchQuery{Text: "dbs", Fuzzy: true}).Hits, "short terms are not fuzzy")
+}
+
+func testSearchFollowsWrites(t *testing.T, s services.ExperienceService) {
+	id := create(t, s, "Golang developer")
+	require.Len(t, search(t, s, services.SearchQuery{Text: "golang"}).Hits, 1)
+
+	_, err := s.Update(context.Background(), id, newDto("Rust developer"), services.Precondition{})
+	require.NoError(t, err)
+	require.Empty(t, search(t, s, services.SearchQuery{Text: "golang"}).Hits)
+	require.Len(t, search(t, s, services.SearchQuery{Text: "rust"}).Hits, 1)
+
+	require.NoError(t, s.Delete(context.Background(), id, services.Precondition{}))
+	require.Empty(t, search(t, s, services.SearchQuery{Text: "rust"}).Hits)
+}
+
+func testSearchHighlights(t *testing.T, s services.ExperienceService) {
+	dto := newDto("Go <developer>")
+	dto.Summary = strings.Repeat("filler ", 30) + "wrote Go services " + strings.Repeat("padding ", 30)
+	_, err := s.Create(context.Background(), dto)
+	require.NoError(t, err)
+
+	result := search(t, s, services.SearchQuery{Text: "go"})
+	require.Len(t, result.Hits, 1)
+	highlights := result.Hits[0].Highlights
+	require.Len(t, highlights, 2)
+	require.Equal(t, services.Highlight{Field: "title", Snippet: "<mark>Go</mark> &lt;developer&gt;"}, highlights[0])
+
+	summary := highlights[1]
+	require.Equal(t, "summary", summary.Field)
+	require.Contains(t, summary.Snippet, "filler wrote <mark>Go</mark> services padding")
+	require.True(t, strings.HasPrefix(summary.Snippet, "…filler "), summary.Snippet)
+	require.True(t, strings.HasSuffix(summary.Snippet, " padding…"), summary.Snippet)
+	require.Less(t, len(summary.Snippet), len(dto.Summary))
+}
+
+func testSearchEmptyQuery(t *testing.T, s services.ExperienceService) {
+	_, err := s.Search(context.Background(), services.SearchQuery{Text: " -- "})
+	require.ErrorIs(t, err, services.ErrValidation)
+}
+
+func testReturnedValuesAreCopies(t *testing.T, s services.ExperienceService) {
+	id := create(t, s, "original")
+
+	exp, err := s.FindById(context.Background(), id)
+	require.NoError(t, err)
+	exp.Title = "mutated"
+
+	again, err := s.FindById(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, "original", again.Title)
+}
+
+func testCanceledContext(t *testing.T, s services.ExperienceService) {
+	id := create(t, s, "kept")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := s.Create(ctx, newDto("x"))
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = s.Update(ctx, id, newDto("x"), services.Precondition{})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = s.Patch(ctx, id, mergePatch(t, `{"title": "x"}`), services.Precondition{})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = s.FindById(ctx, id)
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = s.FindAll(ctx, services.ListQuery{})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = s.Search(ctx, services.SearchQuery{Text: "kept"})
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, s.Delete(ctx, id, services.Precondition{}), context.Canceled)
+	_, err = s.Trash(ctx, services.ListQuery{})
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = s.Undelete(ctx, id, services.Precondition{})
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, s.Purge(ctx, id, services.Precondition{}), context.Canceled)
+	_, err = s.PurgeDeleted(ctx, time.Now())
+	require.ErrorIs(t, err, context.Canceled)
+	_, err = s.Bulk(ctx, []services.BulkOperation{{Op: services.BulkDelete, ID: id}}, false)
+	require.ErrorIs(t, err, context.Canceled)
+
+	exp, err := s.FindById(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, "kept", exp.Title)
+}
@@ -0,0 +1,330 @@
+// Package validation evaluates declarative rules attached to struct fields
+// with the `validate` tag, collecting every violation instead of stopping at
+// the first one.
+//
+// Rules are comma separated and run in order:
+//
+//	Title    string     `json:"title" validate:"trim,required,max=120,charset=text"`
+//	Tags     []string   `json:"tags" validate:"max=20,dive,trim,required,max=40"`
+//	EndDate  *time.Time `json:"endDate" validate:"gtefield=StartDate"`
+//
+// Supported rules:
+//
+//	trim          strip surrounding white space from a string (mutates the value)
+//	required      string/slice/map non-empty, pointer non-nil, other values non-zero
+//	min=N, max=N  rune count for strings, length for slices and maps, value for numbers
+//	oneof=a b c   string must be one of the listed values
+//	charset=NAME  every rune must belong to a registered charset (see RegisterCharset)
+//	gtefield=F    time must not be before sibling field F (skipped when either is unset)
+//	dive          apply the remaining rules to each element of a slice
+//
+// Nested structs are validated recursively. A struct can add rules that do
+// not fit in tags by implementing Validator.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// FieldError describes a single violated rule. Field is the JSON path of the
+// offending value, e.g. "achievements[2]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Errors is the list of violations found by Struct. It is nil when the value
+// is valid.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + " " + fe.Message
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Validator is implemented by structs with rules that cannot be expressed
+// in tags. Validate is called after the tag rules and should report fields by
+// their JSON names.
+type Validator interface {
+	Validate() Errors
+}
+
+var (
+	charsetsMu sync.RWMutex
+	charsets   = map[string]func(rune) bool{
+		// text allows any printable character plus line breaks and tabs.
+		"text": func(r rune) bool {
+			return unicode.IsPrint(r) || r == '\n' || r == '\r' || r == '\t'
+		},
+		// line is text without line breaks, for single-line fields.
+		"line": func(r rune) bool {
+			return unicode.IsPrint(r) || r == '\t'
+		},
+	}
+)
+
+// RegisterCharset makes a charset available to the charset=NAME rule.
+func RegisterCharset(name string, allowed func(rune) bool) {
+	charsetsMu.Lock()
+	defer charsetsMu.Unlock()
+	charsets[name] = allowed
+}
+
+// Struct validates v, which must be a pointer to a struct so trim can update
+// fields in place. A nil pointer is reported as a missing body.
+func Struct(v any) Errors {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return Errors{{Field: "body", Rule: "required", Message: "is required"}}
+		}
+		panic(fmt.Sprintf("validation: Struct needs a pointer to a struct, got %T", v))
+	}
+
+	var errs Errors
+	validateStruct(rv.Elem(), "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateStruct(sv reflect.Value, prefix string, errs *Errors) {
+	st := sv.Type()
+	for i := 0; i < st.NumField(); i++ {
+		sf := st.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := jsonName(sf)
+		if name == "-" {
+			continue
+		}
+		path := join(prefix, name)
+		fv := sv.Field(i)
+
+		if tag := sf.Tag.Get("validate"); tag != "" {
+			applyRules(sv, fv, path, strings.Split(tag, ","), errs)
+		}
+
+		inner := fv
+		if inner.Kind() == reflect.Pointer && !inner.IsNil() {
+			inner = inner.Elem()
+		}
+		if inner.Kind() == reflect.Struct && inner.Type() != reflect.TypeOf(time.Time{}) {
+			validateStruct(inner, path, errs)
+		}
+	}
+
+	if sv.CanAddr() {
+		if v, ok := sv.Addr().Interface().(Validator); ok {
+			for _, fe := range v.Validate() {
+				fe.Field = join(prefix, fe.Field)
+				*errs = append(*errs, fe)
+			}
+		}
+	}
+}
+
+func applyRules(parent, fv reflect.Value, path string, rules []string, errs *Errors) {
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if name == "dive" {
+			if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
+				for j := 0; j < fv.Len(); j++ {
+					applyRules(parent, fv.Index(j), fmt.Sprintf("%s[%d]", path, j), rules[i+1:], errs)
+				}
+			}
+			return
+		}
+
+		check, ok := ruleFuncs[name]
+		if !ok {
+			panic(fmt.Sprintf("validation: unknown rule %q on %s", name, path))
+		}
+		if msg := check(parent, fv, param); msg != "" {
+			*errs = append(*errs, FieldError{Field: path, Rule: name, Message: msg})
+			// Later rules on the same value would only repeat the problem.
+			return
+		}
+	}
+}
+
+type ruleFunc func(parent, v reflect.Value, param string) string
+
+var ruleFuncs map[string]ruleFunc
+
+func init() {
+	ruleFuncs = map[string]ruleFunc{
+		"trim":     ruleTrim,
+		"required": ruleRequired,
+		"min":      ruleMin,
+		"max":      ruleMax,
+		"oneof":    ruleOneOf,
+		"charset":  ruleCharset,
+		"gtefield": ruleGteField,
+	}
+}
+
+func ruleTrim(_, v reflect.Value, _ string) string {
+	if v.Kind() == reflect.String && v.CanSet() {
+		v.SetString(strings.TrimSpace(v.String()))
+	}
+	return ""
+}
+
+func ruleRequired(_, v reflect.Value, _ string) string {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return "is required"
+		}
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return "is required"
+		}
+	default:
+		if v.IsZero() {
+			return "is required"
+		}
+	}
+	return ""
+}
+
+func size(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+func unit(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return " items"
+	}
+	return ""
+}
+
+func ruleMin(_, v reflect.Value, param string) string {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: min=%q is not a number", param))
+	}
+	if n, ok := size(v); ok && n < limit {
+		return fmt.Sprintf("must be at least %s%s", param, unit(v))
+	}
+	return ""
+}
+
+func ruleMax(_, v reflect.Value, param string) string {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("validation: max=%q is not a number", param))
+	}
+	if n, ok := size(v); ok && n > limit {
+		return fmt.Sprintf("must be at most %s%s", param, unit(v))
+	}
+	return ""
+}
+
+func ruleOneOf(_, v reflect.Value, param string) string {
+	if v.Kind() != reflect.String || v.String() == "" {
+		return ""
+	}
+	allowed := strings.Fields(param)
+	for _, a := range allowed {
+		if v.String() == a {
+			return ""
+		}
+	}
+	return "must be one of " + strings.Join(allowed, ", ")
+}
+
+func ruleCharset(_, v reflect.Value, param string) string {
+	charsetsMu.RLock()
+	allowed, ok := charsets[param]
+	charsetsMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("validation: unknown charset %q", param))
+	}
+	if v.Kind() != reflect.String {
+		return ""
+	}
+	for _, r := range v.String() {
+		if !allowed(r) {
+			return fmt.Sprintf("contains a disallowed character %q", r)
+		}
+	}
+	return ""
+}
+
+func ruleGteField(parent, v reflect.Value, param string) string {
+	other := parent.FieldByName(param)
+	if !other.IsValid() {
+		panic(fmt.Sprintf("validation: gtefield refers to unknown field %q", param))
+	}
+	a, okA := asTime(v)
+	b, okB := asTime(other)
+	if !okA || !okB {
+		return ""
+	}
+	if a.Before(b) {
+		name := param
+		if sf, ok := parent.Type().FieldByName(param); ok {
+			name = jsonName(sf)
+		}
+		return "must not be before " + name
+	}
+	return ""
+}
+
+func asTime(v reflect.Value) (time.Time, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return time.Time{}, false
+		}
+		v = v.Elem()
+	}
+	t, ok := v.Interface().(time.Time)
+	if !ok || t.IsZero() {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" {
+		return sf.Name
+	}
+	return name
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
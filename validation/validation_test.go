@@ -0,0 +1,96 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `json:"city" validate:"trim,required"`
+}
+
+type job struct {
+	Title    string     `json:"title" validate:"trim,required,max=10,charset=line"`
+	Kind     string     `json:"kind" validate:"oneof=full-time part-time"`
+	Tags     []string   `json:"tags" validate:"max=2,dive,trim,required,max=5"`
+	Start    time.Time  `json:"start" validate:"required"`
+	End      *time.Time `json:"end" validate:"gtefield=Start"`
+	Address  address    `json:"address"`
+	Internal string     `json:"-" validate:"required"`
+	Secret   string     `json:"secret"`
+}
+
+func (j *job) Validate() Errors {
+	if j.Secret == "forbidden" {
+		return Errors{{Field: "secret", Rule: "custom", Message: "is not allowed"}}
+	}
+	return nil
+}
+
+func TestStructValid(t *testing.T) {
+	end := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	j := &job{
+		Title:   "  Engineer ",
+		Kind:    "full-time",
+		Tags:    []string{" go "},
+		Start:   end.AddDate(-1, 0, 0),
+		End:     &end,
+		Address: address{City: "Bangkok"},
+	}
+
+	require.Nil(t, Struct(j))
+	require.Equal(t, "Engineer", j.Title)
+	require.Equal(t, "go", j.Tags[0])
+}
+
+func TestStructCollectsAllViolations(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, -1, 0)
+	j := &job{
+		Title:   "Line\nbreak",
+		Kind:    "gig",
+		Tags:    []string{"ok", "   ", "toolong"},
+		Start:   start,
+		End:     &end,
+		Address: address{City: " "},
+		Secret:  "forbidden",
+	}
+
+	errs := Struct(j)
+	require.Equal(t, Errors{
+		{Field: "title", Rule: "charset", Message: `contains a disallowed character '\n'`},
+		{Field: "kind", Rule: "oneof", Message: "must be one of full-time, part-time"},
+		{Field: "tags", Rule: "max", Message: "must be at most 2 items"},
+		{Field: "end", Rule: "gtefield", Message: "must not be before start"},
+		{Field: "address.city", Rule: "required", Message: "is required"},
+		{Field: "secret", Rule: "custom", Message: "is not allowed"},
+	}, errs)
+}
+
+func TestStructDive(t *testing.T) {
+	j := &job{Title: "x", Start: time.Now(), Address: address{City: "x"}, Tags: []string{"   ", "toolong"}}
+
+	errs := Struct(j)
+	require.Equal(t, Errors{
+		{Field: "tags[0]", Rule: "required", Message: "is required"},
+		{Field: "tags[1]", Rule: "max", Message: "must be at most 5 characters"},
+	}, errs)
+}
+
+func TestStructLengthCountsRunes(t *testing.T) {
+	j := &job{Title: "ประสบการณ์", Start: time.Now(), Address: address{City: "x"}}
+	require.Nil(t, Struct(j))
+
+	j.Title = "ประสบการณ์ทำงาน"
+	errs := Struct(j)
+	require.Len(t, errs, 1)
+	require.Equal(t, "max", errs[0].Rule)
+}
+
+func TestStructNilPointer(t *testing.T) {
+	var j *job
+	errs := Struct(j)
+	require.Equal(t, Errors{{Field: "body", Rule: "required", Message: "is required"}}, errs)
+}